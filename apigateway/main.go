@@ -0,0 +1,121 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/golang/glog"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apigateway"
+	crclientset "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned"
+)
+
+var (
+	master              = flag.String("master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
+	kubeConfig          = flag.String("kubeConfig", "", "Path to a kube config. Only required if out-of-cluster.")
+	port                = flag.Int("port", 8080, "Port the API gateway server listens on.")
+	namespace           = flag.String("namespace", "default", "The Kubernetes namespace SparkApplications are submitted into.")
+	sparkVersion        = flag.String("spark-version", "3.1.1", "Spark version used when a submission does not specify one.")
+	image               = flag.String("image", "", "Container image used when a submission does not specify one.")
+	serviceAccount      = flag.String("service-account", "", "Service account of the Spark driver pods.")
+	basicAuthFile       = flag.String("basic-auth-file", "", "Path to a file of user:password lines used for basic authentication.")
+	tokenAuthFile       = flag.String("token-auth-file", "", "Path to a file of token,user lines used for bearer token authentication.")
+	oidcIssuerURL       = flag.String("oidc-issuer-url", "", "URL of the OpenID Connect provider whose ID tokens are accepted as bearer tokens.")
+	oidcClientID        = flag.String("oidc-client-id", "", "Client ID that accepted OpenID Connect ID tokens must be issued for.")
+	oidcUserClaim       = flag.String("oidc-username-claim", "sub", "OpenID Connect ID token claim used as the user name.")
+	tlsCertFile         = flag.String("tls-cert-file", "", "Path to the TLS serving certificate. The server uses plain HTTP if unset.")
+	tlsKeyFile          = flag.String("tls-key-file", "", "Path to the TLS serving key.")
+	clientCAFile        = flag.String("client-ca-file", "", "Path to the CA bundle used to verify client certificates for mTLS authentication.")
+	allowAnonymous      = flag.Bool("allow-anonymous", false, "Accept every request as the user anonymous if no authentication method is configured. Anyone who can reach the gateway can then submit, kill and delete applications.")
+	namespacePolicyFile = flag.String("namespace-policy-file", "", "Path to a YAML or JSON file mapping users to the namespaces they may use. Without it, all users may only use -namespace.")
+	s3Bucket            = flag.String("s3-bucket", "", "S3 bucket uploaded files are stored in.")
+	s3Prefix            = flag.String("s3-prefix", "uploads", "Key prefix of uploaded files in the S3 bucket.")
+	s3Region            = flag.String("s3-region", "", "Region of the S3 bucket.")
+	s3Endpoint          = flag.String("s3-endpoint", "", "Custom endpoint of an S3-compatible object store.")
+	localStorageDir     = flag.String("local-storage-dir", "", "Directory uploaded files are stored in if -s3-bucket is unset. File upload is disabled if neither is set.")
+	localStorageURL     = flag.String("local-storage-url", "", "URL Spark pods can download the files in -local-storage-dir from. Defaults to the file:// URL of the directory.")
+	uploadDir           = flag.String("upload-dir", "", "Directory chunked uploads are staged in until they are complete. Defaults to a directory in the system temporary directory.")
+)
+
+func main() {
+	flag.Parse()
+
+	config, err := buildConfig(*master, *kubeConfig)
+	if err != nil {
+		glog.Fatal(err)
+	}
+	kubeClient, err := clientset.NewForConfig(config)
+	if err != nil {
+		glog.Fatal(err)
+	}
+	crClient, err := crclientset.NewForConfig(config)
+	if err != nil {
+		glog.Fatal(err)
+	}
+
+	server, err := apigateway.NewServer(apigateway.Config{
+		Port:                *port,
+		Namespace:           *namespace,
+		SparkVersion:        *sparkVersion,
+		Image:               *image,
+		ServiceAccount:      *serviceAccount,
+		BasicAuthFile:       *basicAuthFile,
+		TokenAuthFile:       *tokenAuthFile,
+		OIDCIssuerURL:       *oidcIssuerURL,
+		OIDCClientID:        *oidcClientID,
+		OIDCUsernameClaim:   *oidcUserClaim,
+		TLSCertFile:         *tlsCertFile,
+		TLSKeyFile:          *tlsKeyFile,
+		ClientCAFile:        *clientCAFile,
+		AllowAnonymous:      *allowAnonymous,
+		NamespacePolicyFile: *namespacePolicyFile,
+		S3Bucket:            *s3Bucket,
+		S3Prefix:            *s3Prefix,
+		S3Region:            *s3Region,
+		S3Endpoint:          *s3Endpoint,
+		LocalStorageDir:     *localStorageDir,
+		LocalStorageURL:     *localStorageURL,
+		UploadDir:           *uploadDir,
+	}, crClient, kubeClient)
+	if err != nil {
+		glog.Fatal(err)
+	}
+
+	signalCh := make(chan os.Signal, 1)
+	signal.Notify(signalCh, syscall.SIGINT, syscall.SIGTERM)
+
+	server.Start()
+	<-signalCh
+
+	if err := server.Stop(); err != nil {
+		glog.Fatal(err)
+	}
+}
+
+func buildConfig(masterURL string, kubeConfig string) (*rest.Config, error) {
+	if kubeConfig != "" {
+		return clientcmd.BuildConfigFromFlags(masterURL, kubeConfig)
+	}
+	return rest.InClusterConfig()
+}
@@ -20,6 +20,7 @@ require (
 	github.com/prometheus/client_model v0.2.0
 	github.com/robfig/cron v1.2.0
 	github.com/spf13/cobra v1.0.0
+	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.4.0
 	golang.org/x/net v0.0.0-20201110031124-69a78807bb2b
 	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e
@@ -30,6 +31,7 @@ require (
 	k8s.io/client-go v0.19.6
 	k8s.io/kubectl v0.19.6
 	k8s.io/kubernetes v1.19.6
+	sigs.k8s.io/yaml v1.2.0
 	volcano.sh/volcano v1.1.0
 )
 
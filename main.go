@@ -62,6 +62,12 @@ var (
 	enableResourceQuotaEnforcement = flag.Bool("enable-resource-quota-enforcement", false, "Whether to enable ResourceQuota enforcement for SparkApplication resources. Requires the webhook to be enabled.")
 	ingressURLFormat               = flag.String("ingress-url-format", "", "Ingress URL format.")
 	enableUIService                = flag.Bool("enable-ui-service", true, "Enable Spark service UI.")
+	submitter                      = flag.String("submitter", string(sparkapplication.SparkSubmitSubmitter), "How SparkApplications are submitted: spark-submit, or native to create the driver pod directly. Can be overridden per application with the sparkoperator.k8s.io/submitter annotation.")
+	submissionWorkers              = flag.Int("submission-workers", 10, "Maximum number of spark-submit processes run concurrently by the SparkApplication controller.")
+	submissionTimeout              = flag.Duration("submission-timeout", 3*time.Minute, "Time after which a spark-submit process is killed and the submission is recorded as failed. Zero means no timeout.")
+	orphanSweepInterval            = flag.Duration("orphan-sweep-interval", 10*time.Minute, "Interval at which resources left behind by deleted SparkApplications are deleted. Zero disables the sweep.")
+	progressPollInterval           = flag.Duration("progress-poll-interval", 0, "Interval at which the job and stage progress of running SparkApplications is read from the REST API of their driver. Zero disables polling.")
+	executorStatusLimit            = flag.Int("executor-status-limit", 200, "Maximum number of executors listed in the status of a SparkApplication. Terminated executors beyond it are only counted. Zero lists all executors.")
 	enableLeaderElection           = flag.Bool("leader-election", false, "Enable Spark operator leader election.")
 	leaderElectionLockNamespace    = flag.String("leader-election-lock-namespace", "spark-operator", "Namespace in which to create the ConfigMap for leader election.")
 	leaderElectionLockName         = flag.String("leader-election-lock-name", "spark-operator-lock", "Name of the ConfigMap for leader election.")
@@ -84,6 +90,15 @@ func main() {
 			"it accepts any numerical values that can be parsed into a 64-bit floating point")
 	flag.Parse()
 
+	switch sparkapplication.SubmitterType(*submitter) {
+	case sparkapplication.SparkSubmitSubmitter, sparkapplication.NativeSubmitter:
+	default:
+		glog.Fatalf("invalid submitter %q, must be spark-submit or native", *submitter)
+	}
+	if *submissionWorkers < 1 {
+		glog.Fatalf("invalid submission-workers %d, must be at least 1", *submissionWorkers)
+	}
+
 	// Create the client config. Use kubeConfig if given, otherwise assume in-cluster.
 	config, err := buildConfig(*master, *kubeConfig)
 	if err != nil {
@@ -184,7 +199,7 @@ func main() {
 	}
 
 	applicationController := sparkapplication.NewController(
-		crClient, kubeClient, crInformerFactory, podInformerFactory, metricConfig, *namespace, *ingressURLFormat, batchSchedulerMgr, *enableUIService)
+		crClient, kubeClient, crInformerFactory, podInformerFactory, metricConfig, *namespace, *ingressURLFormat, batchSchedulerMgr, *enableUIService, sparkapplication.SubmitterType(*submitter), *submissionWorkers, *submissionTimeout, *orphanSweepInterval, *progressPollInterval, *executorStatusLimit)
 	scheduledApplicationController := scheduledsparkapplication.NewController(
 		crClient, kubeClient, apiExtensionsClient, crInformerFactory, clock.RealClock{})
 
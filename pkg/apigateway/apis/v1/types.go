@@ -0,0 +1,177 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1 contains the request and response types of the Spark API gateway REST interface.
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SparkApplicationSubmissionRequest describes a Spark application to be submitted through the API gateway.
+type SparkApplicationSubmissionRequest struct {
+	// ApplicationName is a user-facing name of the application. Several submissions may share the same name.
+	ApplicationName string `json:"applicationName,omitempty"`
+	// Namespace is the namespace to submit the application into. Defaults to the first namespace the caller may use.
+	Namespace string `json:"namespace,omitempty"`
+	// Type is the type of the Spark application, one of Java, Scala, Python or R. Defaults to Scala.
+	Type string `json:"type,omitempty"`
+	// SparkVersion is the version of Spark the application uses.
+	SparkVersion string `json:"sparkVersion,omitempty"`
+	// Image is the container image for the driver and executors.
+	Image string `json:"image,omitempty"`
+	// MainClass is the fully-qualified main class of the Spark application.
+	MainClass string `json:"mainClass,omitempty"`
+	// MainApplicationFile is the path to a bundled JAR, Python, or R file of the application.
+	MainApplicationFile string `json:"mainApplicationFile"`
+	// Arguments is a list of arguments to be passed to the application.
+	Arguments []string `json:"arguments,omitempty"`
+	// SparkConf carries user-specified Spark configuration properties.
+	SparkConf map[string]string `json:"sparkConf,omitempty"`
+	// HadoopConf carries user-specified Hadoop configuration properties.
+	HadoopConf map[string]string `json:"hadoopConf,omitempty"`
+	// Jars is a list of JAR files the Spark application depends on.
+	Jars []string `json:"jars,omitempty"`
+	// Files is a list of files the Spark application depends on.
+	Files []string `json:"files,omitempty"`
+	// PyFiles is a list of Python files the Spark application depends on.
+	PyFiles []string `json:"pyFiles,omitempty"`
+	// Driver is the driver specification.
+	Driver DriverSpec `json:"driver,omitempty"`
+	// Executor is the executor specification.
+	Executor ExecutorSpec `json:"executor,omitempty"`
+}
+
+// DriverSpec is the driver specification of a submission.
+type DriverSpec struct {
+	// Cores maps to `spark.driver.cores`.
+	Cores int32 `json:"cores,omitempty"`
+	// Memory is the amount of memory to request for the driver pod.
+	Memory string `json:"memory,omitempty"`
+	// MemoryOverhead is the amount of off-heap memory to allocate for the driver.
+	MemoryOverhead string `json:"memoryOverhead,omitempty"`
+}
+
+// ExecutorSpec is the executor specification of a submission.
+type ExecutorSpec struct {
+	// Instances is the number of executor instances.
+	Instances int32 `json:"instances,omitempty"`
+	// Cores maps to `spark.executor.cores`.
+	Cores int32 `json:"cores,omitempty"`
+	// Memory is the amount of memory to request for each executor pod.
+	Memory string `json:"memory,omitempty"`
+	// MemoryOverhead is the amount of off-heap memory to allocate for each executor.
+	MemoryOverhead string `json:"memoryOverhead,omitempty"`
+}
+
+// SparkApplicationSubmissionResponse is returned after an application was submitted.
+type SparkApplicationSubmissionResponse struct {
+	SubmissionId string `json:"submissionId"`
+}
+
+// SubmissionStatusResponse describes the current status of a submission.
+type SubmissionStatusResponse struct {
+	SubmissionId       string `json:"submissionId"`
+	ApplicationName    string `json:"applicationName,omitempty"`
+	Namespace          string `json:"namespace,omitempty"`
+	State              string `json:"state"`
+	ErrorMessage       string `json:"errorMessage,omitempty"`
+	SparkApplicationId string `json:"sparkApplicationId,omitempty"`
+	DriverPodName      string `json:"driverPodName,omitempty"`
+	SparkUIUrl         string `json:"sparkUIUrl,omitempty"`
+	// Killed is true if the submission was killed through the API gateway.
+	Killed bool `json:"killed,omitempty"`
+	// CreationTime is the time when the submission was received.
+	CreationTime metav1.Time `json:"creationTime,omitempty"`
+	// SubmissionTime is the time when the application was last submitted by the operator.
+	SubmissionTime metav1.Time `json:"submissionTime,omitempty"`
+	// TerminationTime is the time when the application terminated, if it has.
+	TerminationTime metav1.Time `json:"terminationTime,omitempty"`
+	// Executors counts the executors of the application by state.
+	Executors ExecutorCounts `json:"executors"`
+}
+
+// ExecutorCounts counts the executors of an application by state.
+type ExecutorCounts struct {
+	Pending   int `json:"pending"`
+	Running   int `json:"running"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+// ListSubmissionsResponse carries a list of submissions, most recent first.
+type ListSubmissionsResponse struct {
+	Submissions []SubmissionStatusResponse `json:"submissions"`
+}
+
+// DeleteSubmissionResponse is returned after a submission was deleted.
+type DeleteSubmissionResponse struct {
+	SubmissionId string `json:"submissionId"`
+	Message      string `json:"message,omitempty"`
+}
+
+// KillSubmissionRequest requests to kill a running submission.
+type KillSubmissionRequest struct {
+}
+
+// KillSubmissionResponse is returned after a submission was killed.
+type KillSubmissionResponse struct {
+	SubmissionId string `json:"submissionId"`
+	State        string `json:"state,omitempty"`
+}
+
+// KillSubmissionByNameRequest requests to kill the running submissions sharing an application name.
+type KillSubmissionByNameRequest struct {
+	ApplicationName string `json:"applicationName"`
+	// MaxApplicationCount is the maximum number of submissions to kill. All matching submissions
+	// are killed if it is not positive.
+	MaxApplicationCount int `json:"maxApplicationCount,omitempty"`
+}
+
+// KillSubmissionByNameResponse lists the submissions killed for an application name.
+type KillSubmissionByNameResponse struct {
+	ApplicationName string   `json:"applicationName"`
+	SubmissionIds   []string `json:"submissionIds"`
+}
+
+// UploadFileResponse is returned after a file was uploaded.
+type UploadFileResponse struct {
+	// Url is the location of the uploaded file that can be referenced by a submission.
+	Url string `json:"url"`
+}
+
+// CreateUploadRequest starts or resumes a chunked upload of a file.
+type CreateUploadRequest struct {
+	// Name is the file name the uploaded file gets.
+	Name string `json:"name"`
+	// Size is the size of the file in bytes.
+	Size int64 `json:"size"`
+	// Sha256 is the hex-encoded SHA-256 hash of the file content.
+	Sha256 string `json:"sha256"`
+}
+
+// UploadStatusResponse describes the progress of a chunked upload.
+type UploadStatusResponse struct {
+	// UploadId identifies the upload. Creating an upload of the same file again returns the same ID, so an
+	// interrupted upload can be resumed.
+	UploadId string `json:"uploadId,omitempty"`
+	// ReceivedBytes is the number of bytes received so far. The next chunk should start at this offset.
+	ReceivedBytes int64 `json:"receivedBytes"`
+	// MaxChunkSize is the maximum size of a chunk the server accepts.
+	MaxChunkSize int64 `json:"maxChunkSize,omitempty"`
+	// Url is set if the file was already uploaded before, in which case no chunks need to be sent.
+	Url string `json:"url,omitempty"`
+}
@@ -0,0 +1,193 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigateway
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// anonymousUser is the name of the identity of requests when no authenticator is configured, which the server only
+// allows with Config.AllowAnonymous.
+const anonymousUser = "anonymous"
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	Name string
+}
+
+// Authenticator authenticates the caller of a request.
+type Authenticator interface {
+	// Authenticate returns the identity of the caller, or nil if the request carries no credentials
+	// this Authenticator understands. An error is returned if the credentials are present but invalid.
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+type identityContextKey struct{}
+
+// identityFromRequest returns the identity stored in the request context by the authentication handler.
+func identityFromRequest(r *http.Request) *Identity {
+	identity, ok := r.Context().Value(identityContextKey{}).(*Identity)
+	if !ok {
+		return &Identity{Name: anonymousUser}
+	}
+	return identity
+}
+
+// authenticated wraps a handler with authentication if any authenticator is configured. Otherwise, every request is
+// made as the anonymous user.
+// The first authenticator that recognizes the credentials of a request decides on its identity.
+func (s *Server) authenticated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity := &Identity{Name: anonymousUser}
+		if len(s.authenticators) > 0 {
+			identity = nil
+			for _, authenticator := range s.authenticators {
+				id, err := authenticator.Authenticate(r)
+				if err != nil {
+					glog.V(2).Infof("Authentication of request to %s failed: %v", r.URL.Path, err)
+					break
+				}
+				if id != nil {
+					identity = id
+					break
+				}
+			}
+		}
+		if identity == nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="spark-api-gateway"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityContextKey{}, identity)))
+	})
+}
+
+// basicAuthenticator authenticates requests with a user name and password.
+type basicAuthenticator struct {
+	credentials map[string]string
+}
+
+// newBasicAuthenticator creates a basicAuthenticator from a file of "user:password" lines.
+func newBasicAuthenticator(path string) (*basicAuthenticator, error) {
+	entries, err := readCredentialFile(path, ":")
+	if err != nil {
+		return nil, err
+	}
+	credentials := make(map[string]string)
+	for _, entry := range entries {
+		credentials[entry[0]] = entry[1]
+	}
+	return &basicAuthenticator{credentials: credentials}, nil
+}
+
+func (a *basicAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+	expected, found := a.credentials[user]
+	if !found || subtle.ConstantTimeCompare([]byte(password), []byte(expected)) != 1 {
+		return nil, fmt.Errorf("invalid password for user %s", user)
+	}
+	return &Identity{Name: user}, nil
+}
+
+// tokenAuthenticator authenticates requests with static bearer tokens.
+type tokenAuthenticator struct {
+	users map[string]string
+}
+
+// newTokenAuthenticator creates a tokenAuthenticator from a file of "token,user" lines.
+func newTokenAuthenticator(path string) (*tokenAuthenticator, error) {
+	entries, err := readCredentialFile(path, ",")
+	if err != nil {
+		return nil, err
+	}
+	users := make(map[string]string)
+	for _, entry := range entries {
+		users[entry[0]] = entry[1]
+	}
+	return &tokenAuthenticator{users: users}, nil
+}
+
+func (a *tokenAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, nil
+	}
+	for expected, user := range a.users {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+			return &Identity{Name: user}, nil
+		}
+	}
+	// The token may be meant for another authenticator, e.g. an OIDC token.
+	return nil, nil
+}
+
+// certificateAuthenticator authenticates requests with a verified TLS client certificate.
+// The common name of the certificate subject is the name of the identity.
+type certificateAuthenticator struct{}
+
+func (a certificateAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+	commonName := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	if commonName == "" {
+		return nil, fmt.Errorf("client certificate has no common name")
+	}
+	return &Identity{Name: commonName}, nil
+}
+
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(auth[len(prefix):])
+}
+
+// readCredentialFile reads a file of lines with two fields separated by sep. Empty lines and lines starting
+// with '#' are ignored.
+func readCredentialFile(path string, sep string) ([][2]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credential file %s: %v", path, err)
+	}
+
+	var entries [][2]string
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, sep, 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid entry on line %d of credential file %s", i+1, path)
+		}
+		entries = append(entries, [2]string{kv[0], kv[1]})
+	}
+	return entries, nil
+}
@@ -0,0 +1,235 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apigatewayv1 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apigateway/apis/v1"
+)
+
+func writeTempFile(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "apigateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTokenAuthentication(t *testing.T) {
+	authenticator, err := newTokenAuthenticator(writeTempFile(t, "# token,user\ntoken-a,alice\ntoken-b,bob\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _, _ := newFakeServer()
+	s.authenticators = []Authenticator{authenticator}
+
+	testFn := func(header string, expectedCode int) {
+		req := httptest.NewRequest(http.MethodGet, "/submissions", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		recorder := httptest.NewRecorder()
+		s.handler().ServeHTTP(recorder, req)
+		assert.Equal(t, expectedCode, recorder.Code, "Authorization: %s", header)
+	}
+	testFn("", http.StatusUnauthorized)
+	testFn("Bearer token-c", http.StatusUnauthorized)
+	testFn("Bearer token-b", http.StatusOK)
+	testFn("bearer token-a", http.StatusOK)
+
+	_, err = newTokenAuthenticator(writeTempFile(t, "token-without-user\n"))
+	assert.Error(t, err)
+}
+
+func TestOIDCAuthentication(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var provider *httptest.Server
+	provider = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"jwks_uri": provider.URL + "/keys"})
+		case "/keys":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"keys": []map[string]string{{
+					"kty": "RSA",
+					"kid": "key-1",
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				}},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer provider.Close()
+
+	sign := func(kid string, claims map[string]interface{}) string {
+		header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid})
+		payload, _ := json.Marshal(claims)
+		signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+		digest := sha256.Sum256([]byte(signed))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+	}
+
+	authenticator := newOIDCAuthenticator(provider.URL, "sparkcli", "email")
+	authenticate := func(token string) (*Identity, error) {
+		req := httptest.NewRequest(http.MethodGet, "/submissions", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return authenticator.Authenticate(req)
+	}
+	validClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":   provider.URL,
+			"aud":   []string{"sparkcli"},
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"email": "alice@example.com",
+		}
+	}
+
+	identity, err := authenticate(sign("key-1", validClaims()))
+	assert.NoError(t, err)
+	assert.Equal(t, &Identity{Name: "alice@example.com"}, identity)
+
+	// Opaque tokens are left to other authenticators.
+	identity, err = authenticate("opaque-token")
+	assert.NoError(t, err)
+	assert.Nil(t, identity)
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	wrongAudience := validClaims()
+	wrongAudience["aud"] = "other"
+	wrongIssuer := validClaims()
+	wrongIssuer["iss"] = "https://issuer.example.com"
+	for _, token := range []string{
+		sign("key-1", expired),
+		sign("key-1", wrongAudience),
+		sign("key-1", wrongIssuer),
+		sign("key-2", validClaims()),
+	} {
+		_, err := authenticate(token)
+		assert.Error(t, err)
+	}
+
+	// Tampered claims fail signature verification.
+	token := sign("key-1", validClaims())
+	tampered, _ := json.Marshal(map[string]interface{}{"email": "mallory@example.com"})
+	parts := bytes.Split([]byte(token), []byte("."))
+	_, err = authenticate(fmt.Sprintf("%s.%s.%s", parts[0], base64.RawURLEncoding.EncodeToString(tampered), parts[2]))
+	assert.Error(t, err)
+}
+
+func TestNamespacePolicy(t *testing.T) {
+	policy, err := loadNamespacePolicy(writeTempFile(t, `
+users:
+  alice: [team-a]
+  bob: [team-b, team-a]
+  "*": [shared]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, crdClient, _ := newFakeServer()
+	s.authenticators = []Authenticator{&basicAuthenticator{credentials: map[string]string{
+		"alice": "a", "bob": "b", "carol": "c",
+	}}}
+	s.namespacePolicy = policy
+
+	submit := func(user string, namespace string) (int, string) {
+		body, _ := json.Marshal(apigatewayv1.SparkApplicationSubmissionRequest{
+			Namespace:           namespace,
+			MainApplicationFile: "local:///app.jar",
+		})
+		req := httptest.NewRequest(http.MethodPost, "/submissions", bytes.NewReader(body))
+		req.SetBasicAuth(user, user[:1])
+		recorder := httptest.NewRecorder()
+		s.handler().ServeHTTP(recorder, req)
+		response := apigatewayv1.SparkApplicationSubmissionResponse{}
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		return recorder.Code, response.SubmissionId
+	}
+	request := func(user string, method string, url string) int {
+		req := httptest.NewRequest(method, url, nil)
+		req.SetBasicAuth(user, user[:1])
+		recorder := httptest.NewRecorder()
+		s.handler().ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// Submissions go into the first namespace of the caller by default.
+	code, aliceID := submit("alice", "")
+	assert.Equal(t, http.StatusOK, code)
+	_, err = crdClient.SparkoperatorV1beta2().SparkApplications("team-a").Get(context.TODO(), aliceID, metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	code, _ = submit("alice", "team-b")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, sharedID := submit("carol", "shared")
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = submit("carol", "")
+	assert.Equal(t, http.StatusOK, code)
+
+	// Submissions in namespaces the caller may not use are not visible.
+	assert.Equal(t, http.StatusOK, request("bob", http.MethodGet, "/submissions/"+aliceID))
+	assert.Equal(t, http.StatusNotFound, request("carol", http.MethodGet, "/submissions/"+aliceID))
+	assert.Equal(t, http.StatusForbidden, request("carol", http.MethodGet, "/submissions/"+aliceID+"?namespace=team-a"))
+	assert.Equal(t, http.StatusOK, request("alice", http.MethodGet, "/submissions/"+sharedID))
+
+	req := httptest.NewRequest(http.MethodGet, "/submissions", nil)
+	req.SetBasicAuth("alice", "a")
+	recorder := httptest.NewRecorder()
+	s.handler().ServeHTTP(recorder, req)
+	list := apigatewayv1.ListSubmissionsResponse{}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &list))
+	assert.Equal(t, 3, len(list.Submissions))
+
+	assert.Equal(t, http.StatusNotFound, request("carol", http.MethodDelete, "/submissions/"+aliceID))
+	assert.Equal(t, http.StatusOK, request("alice", http.MethodDelete, "/submissions/"+aliceID))
+}
@@ -0,0 +1,91 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigateway
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// allUsers is the key of the namespace policy entry that applies to every identity.
+const allUsers = "*"
+
+// NamespacePolicy maps identities to the namespaces they may submit into and manage submissions in.
+type NamespacePolicy struct {
+	// Users maps identity names to namespaces. The namespaces of the "*" entry are granted to every identity.
+	Users map[string][]string `json:"users"`
+}
+
+// loadNamespacePolicy reads a NamespacePolicy from a YAML or JSON file.
+func loadNamespacePolicy(path string) (*NamespacePolicy, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open namespace policy file %s: %v", path, err)
+	}
+	defer file.Close()
+
+	policy := &NamespacePolicy{}
+	if err := yaml.NewYAMLOrJSONDecoder(file, 4096).Decode(policy); err != nil {
+		return nil, fmt.Errorf("failed to parse namespace policy file %s: %v", path, err)
+	}
+	return policy, nil
+}
+
+func (p *NamespacePolicy) namespacesFor(identity *Identity) []string {
+	var namespaces []string
+	seen := make(map[string]bool)
+	for _, namespace := range append(p.Users[identity.Name], p.Users[allUsers]...) {
+		if !seen[namespace] {
+			seen[namespace] = true
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// allowedNamespaces returns the namespaces the caller of the request may use. The first one is the default
+// namespace of the caller. Without a namespace policy, only the namespace of the server may be used.
+func (s *Server) allowedNamespaces(r *http.Request) []string {
+	if s.namespacePolicy == nil {
+		return []string{s.config.Namespace}
+	}
+	return s.namespacePolicy.namespacesFor(identityFromRequest(r))
+}
+
+// requestNamespaces returns the namespaces a request operates on: the requested namespace if one is given,
+// or all the namespaces the caller may use otherwise. It writes an error and returns nil if the caller may
+// not use the requested namespace or any namespace at all.
+func (s *Server) requestNamespaces(w http.ResponseWriter, r *http.Request, requested string) []string {
+	allowed := s.allowedNamespaces(r)
+	if len(allowed) == 0 {
+		http.Error(w, fmt.Sprintf("%s is not allowed to use any namespace", identityFromRequest(r).Name), http.StatusForbidden)
+		return nil
+	}
+	if requested == "" {
+		return allowed
+	}
+	for _, namespace := range allowed {
+		if namespace == requested {
+			return []string{requested}
+		}
+	}
+	http.Error(w, fmt.Sprintf("%s is not allowed to use namespace %s", identityFromRequest(r).Name, requested), http.StatusForbidden)
+	return nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigateway
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// flushWriter flushes the response after every write so that followed logs reach the client as they come.
+type flushWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+}
+
+func (fw flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if fw.flusher != nil {
+		fw.flusher.Flush()
+	}
+	return n, err
+}
+
+// getSubmissionLog streams the log of the driver, or of an executor if the executor query parameter is set.
+// With timestamps=true every line is prefixed with its RFC3339 timestamp, and sinceTime only returns lines
+// logged at or after the given time, which together allow clients to resume a stream that was interrupted.
+func (s *Server) getSubmissionLog(w http.ResponseWriter, r *http.Request, submissionID string) {
+	query := r.URL.Query()
+	logOptions := &apiv1.PodLogOptions{}
+
+	executorID := -1
+	if value := query.Get("executor"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			badRequest(w, fmt.Errorf("invalid value for executor: %s", value))
+			return
+		}
+		executorID = parsed
+	}
+	if value := query.Get("follow"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			badRequest(w, fmt.Errorf("invalid value for follow: %s", value))
+			return
+		}
+		logOptions.Follow = parsed
+	}
+	if value := query.Get("timestamps"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			badRequest(w, fmt.Errorf("invalid value for timestamps: %s", value))
+			return
+		}
+		logOptions.Timestamps = parsed
+	}
+	if value := query.Get("sinceTime"); value != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			badRequest(w, fmt.Errorf("invalid value for sinceTime: %s", value))
+			return
+		}
+		sinceTime := metav1.NewTime(parsed)
+		logOptions.SinceTime = &sinceTime
+	}
+
+	app := s.getApplication(w, r, submissionID)
+	if app == nil {
+		return
+	}
+
+	podName, err := s.getLogPodName(app, executorID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if podName == "" {
+		http.Error(w, fmt.Sprintf("no pod found for submission %s", submissionID), http.StatusNotFound)
+		return
+	}
+
+	stream, err := s.kubeClient.CoreV1().Pods(app.Namespace).GetLogs(podName, logOptions).Stream(r.Context())
+	if err != nil {
+		internalError(w, fmt.Errorf("failed to get log of pod %s/%s: %v", app.Namespace, podName, err))
+		return
+	}
+	defer stream.Close()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	flusher, _ := w.(http.Flusher)
+	if _, err := io.Copy(flushWriter{w: w, flusher: flusher}, stream); err != nil {
+		glog.Errorf("failed to stream log of pod %s/%s: %v", app.Namespace, podName, err)
+	}
+}
+
+// getLogPodName returns the name of the driver pod if executorID is negative, or of the executor pod otherwise.
+func (s *Server) getLogPodName(app *v1beta2.SparkApplication, executorID int) (string, error) {
+	if executorID < 0 {
+		return app.Status.DriverInfo.PodName, nil
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{
+		config.SparkAppNameLabel:    app.Name,
+		config.SparkRoleLabel:       config.SparkExecutorRole,
+		config.SparkExecutorIDLabel: strconv.Itoa(executorID),
+	})
+	pods, err := s.kubeClient.CoreV1().Pods(app.Namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return "", fmt.Errorf("failed to list executor pods of SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+	}
+	if len(pods.Items) == 0 {
+		return "", nil
+	}
+	return pods.Items[0].Name, nil
+}
@@ -0,0 +1,223 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigateway
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minKeyRefreshInterval limits how often the signing keys are fetched when a token has an unknown key ID.
+const minKeyRefreshInterval = time.Minute
+
+// oidcAuthenticator authenticates requests with RS256-signed ID tokens of an OpenID Connect provider.
+type oidcAuthenticator struct {
+	issuerURL     string
+	clientID      string
+	usernameClaim string
+	httpClient    *http.Client
+	now           func() time.Time
+
+	mutex         sync.Mutex
+	keys          map[string]*rsa.PublicKey
+	lastKeyUpdate time.Time
+}
+
+func newOIDCAuthenticator(issuerURL string, clientID string, usernameClaim string) *oidcAuthenticator {
+	if usernameClaim == "" {
+		usernameClaim = "sub"
+	}
+	return &oidcAuthenticator{
+		issuerURL:     strings.TrimSuffix(issuerURL, "/"),
+		clientID:      clientID,
+		usernameClaim: usernameClaim,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+		now:           time.Now,
+	}
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+func (a *oidcAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	token := bearerToken(r)
+	// Only JWTs are handled here, other bearer tokens are left to other authenticators.
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil
+	}
+
+	header := jwtHeader{}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %v", err)
+	}
+	if header.Algorithm != "RS256" {
+		return nil, fmt.Errorf("unsupported token signing algorithm %s", header.Algorithm)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature encoding: %v", err)
+	}
+
+	key, err := a.getKey(header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("invalid token signature: %v", err)
+	}
+
+	claims := map[string]interface{}{}
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %v", err)
+	}
+	if issuer, _ := claims["iss"].(string); strings.TrimSuffix(issuer, "/") != a.issuerURL {
+		return nil, fmt.Errorf("unexpected token issuer %q", issuer)
+	}
+	if !audienceContains(claims["aud"], a.clientID) {
+		return nil, fmt.Errorf("token audience does not contain %s", a.clientID)
+	}
+	expiry, ok := claims["exp"].(float64)
+	if !ok || a.now().After(time.Unix(int64(expiry), 0)) {
+		return nil, fmt.Errorf("token is expired")
+	}
+	username, _ := claims[a.usernameClaim].(string)
+	if username == "" {
+		return nil, fmt.Errorf("token has no %s claim", a.usernameClaim)
+	}
+	return &Identity{Name: username}, nil
+}
+
+func audienceContains(audience interface{}, clientID string) bool {
+	switch aud := audience.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, value := range aud {
+			if value == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeJWTSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// getKey returns the signing key with the given ID, fetching the keys of the provider if it is unknown.
+func (a *oidcAuthenticator) getKey(keyID string) (*rsa.PublicKey, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if key, ok := a.keys[keyID]; ok {
+		return key, nil
+	}
+	if a.keys != nil && a.now().Sub(a.lastKeyUpdate) < minKeyRefreshInterval {
+		return nil, fmt.Errorf("unknown token signing key %q", keyID)
+	}
+
+	keys, err := a.fetchKeys()
+	if err != nil {
+		return nil, err
+	}
+	a.keys = keys
+	a.lastKeyUpdate = a.now()
+	if key, ok := a.keys[keyID]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown token signing key %q", keyID)
+}
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+func (a *oidcAuthenticator) fetchKeys() (map[string]*rsa.PublicKey, error) {
+	discovery := struct {
+		JWKSURI string `json:"jwks_uri"`
+	}{}
+	if err := a.getJSON(a.issuerURL+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC provider %s has no jwks_uri", a.issuerURL)
+	}
+
+	keySet := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	if err := a.getJSON(discovery.JWKSURI, &keySet); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range keySet.Keys {
+		if jwk.KeyType != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus of key %s: %v", jwk.KeyID, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent of key %s: %v", jwk.KeyID, err)
+		}
+		keys[jwk.KeyID] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+func (a *oidcAuthenticator) getJSON(url string, v interface{}) error {
+	response, err := a.httpClient.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %v", url, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get %s: status %d", url, response.StatusCode)
+	}
+	if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse response from %s: %v", url, err)
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigateway
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	clientset "k8s.io/client-go/kubernetes"
+
+	crdclientset "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned"
+)
+
+// Config carries the configuration of the API gateway server.
+type Config struct {
+	// Port is the port the server listens on.
+	Port int
+	// Namespace is the namespace SparkApplications are submitted into.
+	Namespace string
+	// SparkVersion is the Spark version used when a submission does not specify one.
+	SparkVersion string
+	// Image is the container image used when a submission does not specify one.
+	Image string
+	// ServiceAccount is the service account of the driver pods.
+	ServiceAccount string
+	// BasicAuthFile is the path to a file of "user:password" lines.
+	BasicAuthFile string
+	// TokenAuthFile is the path to a file of "token,user" lines of static bearer tokens.
+	TokenAuthFile string
+	// OIDCIssuerURL is the URL of the OpenID Connect provider whose ID tokens are accepted as bearer tokens.
+	OIDCIssuerURL string
+	// OIDCClientID is the client ID ID tokens must be issued for.
+	OIDCClientID string
+	// OIDCUsernameClaim is the ID token claim used as the name of the identity. Defaults to "sub".
+	OIDCUsernameClaim string
+	// TLSCertFile and TLSKeyFile are the serving certificate and key. The server uses plain HTTP if empty.
+	TLSCertFile string
+	TLSKeyFile  string
+	// ClientCAFile is the path to the CA bundle client certificates are verified against. Requires TLS.
+	ClientCAFile string
+	// AllowAnonymous lets the server start without any authentication method, accepting every request as the
+	// anonymous user.
+	AllowAnonymous bool
+	// NamespacePolicyFile is the path to a NamespacePolicy file. Without it, every identity may only use Namespace.
+	NamespacePolicyFile string
+	// S3Bucket is the bucket uploaded files are stored in. Takes precedence over LocalStorageDir.
+	S3Bucket string
+	// S3Prefix is the key prefix of uploaded files.
+	S3Prefix string
+	// S3Region is the region of the S3 bucket.
+	S3Region string
+	// S3Endpoint is a custom S3 endpoint, e.g. of an S3-compatible object store.
+	S3Endpoint string
+	// LocalStorageDir is the directory uploaded files are stored in if no S3 bucket is set, e.g. a volume shared
+	// with the Spark pods. Uploads are disabled if neither is set.
+	LocalStorageDir string
+	// LocalStorageURL is the URL Spark pods can download the files in LocalStorageDir from. Defaults to the
+	// file:// URL of the directory.
+	LocalStorageURL string
+	// UploadDir is the directory chunked uploads are staged in until they are complete.
+	UploadDir string
+}
+
+// Server is the REST API gateway that translates sparkcli requests into SparkApplication operations.
+type Server struct {
+	config          Config
+	crdClient       crdclientset.Interface
+	kubeClient      clientset.Interface
+	authenticators  []Authenticator
+	namespacePolicy *NamespacePolicy
+	storage         Storage
+	server          *http.Server
+
+	// uploadMutex guards the staged chunked uploads and activeUploads.
+	uploadMutex sync.Mutex
+	// activeUploads are the chunked uploads currently receiving a chunk.
+	activeUploads map[string]bool
+}
+
+// NewServer creates a new API gateway Server.
+func NewServer(config Config, crdClient crdclientset.Interface, kubeClient clientset.Interface) (*Server, error) {
+	s := &Server{
+		config:     config,
+		crdClient:  crdClient,
+		kubeClient: kubeClient,
+	}
+
+	if config.BasicAuthFile != "" {
+		authenticator, err := newBasicAuthenticator(config.BasicAuthFile)
+		if err != nil {
+			return nil, err
+		}
+		s.authenticators = append(s.authenticators, authenticator)
+	}
+	if config.TokenAuthFile != "" {
+		authenticator, err := newTokenAuthenticator(config.TokenAuthFile)
+		if err != nil {
+			return nil, err
+		}
+		s.authenticators = append(s.authenticators, authenticator)
+	}
+	if config.OIDCIssuerURL != "" {
+		if config.OIDCClientID == "" {
+			return nil, fmt.Errorf("an OIDC client ID is required with an OIDC issuer URL")
+		}
+		s.authenticators = append(s.authenticators,
+			newOIDCAuthenticator(config.OIDCIssuerURL, config.OIDCClientID, config.OIDCUsernameClaim))
+	}
+
+	var tlsConfig *tls.Config
+	if config.ClientCAFile != "" {
+		if config.TLSCertFile == "" || config.TLSKeyFile == "" {
+			return nil, fmt.Errorf("a TLS certificate and key are required to verify client certificates")
+		}
+		caBundle, err := ioutil.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file %s: %v", config.ClientCAFile, err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", config.ClientCAFile)
+		}
+		tlsConfig = &tls.Config{ClientCAs: clientCAs, ClientAuth: tls.VerifyClientCertIfGiven}
+		s.authenticators = append(s.authenticators, certificateAuthenticator{})
+	}
+
+	if len(s.authenticators) == 0 {
+		if !config.AllowAnonymous {
+			return nil, fmt.Errorf("no authentication method is configured and anonymous access is not allowed")
+		}
+		glog.Warning("No authentication method is configured, every request is accepted as the anonymous user")
+	}
+
+	if config.NamespacePolicyFile != "" {
+		policy, err := loadNamespacePolicy(config.NamespacePolicyFile)
+		if err != nil {
+			return nil, err
+		}
+		s.namespacePolicy = policy
+	}
+
+	var err error
+	if config.S3Bucket != "" {
+		s.storage, err = NewS3Storage(config.S3Bucket, config.S3Prefix, config.S3Region, config.S3Endpoint)
+	} else if config.LocalStorageDir != "" {
+		s.storage, err = NewLocalStorage(config.LocalStorageDir, config.LocalStorageURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.config.UploadDir == "" {
+		s.config.UploadDir = filepath.Join(os.TempDir(), "spark-api-gateway-uploads")
+	}
+
+	s.server = &http.Server{
+		Addr:      fmt.Sprintf(":%d", config.Port),
+		Handler:   s.handler(),
+		TLSConfig: tlsConfig,
+	}
+	return s, nil
+}
+
+// Start starts serving requests in the background.
+func (s *Server) Start() {
+	go func() {
+		glog.Infof("Starting the Spark API gateway server on %s", s.server.Addr)
+		var err error
+		if s.config.TLSCertFile != "" {
+			err = s.server.ListenAndServeTLS(s.config.TLSCertFile, s.config.TLSKeyFile)
+		} else {
+			err = s.server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			glog.Errorf("error while serving the Spark API gateway: %v", err)
+		}
+	}()
+}
+
+// Stop gracefully shuts down the server.
+func (s *Server) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	glog.Info("Stopping the Spark API gateway server")
+	return s.server.Shutdown(ctx)
+}
+
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle("/submissions", s.authenticated(http.HandlerFunc(s.serveSubmissions)))
+	mux.Handle("/submissions/", s.authenticated(http.HandlerFunc(s.serveSubmission)))
+	mux.Handle("/deploy/killByName", s.authenticated(http.HandlerFunc(s.killSubmissionsByName)))
+	mux.Handle("/s3/upload", s.authenticated(http.HandlerFunc(s.uploadFile)))
+	mux.Handle("/uploads", s.authenticated(http.HandlerFunc(s.serveUploads)))
+	mux.Handle("/uploads/", s.authenticated(http.HandlerFunc(s.serveUpload)))
+	return mux
+}
+
+// serveSubmissions handles requests to /submissions.
+func (s *Server) serveSubmissions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listSubmissions(w, r)
+	case http.MethodPost:
+		s.submitApplication(w, r, "")
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// serveSubmission handles requests to /submissions/{id} and its sub-resources.
+func (s *Server) serveSubmission(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/submissions/"), "/"), "/")
+	submissionID := parts[0]
+	if submissionID == "" || len(parts) > 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodPost:
+		s.submitApplication(w, r, submissionID)
+	case action == "" && r.Method == http.MethodGet:
+		s.getSubmissionStatus(w, r, submissionID)
+	case action == "" && r.Method == http.MethodDelete:
+		s.deleteSubmission(w, r, submissionID)
+	case action == "status" && r.Method == http.MethodGet:
+		s.getSubmissionStatus(w, r, submissionID)
+	case action == "log" && r.Method == http.MethodGet:
+		s.getSubmissionLog(w, r, submissionID)
+	case action == "kill" && r.Method == http.MethodPost:
+		s.killSubmission(w, r, submissionID)
+	case action == "" || action == "status" || action == "log" || action == "kill":
+		methodNotAllowed(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, response interface{}) {
+	body, err := json.Marshal(response)
+	if err != nil {
+		internalError(w, fmt.Errorf("failed to serialize response: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(body); err != nil {
+		glog.Errorf("failed to write response: %v", err)
+	}
+}
+
+func badRequest(w http.ResponseWriter, err error) {
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, fmt.Sprintf("method %s is not allowed for %s", r.Method, r.URL.Path), http.StatusMethodNotAllowed)
+}
+
+func internalError(w http.ResponseWriter, err error) {
+	glog.Errorf("internal error: %v", err)
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,322 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+
+	apigatewayv1 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apigateway/apis/v1"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	crdclientfake "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/client/clientset/versioned/fake"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+type fakeStorage struct {
+	uploaded map[string][]byte
+}
+
+func (u *fakeStorage) Put(key string, content io.Reader) (string, error) {
+	data, err := ioutil.ReadAll(content)
+	if err != nil {
+		return "", err
+	}
+	u.uploaded[key] = data
+	return "s3a://bucket/" + key, nil
+}
+
+func (u *fakeStorage) URL(key string) (string, error) {
+	if _, ok := u.uploaded[key]; !ok {
+		return "", nil
+	}
+	return "s3a://bucket/" + key, nil
+}
+
+func TestNewServerRequiresAuthentication(t *testing.T) {
+	crdClient := crdclientfake.NewSimpleClientset()
+	kubeClient := kubeclientfake.NewSimpleClientset()
+
+	_, err := NewServer(Config{Namespace: "default"}, crdClient, kubeClient)
+	assert.Error(t, err)
+
+	server, err := NewServer(Config{Namespace: "default", AllowAnonymous: true}, crdClient, kubeClient)
+	assert.NoError(t, err)
+	assert.Empty(t, server.authenticators)
+}
+
+func newFakeServer() (*Server, *crdclientfake.Clientset, *kubeclientfake.Clientset) {
+	crdClient := crdclientfake.NewSimpleClientset()
+	kubeClient := kubeclientfake.NewSimpleClientset()
+	server := &Server{
+		config: Config{
+			Namespace:    "spark",
+			SparkVersion: "3.1.1",
+			Image:        "spark:3.1.1",
+		},
+		crdClient:  crdClient,
+		kubeClient: kubeClient,
+		storage:    &fakeStorage{uploaded: map[string][]byte{}},
+	}
+	return server, crdClient, kubeClient
+}
+
+func doRequest(t *testing.T, s *Server, method string, url string, body interface{}, response interface{}) int {
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, url, reader)
+	recorder := httptest.NewRecorder()
+	s.handler().ServeHTTP(recorder, req)
+	if response != nil && recorder.Code == http.StatusOK {
+		if err := json.Unmarshal(recorder.Body.Bytes(), response); err != nil {
+			t.Fatalf("failed to parse response %q: %v", recorder.Body.String(), err)
+		}
+	}
+	return recorder.Code
+}
+
+func TestSubmitApplication(t *testing.T) {
+	s, crdClient, _ := newFakeServer()
+
+	request := apigatewayv1.SparkApplicationSubmissionRequest{
+		ApplicationName:     "word-count",
+		MainApplicationFile: "s3a://bucket/app.jar",
+		MainClass:           "org.example.WordCount",
+		Arguments:           []string{"input"},
+		Driver:              apigatewayv1.DriverSpec{Cores: 1, Memory: "1g"},
+		Executor:            apigatewayv1.ExecutorSpec{Instances: 2, Memory: "2g"},
+	}
+	response := apigatewayv1.SparkApplicationSubmissionResponse{}
+	assert.Equal(t, http.StatusOK, doRequest(t, s, http.MethodPost, "/submissions", request, &response))
+	assert.NotEmpty(t, response.SubmissionId)
+
+	app, err := crdClient.SparkoperatorV1beta2().SparkApplications("spark").Get(context.TODO(), response.SubmissionId, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "word-count", app.Labels[config.GatewayApplicationNameLabel])
+	assert.Equal(t, v1beta2.ScalaApplicationType, app.Spec.Type)
+	assert.Equal(t, "3.1.1", app.Spec.SparkVersion)
+	assert.Equal(t, "spark:3.1.1", *app.Spec.Image)
+	assert.Equal(t, "org.example.WordCount", *app.Spec.MainClass)
+	assert.Equal(t, int32(2), *app.Spec.Executor.Instances)
+	assert.Equal(t, "1g", *app.Spec.Driver.Memory)
+	assert.Nil(t, app.Spec.Executor.Cores)
+
+	// Submitting with an existing ID fails unless overwrite is requested.
+	url := "/submissions/" + response.SubmissionId
+	assert.Equal(t, http.StatusConflict, doRequest(t, s, http.MethodPost, url, request, nil))
+	assert.Equal(t, http.StatusOK, doRequest(t, s, http.MethodPost, url+"?overwrite=true", request, nil))
+
+	assert.Equal(t, http.StatusBadRequest, doRequest(t, s, http.MethodPost, "/submissions/Invalid_ID", request, nil))
+	assert.Equal(t, http.StatusBadRequest, doRequest(t, s, http.MethodPost, "/submissions",
+		apigatewayv1.SparkApplicationSubmissionRequest{}, nil))
+}
+
+func TestGetSubmissionStatusAndList(t *testing.T) {
+	s, crdClient, _ := newFakeServer()
+
+	apps := []*v1beta2.SparkApplication{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "s-1",
+				Namespace:         "spark",
+				Labels:            map[string]string{config.GatewayApplicationNameLabel: "etl"},
+				CreationTimestamp: metav1.Unix(100, 0),
+			},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState: v1beta2.ApplicationState{State: v1beta2.CompletedState},
+				Executors: []v1beta2.ExecutorStatus{
+					{PodName: "exec-1", State: v1beta2.ExecutorCompletedState},
+					{PodName: "exec-3", State: v1beta2.ExecutorFailedState},
+				},
+				RolledUpExecutors: &v1beta2.RolledUpExecutors{Completed: 1},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "s-2",
+				Namespace:         "spark",
+				Labels:            map[string]string{config.GatewayApplicationNameLabel: "etl"},
+				Annotations:       map[string]string{config.GatewayKilledAnnotation: "true"},
+				CreationTimestamp: metav1.Unix(200, 0),
+			},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState: v1beta2.ApplicationState{State: v1beta2.FailedState},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "s-3",
+				Namespace:         "spark",
+				CreationTimestamp: metav1.Unix(300, 0),
+			},
+		},
+	}
+	for _, app := range apps {
+		if _, err := crdClient.SparkoperatorV1beta2().SparkApplications("spark").Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status := apigatewayv1.SubmissionStatusResponse{}
+	assert.Equal(t, http.StatusOK, doRequest(t, s, http.MethodGet, "/submissions/s-1/status", nil, &status))
+	assert.Equal(t, "s-1", status.SubmissionId)
+	assert.Equal(t, "etl", status.ApplicationName)
+	assert.Equal(t, "COMPLETED", status.State)
+	assert.Equal(t, apigatewayv1.ExecutorCounts{Completed: 2, Failed: 1}, status.Executors)
+
+	assert.Equal(t, http.StatusOK, doRequest(t, s, http.MethodGet, "/submissions/s-2/status", nil, &status))
+	assert.Equal(t, "KILLED", status.State)
+	assert.Equal(t, http.StatusOK, doRequest(t, s, http.MethodGet, "/submissions/s-3/status", nil, &status))
+	assert.Equal(t, "NEW", status.State)
+	assert.Equal(t, http.StatusNotFound, doRequest(t, s, http.MethodGet, "/submissions/s-4/status", nil, nil))
+
+	submissionIDs := func(response apigatewayv1.ListSubmissionsResponse) []string {
+		var ids []string
+		for _, submission := range response.Submissions {
+			ids = append(ids, submission.SubmissionId)
+		}
+		return ids
+	}
+
+	list := apigatewayv1.ListSubmissionsResponse{}
+	assert.Equal(t, http.StatusOK, doRequest(t, s, http.MethodGet, "/submissions", nil, &list))
+	assert.Equal(t, []string{"s-3", "s-2", "s-1"}, submissionIDs(list))
+
+	list = apigatewayv1.ListSubmissionsResponse{}
+	assert.Equal(t, http.StatusOK, doRequest(t, s, http.MethodGet, "/submissions?applicationName=etl&ignoreKilled=true", nil, &list))
+	assert.Equal(t, []string{"s-1"}, submissionIDs(list))
+
+	list = apigatewayv1.ListSubmissionsResponse{}
+	assert.Equal(t, http.StatusOK, doRequest(t, s, http.MethodGet, "/submissions?limit=1", nil, &list))
+	assert.Equal(t, []string{"s-3"}, submissionIDs(list))
+
+	list = apigatewayv1.ListSubmissionsResponse{}
+	assert.Equal(t, http.StatusOK, doRequest(t, s, http.MethodGet, "/submissions?state=completed", nil, &list))
+	assert.Equal(t, []string{"s-1"}, submissionIDs(list))
+}
+
+func TestKillAndDeleteSubmission(t *testing.T) {
+	s, crdClient, kubeClient := newFakeServer()
+
+	for _, name := range []string{"s-1", "s-2"} {
+		app := &v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "spark",
+				Labels:    map[string]string{config.GatewayApplicationNameLabel: "etl"},
+			},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState:   v1beta2.ApplicationState{State: v1beta2.RunningState},
+				DriverInfo: v1beta2.DriverInfo{PodName: name + "-driver"},
+			},
+		}
+		if _, err := crdClient.SparkoperatorV1beta2().SparkApplications("spark").Create(context.TODO(), app, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		pod := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name + "-driver", Namespace: "spark"}}
+		if _, err := kubeClient.CoreV1().Pods("spark").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	killResponse := apigatewayv1.KillSubmissionResponse{}
+	assert.Equal(t, http.StatusOK, doRequest(t, s, http.MethodPost, "/submissions/s-1/kill", apigatewayv1.KillSubmissionRequest{}, &killResponse))
+	assert.Equal(t, "s-1", killResponse.SubmissionId)
+	_, err := kubeClient.CoreV1().Pods("spark").Get(context.TODO(), "s-1-driver", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	app, err := crdClient.SparkoperatorV1beta2().SparkApplications("spark").Get(context.TODO(), "s-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "true", app.Annotations[config.GatewayKilledAnnotation])
+
+	// s-1 was already killed, so only s-2 is killed by name.
+	killByNameResponse := apigatewayv1.KillSubmissionByNameResponse{}
+	assert.Equal(t, http.StatusOK, doRequest(t, s, http.MethodPost, "/deploy/killByName",
+		apigatewayv1.KillSubmissionByNameRequest{ApplicationName: "etl"}, &killByNameResponse))
+	assert.Equal(t, []string{"s-2"}, killByNameResponse.SubmissionIds)
+
+	deleteResponse := apigatewayv1.DeleteSubmissionResponse{}
+	assert.Equal(t, http.StatusOK, doRequest(t, s, http.MethodDelete, "/submissions/s-1", nil, &deleteResponse))
+	assert.Equal(t, "s-1", deleteResponse.SubmissionId)
+	assert.Equal(t, http.StatusNotFound, doRequest(t, s, http.MethodDelete, "/submissions/s-1", nil, nil))
+}
+
+func TestUploadFile(t *testing.T) {
+	s, _, _ := newFakeServer()
+
+	req := httptest.NewRequest(http.MethodPost, "/s3/upload?name=app.jar", bytes.NewReader([]byte("jar")))
+	recorder := httptest.NewRecorder()
+	s.handler().ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	response := apigatewayv1.UploadFileResponse{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, strings.HasPrefix(response.Url, "s3a://bucket/"))
+	assert.True(t, strings.HasSuffix(response.Url, "/app.jar"))
+	assert.Equal(t, []byte("jar"), s.storage.(*fakeStorage).uploaded[strings.TrimPrefix(response.Url, "s3a://bucket/")])
+}
+
+func TestBasicAuthentication(t *testing.T) {
+	s, _, _ := newFakeServer()
+	s.authenticators = []Authenticator{&basicAuthenticator{credentials: map[string]string{"alice": "secret"}}}
+
+	req := httptest.NewRequest(http.MethodGet, "/submissions", nil)
+	recorder := httptest.NewRecorder()
+	s.handler().ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/submissions", nil)
+	req.SetBasicAuth("alice", "wrong")
+	recorder = httptest.NewRecorder()
+	s.handler().ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/submissions", nil)
+	req.SetBasicAuth("alice", "secret")
+	recorder = httptest.NewRecorder()
+	s.handler().ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	// The health endpoint is not authenticated.
+	req = httptest.NewRequest(http.MethodGet, "/health", nil)
+	recorder = httptest.NewRecorder()
+	s.handler().ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
@@ -0,0 +1,174 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigateway
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// Storage stores uploaded files and returns URLs Spark can download them from.
+type Storage interface {
+	// Put stores the content under the key, replacing any existing file, and returns its URL.
+	Put(key string, content io.Reader) (string, error)
+	// URL returns the URL of the file stored under the key, or an empty string if there is none.
+	URL(key string) (string, error)
+}
+
+// s3Storage stores files in an S3 bucket and returns s3a:// URLs.
+type s3Storage struct {
+	bucket   string
+	prefix   string
+	client   *s3.S3
+	uploader *s3manager.Uploader
+}
+
+// NewS3Storage creates a Storage that stores files in the bucket under the key prefix. A custom endpoint can
+// be given for S3-compatible object stores.
+func NewS3Storage(bucket string, prefix string, region string, endpoint string) (Storage, error) {
+	// AWS SDK does require specifying regions, thus set it to default S3 region
+	if region == "" {
+		region = "us-east-1"
+	}
+	c := &aws.Config{Region: aws.String(region)}
+	if endpoint != "" {
+		c.Endpoint = aws.String(endpoint)
+		c.S3ForcePathStyle = aws.Bool(true)
+	}
+	sess, err := session.NewSession(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %v", err)
+	}
+	return &s3Storage{
+		bucket:   bucket,
+		prefix:   strings.Trim(prefix, "/"),
+		client:   s3.New(sess),
+		uploader: s3manager.NewUploader(sess),
+	}, nil
+}
+
+func (s *s3Storage) Put(key string, content io.Reader) (string, error) {
+	objectKey := path.Join(s.prefix, key)
+	_, err := s.uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(objectKey),
+		Body:   content,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %s to bucket %s: %v", objectKey, s.bucket, err)
+	}
+	return s.url(objectKey), nil
+}
+
+func (s *s3Storage) URL(key string) (string, error) {
+	objectKey := path.Join(s.prefix, key)
+	_, err := s.client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.RequestFailure); ok && awsErr.StatusCode() == 404 {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get %s in bucket %s: %v", objectKey, s.bucket, err)
+	}
+	return s.url(objectKey), nil
+}
+
+func (s *s3Storage) url(objectKey string) string {
+	return fmt.Sprintf("s3a://%s/%s", s.bucket, objectKey)
+}
+
+// localStorage stores files in a local directory, e.g. a volume shared with the Spark pods.
+type localStorage struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalStorage creates a Storage that stores files in the directory. The URLs of the files are the keys
+// appended to baseURL, which defaults to the file:// URL of the directory.
+func NewLocalStorage(dir string, baseURL string) (Storage, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage directory %s: %v", dir, err)
+	}
+	if err := os.MkdirAll(absDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory %s: %v", absDir, err)
+	}
+	if baseURL == "" {
+		baseURL = "file://" + filepath.ToSlash(absDir)
+	}
+	return &localStorage{dir: absDir, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+func (s *localStorage) Put(key string, content io.Reader) (string, error) {
+	filePath := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory for %s: %v", key, err)
+	}
+
+	// Write to a temporary file first so that a file is never visible partially written.
+	tmp, err := ioutil.TempFile(filepath.Dir(filePath), ".upload-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file for %s: %v", key, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write %s: %v", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", key, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return "", fmt.Errorf("failed to set permissions of %s: %v", key, err)
+	}
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return "", fmt.Errorf("failed to store %s: %v", key, err)
+	}
+	return s.url(key), nil
+}
+
+func (s *localStorage) URL(key string) (string, error) {
+	if _, err := os.Stat(s.path(key)); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get %s: %v", key, err)
+	}
+	return s.url(key), nil
+}
+
+func (s *localStorage) path(key string) string {
+	// Cleaning the key as an absolute path keeps the file inside the storage directory.
+	return filepath.Join(s.dir, filepath.FromSlash(path.Clean("/"+key)))
+}
+
+func (s *localStorage) url(key string) string {
+	return s.baseURL + path.Clean("/"+key)
+}
@@ -0,0 +1,483 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/google/uuid"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	apigatewayv1 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apigateway/apis/v1"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+const (
+	submissionIDPrefix = "s-"
+	// newSubmissionState is reported for submissions the operator has not processed yet.
+	newSubmissionState = "NEW"
+	// killedSubmissionState is reported for terminated submissions that were killed through the gateway.
+	killedSubmissionState = "KILLED"
+)
+
+func newSubmissionID() string {
+	return submissionIDPrefix + strings.ReplaceAll(uuid.New().String(), "-", "")
+}
+
+func validateSubmissionID(submissionID string) error {
+	if errs := validation.IsDNS1035Label(submissionID); len(errs) > 0 {
+		return fmt.Errorf("invalid submission ID %q: %s", submissionID, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// buildSparkApplication translates a submission request into a SparkApplication.
+func (s *Server) buildSparkApplication(
+	request *apigatewayv1.SparkApplicationSubmissionRequest,
+	submissionID string,
+	namespace string) (*v1beta2.SparkApplication, error) {
+	if request.MainApplicationFile == "" {
+		return nil, fmt.Errorf("mainApplicationFile must be specified")
+	}
+
+	appType := v1beta2.ScalaApplicationType
+	if request.Type != "" {
+		appType = v1beta2.SparkApplicationType(request.Type)
+		switch appType {
+		case v1beta2.JavaApplicationType, v1beta2.ScalaApplicationType, v1beta2.PythonApplicationType, v1beta2.RApplicationType:
+		default:
+			return nil, fmt.Errorf("unsupported application type %q", request.Type)
+		}
+	}
+
+	sparkVersion := request.SparkVersion
+	if sparkVersion == "" {
+		sparkVersion = s.config.SparkVersion
+	}
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      submissionID,
+			Namespace: namespace,
+			Labels:    map[string]string{},
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			Type:                appType,
+			SparkVersion:        sparkVersion,
+			Mode:                v1beta2.ClusterMode,
+			MainApplicationFile: stringPtr(request.MainApplicationFile),
+			Arguments:           request.Arguments,
+			SparkConf:           request.SparkConf,
+			HadoopConf:          request.HadoopConf,
+			Deps: v1beta2.Dependencies{
+				Jars:    request.Jars,
+				Files:   request.Files,
+				PyFiles: request.PyFiles,
+			},
+			RestartPolicy: v1beta2.RestartPolicy{Type: v1beta2.Never},
+		},
+	}
+
+	if request.ApplicationName != "" {
+		if errs := validation.IsValidLabelValue(request.ApplicationName); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid application name %q: %s", request.ApplicationName, strings.Join(errs, ", "))
+		}
+		app.Labels[config.GatewayApplicationNameLabel] = request.ApplicationName
+	}
+	if request.MainClass != "" {
+		app.Spec.MainClass = stringPtr(request.MainClass)
+	}
+	if request.Image != "" {
+		app.Spec.Image = stringPtr(request.Image)
+	} else if s.config.Image != "" {
+		app.Spec.Image = stringPtr(s.config.Image)
+	}
+
+	app.Spec.Driver.Cores = int32PtrOrNil(request.Driver.Cores)
+	app.Spec.Driver.Memory = stringPtrOrNil(request.Driver.Memory)
+	app.Spec.Driver.MemoryOverhead = stringPtrOrNil(request.Driver.MemoryOverhead)
+	if s.config.ServiceAccount != "" {
+		app.Spec.Driver.ServiceAccount = stringPtr(s.config.ServiceAccount)
+	}
+
+	app.Spec.Executor.Instances = int32PtrOrNil(request.Executor.Instances)
+	app.Spec.Executor.Cores = int32PtrOrNil(request.Executor.Cores)
+	app.Spec.Executor.Memory = stringPtrOrNil(request.Executor.Memory)
+	app.Spec.Executor.MemoryOverhead = stringPtrOrNil(request.Executor.MemoryOverhead)
+
+	return app, nil
+}
+
+// submitApplication creates a SparkApplication for the submission request. A new submission ID is generated
+// if submissionID is empty.
+func (s *Server) submitApplication(w http.ResponseWriter, r *http.Request, submissionID string) {
+	if submissionID == "" {
+		submissionID = newSubmissionID()
+	} else if err := validateSubmissionID(submissionID); err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	overwrite := false
+	if value := r.URL.Query().Get("overwrite"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			badRequest(w, fmt.Errorf("invalid value for overwrite: %s", value))
+			return
+		}
+		overwrite = parsed
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		badRequest(w, fmt.Errorf("failed to read the request body: %v", err))
+		return
+	}
+	request := apigatewayv1.SparkApplicationSubmissionRequest{}
+	if err := json.Unmarshal(body, &request); err != nil {
+		badRequest(w, fmt.Errorf("failed to parse the request body: %v", err))
+		return
+	}
+
+	requested := request.Namespace
+	if requested == "" {
+		requested = r.URL.Query().Get("namespace")
+	}
+	namespaces := s.requestNamespaces(w, r, requested)
+	if namespaces == nil {
+		return
+	}
+
+	app, err := s.buildSparkApplication(&request, submissionID, namespaces[0])
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	apps := s.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace)
+	_, err = apps.Create(context.TODO(), app, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) && overwrite {
+		glog.Infof("Overwriting submission %s/%s", app.Namespace, submissionID)
+		if err = apps.Delete(context.TODO(), submissionID, metav1.DeleteOptions{}); err == nil || errors.IsNotFound(err) {
+			_, err = apps.Create(context.TODO(), app, metav1.CreateOptions{})
+		}
+	}
+	if err != nil {
+		if errors.IsAlreadyExists(err) {
+			http.Error(w, fmt.Sprintf("submission %s already exists", submissionID), http.StatusConflict)
+			return
+		}
+		internalError(w, fmt.Errorf("failed to create SparkApplication %s/%s: %v", app.Namespace, submissionID, err))
+		return
+	}
+
+	glog.Infof("Submitted SparkApplication %s/%s", app.Namespace, submissionID)
+	writeJSON(w, apigatewayv1.SparkApplicationSubmissionResponse{SubmissionId: submissionID})
+}
+
+// getApplication returns the SparkApplication of a submission, or writes an error and returns nil. The
+// submission is looked up in the namespace query parameter, or in all the namespaces the caller may use.
+func (s *Server) getApplication(w http.ResponseWriter, r *http.Request, submissionID string) *v1beta2.SparkApplication {
+	namespaces := s.requestNamespaces(w, r, r.URL.Query().Get("namespace"))
+	if namespaces == nil {
+		return nil
+	}
+
+	for _, namespace := range namespaces {
+		app, err := s.crdClient.SparkoperatorV1beta2().SparkApplications(namespace).Get(context.TODO(), submissionID, metav1.GetOptions{})
+		if err == nil {
+			return app
+		}
+		if !errors.IsNotFound(err) {
+			internalError(w, fmt.Errorf("failed to get SparkApplication %s/%s: %v", namespace, submissionID, err))
+			return nil
+		}
+	}
+	http.Error(w, fmt.Sprintf("submission %s not found", submissionID), http.StatusNotFound)
+	return nil
+}
+
+// listApplications lists the SparkApplications matching the selector in the namespace query parameter, or in
+// all the namespaces the caller may use. It writes an error and returns nil if listing fails.
+func (s *Server) listApplications(w http.ResponseWriter, r *http.Request, selector labels.Selector) []v1beta2.SparkApplication {
+	namespaces := s.requestNamespaces(w, r, r.URL.Query().Get("namespace"))
+	if namespaces == nil {
+		return nil
+	}
+
+	apps := []v1beta2.SparkApplication{}
+	for _, namespace := range namespaces {
+		list, err := s.crdClient.SparkoperatorV1beta2().SparkApplications(namespace).List(
+			context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			internalError(w, fmt.Errorf("failed to list SparkApplications in namespace %s: %v", namespace, err))
+			return nil
+		}
+		apps = append(apps, list.Items...)
+	}
+	return apps
+}
+
+func (s *Server) getSubmissionStatus(w http.ResponseWriter, r *http.Request, submissionID string) {
+	app := s.getApplication(w, r, submissionID)
+	if app == nil {
+		return
+	}
+	writeJSON(w, toSubmissionStatus(app))
+}
+
+func (s *Server) listSubmissions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var limit int64
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			badRequest(w, fmt.Errorf("invalid value for limit: %s", value))
+			return
+		}
+		limit = parsed
+	}
+	ignoreKilled := false
+	if value := query.Get("ignoreKilled"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			badRequest(w, fmt.Errorf("invalid value for ignoreKilled: %s", value))
+			return
+		}
+		ignoreKilled = parsed
+	}
+	state := strings.ToUpper(query.Get("state"))
+
+	selector := labels.Everything()
+	if applicationName := query.Get("applicationName"); applicationName != "" {
+		selector = labels.SelectorFromSet(labels.Set{config.GatewayApplicationNameLabel: applicationName})
+	}
+
+	apps := s.listApplications(w, r, selector)
+	if apps == nil {
+		return
+	}
+	sort.SliceStable(apps, func(i, j int) bool {
+		return apps[j].CreationTimestamp.Before(&apps[i].CreationTimestamp)
+	})
+
+	response := apigatewayv1.ListSubmissionsResponse{Submissions: []apigatewayv1.SubmissionStatusResponse{}}
+	for i := range apps {
+		status := toSubmissionStatus(&apps[i])
+		if ignoreKilled && status.Killed {
+			continue
+		}
+		if state != "" && status.State != state {
+			continue
+		}
+		response.Submissions = append(response.Submissions, status)
+		if limit > 0 && int64(len(response.Submissions)) >= limit {
+			break
+		}
+	}
+	writeJSON(w, response)
+}
+
+func (s *Server) deleteSubmission(w http.ResponseWriter, r *http.Request, submissionID string) {
+	app := s.getApplication(w, r, submissionID)
+	if app == nil {
+		return
+	}
+
+	err := s.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Delete(context.TODO(), submissionID, metav1.DeleteOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			http.Error(w, fmt.Sprintf("submission %s not found", submissionID), http.StatusNotFound)
+			return
+		}
+		internalError(w, fmt.Errorf("failed to delete SparkApplication %s/%s: %v", app.Namespace, submissionID, err))
+		return
+	}
+
+	glog.Infof("Deleted SparkApplication %s/%s", app.Namespace, submissionID)
+	writeJSON(w, apigatewayv1.DeleteSubmissionResponse{
+		SubmissionId: submissionID,
+		Message:      fmt.Sprintf("submission %s deleted", submissionID),
+	})
+}
+
+func (s *Server) killSubmission(w http.ResponseWriter, r *http.Request, submissionID string) {
+	app := s.getApplication(w, r, submissionID)
+	if app == nil {
+		return
+	}
+
+	killed, err := s.kill(app)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, apigatewayv1.KillSubmissionResponse{
+		SubmissionId: submissionID,
+		State:        toSubmissionStatus(killed).State,
+	})
+}
+
+func (s *Server) killSubmissionsByName(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		badRequest(w, fmt.Errorf("failed to read the request body: %v", err))
+		return
+	}
+	request := apigatewayv1.KillSubmissionByNameRequest{}
+	if err := json.Unmarshal(body, &request); err != nil {
+		badRequest(w, fmt.Errorf("failed to parse the request body: %v", err))
+		return
+	}
+	if request.ApplicationName == "" {
+		badRequest(w, fmt.Errorf("applicationName must be specified"))
+		return
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{config.GatewayApplicationNameLabel: request.ApplicationName})
+	apps := s.listApplications(w, r, selector)
+	if apps == nil {
+		return
+	}
+
+	response := apigatewayv1.KillSubmissionByNameResponse{
+		ApplicationName: request.ApplicationName,
+		SubmissionIds:   []string{},
+	}
+	for i := range apps {
+		app := &apps[i]
+		if isTerminated(app) || app.Annotations[config.GatewayKilledAnnotation] != "" {
+			continue
+		}
+		if request.MaxApplicationCount > 0 && len(response.SubmissionIds) >= request.MaxApplicationCount {
+			break
+		}
+		if _, err := s.kill(app); err != nil {
+			internalError(w, err)
+			return
+		}
+		response.SubmissionIds = append(response.SubmissionIds, app.Name)
+	}
+	writeJSON(w, response)
+}
+
+// kill marks the SparkApplication as killed and deletes its driver pod, which terminates the application.
+func (s *Server) kill(app *v1beta2.SparkApplication) (*v1beta2.SparkApplication, error) {
+	if app.Annotations[config.GatewayKilledAnnotation] == "" {
+		toUpdate := app.DeepCopy()
+		if toUpdate.Annotations == nil {
+			toUpdate.Annotations = make(map[string]string)
+		}
+		toUpdate.Annotations[config.GatewayKilledAnnotation] = "true"
+		updated, err := s.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Update(context.TODO(), toUpdate, metav1.UpdateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to mark SparkApplication %s/%s as killed: %v", app.Namespace, app.Name, err)
+		}
+		app = updated
+	}
+
+	if podName := app.Status.DriverInfo.PodName; podName != "" && !isTerminated(app) {
+		err := s.kubeClient.CoreV1().Pods(app.Namespace).Delete(context.TODO(), podName, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to delete driver pod %s/%s: %v", app.Namespace, podName, err)
+		}
+	}
+
+	glog.Infof("Killed SparkApplication %s/%s", app.Namespace, app.Name)
+	return app, nil
+}
+
+func isTerminated(app *v1beta2.SparkApplication) bool {
+	state := app.Status.AppState.State
+	return state == v1beta2.CompletedState || state == v1beta2.FailedState
+}
+
+func toSubmissionStatus(app *v1beta2.SparkApplication) apigatewayv1.SubmissionStatusResponse {
+	status := apigatewayv1.SubmissionStatusResponse{
+		SubmissionId:       app.Name,
+		ApplicationName:    app.Labels[config.GatewayApplicationNameLabel],
+		Namespace:          app.Namespace,
+		State:              string(app.Status.AppState.State),
+		ErrorMessage:       app.Status.AppState.ErrorMessage,
+		SparkApplicationId: app.Status.SparkApplicationID,
+		DriverPodName:      app.Status.DriverInfo.PodName,
+		SparkUIUrl:         app.Status.DriverInfo.WebUIIngressAddress,
+		Killed:             app.Annotations[config.GatewayKilledAnnotation] != "",
+		CreationTime:       app.CreationTimestamp,
+		SubmissionTime:     app.Status.LastSubmissionAttemptTime,
+		TerminationTime:    app.Status.TerminationTime,
+	}
+	for _, executor := range app.Status.Executors {
+		switch executor.State {
+		case v1beta2.ExecutorPendingState:
+			status.Executors.Pending++
+		case v1beta2.ExecutorRunningState:
+			status.Executors.Running++
+		case v1beta2.ExecutorCompletedState:
+			status.Executors.Completed++
+		case v1beta2.ExecutorFailedState:
+			status.Executors.Failed++
+		}
+	}
+	if rolledUp := app.Status.RolledUpExecutors; rolledUp != nil {
+		status.Executors.Completed += int(rolledUp.Completed)
+		status.Executors.Failed += int(rolledUp.Failed)
+	}
+	if status.State == "" {
+		status.State = newSubmissionState
+	}
+	if status.Killed && (isTerminated(app) || app.Status.AppState.State == v1beta2.FailingState) {
+		status.State = killedSubmissionState
+	}
+	return status
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func stringPtrOrNil(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func int32PtrOrNil(n int32) *int32 {
+	if n == 0 {
+		return nil
+	}
+	return &n
+}
@@ -0,0 +1,419 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/google/uuid"
+
+	apigatewayv1 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apigateway/apis/v1"
+)
+
+// maxChunkSize is the maximum size of a chunk of a chunked upload.
+const maxChunkSize = 64 << 20
+
+var sha256Pattern = regexp.MustCompile("^[0-9a-f]{64}$")
+
+// uploadSession is the state of a chunked upload. It is stored next to the received content in the upload
+// directory, so uploads can be resumed after a restart of the server.
+type uploadSession struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+// storageKey returns the key the file of the upload is stored under. Files with the same content and name
+// share a key, which lets unchanged files be reused instead of uploaded again.
+func (u *uploadSession) storageKey() string {
+	return path.Join(u.Sha256, u.Name)
+}
+
+// uploadFile handles /s3/upload?name=<file name> with the file content as the request body.
+func (s *Server) uploadFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, r)
+		return
+	}
+	if s.storage == nil {
+		http.Error(w, "file upload is not enabled on this server", http.StatusNotImplemented)
+		return
+	}
+
+	name, err := uploadFileName(r.URL.Query().Get("name"))
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	// Every upload gets its own directory so that files with the same name never overwrite each other.
+	url, err := s.storage.Put(path.Join(uuid.New().String(), name), r.Body)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	glog.Infof("Uploaded file %s to %s", name, url)
+	writeJSON(w, apigatewayv1.UploadFileResponse{Url: url})
+}
+
+func uploadFileName(name string) (string, error) {
+	name = path.Base(name)
+	if name == "" || name == "." || name == "/" {
+		return "", fmt.Errorf("name must be specified")
+	}
+	return name, nil
+}
+
+// serveUploads handles requests to /uploads, which start or resume chunked uploads.
+func (s *Server) serveUploads(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, r)
+		return
+	}
+	if s.storage == nil {
+		http.Error(w, "file upload is not enabled on this server", http.StatusNotImplemented)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		badRequest(w, fmt.Errorf("failed to read the request body: %v", err))
+		return
+	}
+	request := apigatewayv1.CreateUploadRequest{}
+	if err := json.Unmarshal(body, &request); err != nil {
+		badRequest(w, fmt.Errorf("failed to parse the request body: %v", err))
+		return
+	}
+	session := uploadSession{Size: request.Size, Sha256: strings.ToLower(request.Sha256)}
+	if session.Name, err = uploadFileName(request.Name); err != nil {
+		badRequest(w, err)
+		return
+	}
+	if !sha256Pattern.MatchString(session.Sha256) {
+		badRequest(w, fmt.Errorf("sha256 must be a hex-encoded SHA-256 hash"))
+		return
+	}
+	if session.Size < 0 {
+		badRequest(w, fmt.Errorf("size must not be negative"))
+		return
+	}
+
+	url, err := s.storage.URL(session.storageKey())
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if url != "" {
+		glog.Infof("File %s with hash %s was already uploaded to %s", session.Name, session.Sha256, url)
+		writeJSON(w, apigatewayv1.UploadStatusResponse{ReceivedBytes: session.Size, Url: url})
+		return
+	}
+
+	uploadID := newUploadID(identityFromRequest(r), &session)
+	s.uploadMutex.Lock()
+	defer s.uploadMutex.Unlock()
+
+	received, err := s.receivedBytes(uploadID)
+	if os.IsNotExist(err) {
+		received, err = 0, s.createUploadSession(uploadID, &session)
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, apigatewayv1.UploadStatusResponse{
+		UploadId:      uploadID,
+		ReceivedBytes: received,
+		MaxChunkSize:  maxChunkSize,
+	})
+}
+
+// newUploadID returns the ID of the upload of a file by a user. The ID only depends on the file and the user,
+// so uploading the same file again resumes the previous upload, and users cannot write to each other's uploads.
+func newUploadID(identity *Identity, session *uploadSession) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s\n%s\n%s\n%d", identity.Name, session.Sha256, session.Name, session.Size)))
+	return "u-" + hex.EncodeToString(hash[:16])
+}
+
+// serveUpload handles requests to /uploads/{id} and /uploads/{id}/complete.
+func (s *Server) serveUpload(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/uploads/"), "/"), "/")
+	uploadID := parts[0]
+	if uploadID == "" || len(parts) > 2 || (len(parts) == 2 && parts[1] != "complete") {
+		http.NotFound(w, r)
+		return
+	}
+	if s.storage == nil {
+		http.Error(w, "file upload is not enabled on this server", http.StatusNotImplemented)
+		return
+	}
+	if uploadID != path.Base(uploadID) || !strings.HasPrefix(uploadID, "u-") {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		s.getUploadStatus(w, uploadID)
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		s.uploadChunk(w, r, uploadID)
+	case len(parts) == 2 && r.Method == http.MethodPost:
+		s.completeUpload(w, uploadID)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+func (s *Server) getUploadStatus(w http.ResponseWriter, uploadID string) {
+	s.uploadMutex.Lock()
+	defer s.uploadMutex.Unlock()
+
+	received, err := s.receivedBytes(uploadID)
+	if err != nil {
+		s.uploadError(w, uploadID, err)
+		return
+	}
+	writeJSON(w, apigatewayv1.UploadStatusResponse{
+		UploadId:      uploadID,
+		ReceivedBytes: received,
+		MaxChunkSize:  maxChunkSize,
+	})
+}
+
+// uploadChunk writes the request body at the offset given by the offset query parameter. The offset must not
+// be beyond the bytes received so far. A chunk that is cut short, e.g. by a dropped connection, keeps the bytes
+// that were received, and the client resumes from the offset reported by the upload status.
+func (s *Server) uploadChunk(w http.ResponseWriter, r *http.Request, uploadID string) {
+	offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+	if err != nil || offset < 0 {
+		badRequest(w, fmt.Errorf("invalid value for offset: %s", r.URL.Query().Get("offset")))
+		return
+	}
+
+	session, received, err := s.beginChunk(uploadID)
+	if err != nil {
+		s.uploadError(w, uploadID, err)
+		return
+	}
+	defer s.endChunk(uploadID)
+	if offset > received {
+		http.Error(w, fmt.Sprintf("offset %d is beyond the %d bytes received", offset, received), http.StatusConflict)
+		return
+	}
+
+	file, err := os.OpenFile(s.uploadPath(uploadID, ".part"), os.O_WRONLY, 0600)
+	if err != nil {
+		internalError(w, fmt.Errorf("failed to open upload %s: %v", uploadID, err))
+		return
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		internalError(w, fmt.Errorf("failed to seek in upload %s: %v", uploadID, err))
+		return
+	}
+
+	limit := session.Size - offset
+	if limit > maxChunkSize {
+		limit = maxChunkSize
+	}
+	written, err := io.Copy(file, io.LimitReader(r.Body, limit))
+	if err != nil {
+		glog.Warningf("Chunk of upload %s at offset %d was cut short after %d bytes: %v", uploadID, offset, written, err)
+		http.Error(w, fmt.Sprintf("failed to read chunk: %v", err), http.StatusBadRequest)
+		return
+	}
+	if n, _ := r.Body.Read(make([]byte, 1)); n > 0 {
+		http.Error(w, fmt.Sprintf("chunk at offset %d exceeds the file size or the maximum chunk size of %d bytes",
+			offset, maxChunkSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if offset+written > received {
+		received = offset + written
+	}
+	writeJSON(w, apigatewayv1.UploadStatusResponse{
+		UploadId:      uploadID,
+		ReceivedBytes: received,
+		MaxChunkSize:  maxChunkSize,
+	})
+}
+
+// completeUpload verifies the received content against the hash given when the upload was created, and moves
+// it to the storage.
+func (s *Server) completeUpload(w http.ResponseWriter, uploadID string) {
+	session, _, err := s.beginChunk(uploadID)
+	if err != nil {
+		s.uploadError(w, uploadID, err)
+		return
+	}
+	defer s.endChunk(uploadID)
+
+	partPath := s.uploadPath(uploadID, ".part")
+	file, err := os.Open(partPath)
+	if err != nil {
+		internalError(w, fmt.Errorf("failed to open upload %s: %v", uploadID, err))
+		return
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	received, err := io.Copy(hash, file)
+	if err != nil {
+		internalError(w, fmt.Errorf("failed to read upload %s: %v", uploadID, err))
+		return
+	}
+	if received != session.Size {
+		http.Error(w, fmt.Sprintf("received %d of %d bytes", received, session.Size), http.StatusConflict)
+		return
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != session.Sha256 {
+		// The content is corrupt, start over.
+		if err := os.Truncate(partPath, 0); err != nil {
+			glog.Errorf("failed to reset upload %s: %v", uploadID, err)
+		}
+		badRequest(w, fmt.Errorf("the SHA-256 hash of the received content does not match %s, the upload was reset", session.Sha256))
+		return
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		internalError(w, fmt.Errorf("failed to read upload %s: %v", uploadID, err))
+		return
+	}
+	url, err := s.storage.Put(session.storageKey(), file)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	s.removeUploadSession(uploadID)
+
+	glog.Infof("Uploaded file %s to %s", session.Name, url)
+	writeJSON(w, apigatewayv1.UploadFileResponse{Url: url})
+}
+
+// errUploadBusy is returned when a chunk is sent for an upload that is still receiving another chunk.
+var errUploadBusy = fmt.Errorf("another chunk of the upload is being received")
+
+// beginChunk marks the upload as receiving a chunk, so that the chunk can be written without holding the
+// upload lock, and returns the upload and the number of bytes received so far.
+func (s *Server) beginChunk(uploadID string) (*uploadSession, int64, error) {
+	s.uploadMutex.Lock()
+	defer s.uploadMutex.Unlock()
+
+	if s.activeUploads[uploadID] {
+		return nil, 0, errUploadBusy
+	}
+	session, err := s.loadUploadSession(uploadID)
+	if err != nil {
+		return nil, 0, err
+	}
+	received, err := s.receivedBytes(uploadID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if s.activeUploads == nil {
+		s.activeUploads = make(map[string]bool)
+	}
+	s.activeUploads[uploadID] = true
+	return session, received, nil
+}
+
+func (s *Server) endChunk(uploadID string) {
+	s.uploadMutex.Lock()
+	defer s.uploadMutex.Unlock()
+	delete(s.activeUploads, uploadID)
+}
+
+func (s *Server) uploadError(w http.ResponseWriter, uploadID string, err error) {
+	if err == errUploadBusy {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("upload %s not found", uploadID), http.StatusNotFound)
+		return
+	}
+	internalError(w, err)
+}
+
+func (s *Server) uploadPath(uploadID string, extension string) string {
+	return filepath.Join(s.config.UploadDir, uploadID+extension)
+}
+
+func (s *Server) createUploadSession(uploadID string, session *uploadSession) error {
+	if err := os.MkdirAll(s.config.UploadDir, 0700); err != nil {
+		return fmt.Errorf("failed to create upload directory %s: %v", s.config.UploadDir, err)
+	}
+	content, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to serialize upload %s: %v", uploadID, err)
+	}
+	if err := ioutil.WriteFile(s.uploadPath(uploadID, ".part"), nil, 0600); err != nil {
+		return fmt.Errorf("failed to create upload %s: %v", uploadID, err)
+	}
+	if err := ioutil.WriteFile(s.uploadPath(uploadID, ".json"), content, 0600); err != nil {
+		return fmt.Errorf("failed to create upload %s: %v", uploadID, err)
+	}
+	return nil
+}
+
+func (s *Server) loadUploadSession(uploadID string) (*uploadSession, error) {
+	content, err := ioutil.ReadFile(s.uploadPath(uploadID, ".json"))
+	if err != nil {
+		return nil, err
+	}
+	session := &uploadSession{}
+	if err := json.Unmarshal(content, session); err != nil {
+		return nil, fmt.Errorf("failed to parse upload %s: %v", uploadID, err)
+	}
+	return session, nil
+}
+
+// receivedBytes returns the number of bytes received for the upload. The returned error satisfies
+// os.IsNotExist if there is no such upload.
+func (s *Server) receivedBytes(uploadID string) (int64, error) {
+	if _, err := os.Stat(s.uploadPath(uploadID, ".json")); err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(s.uploadPath(uploadID, ".part"))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (s *Server) removeUploadSession(uploadID string) {
+	for _, extension := range []string{".json", ".part"} {
+		if err := os.Remove(s.uploadPath(uploadID, extension)); err != nil && !os.IsNotExist(err) {
+			glog.Errorf("failed to remove upload %s: %v", uploadID, err)
+		}
+	}
+}
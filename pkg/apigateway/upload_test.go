@@ -0,0 +1,156 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigateway
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apigatewayv1 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apigateway/apis/v1"
+)
+
+func newLocalStorageServer(t *testing.T) (*Server, string) {
+	dir, err := ioutil.TempDir("", "apigateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	s, _, _ := newFakeServer()
+	s.storage, err = NewLocalStorage(filepath.Join(dir, "storage"), "local:///mnt/uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.config.UploadDir = filepath.Join(dir, "staging")
+	return s, filepath.Join(dir, "storage")
+}
+
+func doUploadRequest(t *testing.T, s *Server, method string, url string, body []byte, response interface{}) int {
+	req := httptest.NewRequest(method, url, bytes.NewReader(body))
+	recorder := httptest.NewRecorder()
+	s.handler().ServeHTTP(recorder, req)
+	if response != nil && recorder.Code == http.StatusOK {
+		if err := json.Unmarshal(recorder.Body.Bytes(), response); err != nil {
+			t.Fatalf("failed to parse response %q: %v", recorder.Body.String(), err)
+		}
+	}
+	return recorder.Code
+}
+
+func TestChunkedUpload(t *testing.T) {
+	s, storageDir := newLocalStorageServer(t)
+
+	content := []byte("0123456789abcdefghij")
+	hash := sha256.Sum256(content)
+	request := apigatewayv1.CreateUploadRequest{Name: "app.jar", Size: int64(len(content)), Sha256: hex.EncodeToString(hash[:])}
+	requestBody, _ := json.Marshal(request)
+
+	status := apigatewayv1.UploadStatusResponse{}
+	assert.Equal(t, http.StatusOK, doUploadRequest(t, s, http.MethodPost, "/uploads", requestBody, &status))
+	assert.NotEmpty(t, status.UploadId)
+	assert.Equal(t, int64(0), status.ReceivedBytes)
+	assert.Empty(t, status.Url)
+	uploadURL := "/uploads/" + status.UploadId
+
+	assert.Equal(t, http.StatusOK, doUploadRequest(t, s, http.MethodPut, uploadURL+"?offset=0", content[:8], &status))
+	assert.Equal(t, int64(8), status.ReceivedBytes)
+	// Chunks must not leave gaps.
+	assert.Equal(t, http.StatusConflict, doUploadRequest(t, s, http.MethodPut, uploadURL+"?offset=12", content[12:], nil))
+	// Incomplete uploads cannot be completed.
+	assert.Equal(t, http.StatusConflict, doUploadRequest(t, s, http.MethodPost, uploadURL+"/complete", nil, nil))
+
+	// Creating the upload again resumes it.
+	resumed := apigatewayv1.UploadStatusResponse{}
+	assert.Equal(t, http.StatusOK, doUploadRequest(t, s, http.MethodPost, "/uploads", requestBody, &resumed))
+	assert.Equal(t, status.UploadId, resumed.UploadId)
+	assert.Equal(t, int64(8), resumed.ReceivedBytes)
+
+	// Resending received bytes is allowed, e.g. after a lost response.
+	assert.Equal(t, http.StatusOK, doUploadRequest(t, s, http.MethodPut, uploadURL+"?offset=4", content[4:12], &status))
+	assert.Equal(t, http.StatusOK, doUploadRequest(t, s, http.MethodPut, uploadURL+"?offset=12", content[12:], &status))
+	assert.Equal(t, int64(len(content)), status.ReceivedBytes)
+	assert.Equal(t, http.StatusOK, doUploadRequest(t, s, http.MethodGet, uploadURL, nil, &status))
+	assert.Equal(t, int64(len(content)), status.ReceivedBytes)
+
+	uploaded := apigatewayv1.UploadFileResponse{}
+	assert.Equal(t, http.StatusOK, doUploadRequest(t, s, http.MethodPost, uploadURL+"/complete", nil, &uploaded))
+	assert.Equal(t, fmt.Sprintf("local:///mnt/uploads/%s/app.jar", request.Sha256), uploaded.Url)
+	stored, err := ioutil.ReadFile(filepath.Join(storageDir, request.Sha256, "app.jar"))
+	assert.NoError(t, err)
+	assert.Equal(t, content, stored)
+	assert.Equal(t, http.StatusNotFound, doUploadRequest(t, s, http.MethodGet, uploadURL, nil, nil))
+
+	// Uploading the same file again is not needed.
+	deduplicated := apigatewayv1.UploadStatusResponse{}
+	assert.Equal(t, http.StatusOK, doUploadRequest(t, s, http.MethodPost, "/uploads", requestBody, &deduplicated))
+	assert.Equal(t, uploaded.Url, deduplicated.Url)
+	assert.Empty(t, deduplicated.UploadId)
+}
+
+func TestChunkedUploadHashMismatch(t *testing.T) {
+	s, _ := newLocalStorageServer(t)
+
+	request := apigatewayv1.CreateUploadRequest{Name: "app.jar", Size: 3, Sha256: hex.EncodeToString(make([]byte, 32))}
+	requestBody, _ := json.Marshal(request)
+	status := apigatewayv1.UploadStatusResponse{}
+	assert.Equal(t, http.StatusOK, doUploadRequest(t, s, http.MethodPost, "/uploads", requestBody, &status))
+	uploadURL := "/uploads/" + status.UploadId
+
+	// Chunks beyond the file size are rejected.
+	assert.Equal(t, http.StatusRequestEntityTooLarge, doUploadRequest(t, s, http.MethodPut, uploadURL+"?offset=0", []byte("jars"), nil))
+	assert.Equal(t, http.StatusOK, doUploadRequest(t, s, http.MethodPut, uploadURL+"?offset=0", []byte("jar"), &status))
+	assert.Equal(t, http.StatusBadRequest, doUploadRequest(t, s, http.MethodPost, uploadURL+"/complete", nil, nil))
+	assert.Equal(t, http.StatusOK, doUploadRequest(t, s, http.MethodGet, uploadURL, nil, &status))
+	assert.Equal(t, int64(0), status.ReceivedBytes)
+
+	invalid, _ := json.Marshal(apigatewayv1.CreateUploadRequest{Name: "app.jar", Size: 3, Sha256: "abc"})
+	assert.Equal(t, http.StatusBadRequest, doUploadRequest(t, s, http.MethodPost, "/uploads", invalid, nil))
+}
+
+func TestLocalStorageKeepsFilesInDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apigateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	storage, err := NewLocalStorage(filepath.Join(dir, "storage"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, err := storage.URL("a/b.jar")
+	assert.NoError(t, err)
+	assert.Empty(t, url)
+
+	url, err = storage.Put("../../b.jar", bytes.NewReader([]byte("jar")))
+	assert.NoError(t, err)
+	assert.Equal(t, "file://"+filepath.ToSlash(filepath.Join(dir, "storage", "b.jar")), url)
+	found, err := storage.URL("b.jar")
+	assert.NoError(t, err)
+	assert.Equal(t, url, found)
+}
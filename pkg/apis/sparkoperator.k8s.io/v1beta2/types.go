@@ -20,6 +20,8 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // SparkApplicationType describes the type of a Spark application.
@@ -71,8 +73,147 @@ type RestartPolicy struct {
 	// +kubebuilder:validation:Minimum=1
 	// +optional
 	OnFailureRetryInterval *int64 `json:"onFailureRetryInterval,omitempty"`
+
+	// BackoffStrategy is how the interval between retries grows with the number of attempts, based on
+	// OnFailureRetryInterval or OnSubmissionFailureRetryInterval. Linear waits attempts times the interval,
+	// Exponential waits the interval times BackoffMultiplier to the power of attempts minus one, and Fixed always
+	// waits the interval.
+	// Defaults to Linear.
+	// +kubebuilder:validation:Enum={Linear,Exponential,Fixed}
+	// +optional
+	BackoffStrategy BackoffStrategy `json:"backoffStrategy,omitempty"`
+
+	// BackoffMultiplier is the factor the interval grows by with each attempt if BackoffStrategy is Exponential,
+	// given as a decimal number, e.g. "1.5".
+	// Defaults to "2".
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	// +optional
+	BackoffMultiplier *string `json:"backoffMultiplier,omitempty"`
+
+	// MaxRetryInterval is the maximum interval in seconds between retries.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxRetryInterval *int64 `json:"maxRetryInterval,omitempty"`
+
+	// BackoffJitterPercent randomly lengthens or shortens each interval by up to the given percentage, so that
+	// applications failing at the same time do not all retry at the same time.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	BackoffJitterPercent *int32 `json:"backoffJitterPercent,omitempty"`
+
+	// Rules decide whether a failed run is retried based on why the driver terminated. The first rule matching
+	// the termination reason and exit code of the driver applies. Runs not matched by any rule are retried
+	// according to Type and OnFailureRetries.
+	// +optional
+	Rules []RestartRule `json:"rules,omitempty"`
+
+	// MemoryEscalation, if set, reruns applications that failed because the driver or executors ran out of memory
+	// with more memory. The spec of the application is left unchanged.
+	// +optional
+	MemoryEscalation *MemoryEscalation `json:"memoryEscalation,omitempty"`
+}
+
+// MemoryEscalation increases the memory of the driver or the executors with every rerun of an application whose
+// driver or executor containers were OOMKilled.
+type MemoryEscalation struct {
+	// Factor multiplies the memory and memory overhead of the driver or the executors with every such rerun, given
+	// as a decimal number, e.g. "1.5".
+	// Defaults to "1.5".
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	// +optional
+	Factor *string `json:"factor,omitempty"`
+	// MaxMemory is the most memory the driver and the executors are given, e.g. "16g".
+	MaxMemory string `json:"maxMemory"`
 }
 
+// RestartRule decides whether a failed run of an application is retried, based on why its driver terminated.
+// A rule matches a run if the termination reason is one of Reasons, if given, and the exit code of the driver is in
+// one of ExitCodes, if given. A rule with neither matches every failed run.
+type RestartRule struct {
+	// Name identifies the rule in the RestartRuleRetries of the status.
+	// Defaults to "rule-" followed by the index of the rule.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Reasons are the termination reasons the rule matches.
+	// +optional
+	Reasons []TerminationReason `json:"reasons,omitempty"`
+	// ExitCodes are the ranges of driver exit codes the rule matches.
+	// +optional
+	ExitCodes []ExitCodeRange `json:"exitCodes,omitempty"`
+	// Action is whether to retry matching runs or to give up.
+	// +kubebuilder:validation:Enum={Retry,Fail}
+	Action RestartRuleAction `json:"action"`
+	// Retries is the number of times runs matching the rule are retried. Such retries do not count against
+	// OnFailureRetries. If not set, matching runs are retried according to Type and OnFailureRetries.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Retries *int32 `json:"retries,omitempty"`
+}
+
+// ExitCodeRange is a range of container exit codes.
+type ExitCodeRange struct {
+	// Min is the smallest exit code in the range.
+	Min int32 `json:"min"`
+	// Max is the largest exit code in the range.
+	// Defaults to Min.
+	// +optional
+	Max *int32 `json:"max,omitempty"`
+}
+
+// RestartRuleAction is what a RestartRule does with the runs it matches.
+type RestartRuleAction string
+
+const (
+	RestartRuleRetry RestartRuleAction = "Retry"
+	RestartRuleFail  RestartRuleAction = "Fail"
+)
+
+// TerminationReason classifies why the driver of an application terminated.
+// +kubebuilder:validation:Enum={OOMKilled,Evicted,NodeLost,Preempted,ImagePullFailure,ContainerConfigError,PodDeleted,DeadlineExceeded,PendingTimeout,ProgressDeadlineExceeded,ExecutorFailureThresholdExceeded}
+type TerminationReason string
+
+const (
+	// OOMKilledReason means the driver container was killed for exceeding its memory limit.
+	OOMKilledReason TerminationReason = "OOMKilled"
+	// EvictedReason means the driver pod was evicted, e.g. because its node ran out of resources.
+	EvictedReason TerminationReason = "Evicted"
+	// NodeLostReason means the node of the driver pod became unreachable or was shut down.
+	NodeLostReason TerminationReason = "NodeLost"
+	// PreemptedReason means the driver pod was preempted by a pod of higher priority.
+	PreemptedReason TerminationReason = "Preempted"
+	// ImagePullFailureReason means the image of the driver container could not be pulled.
+	ImagePullFailureReason TerminationReason = "ImagePullFailure"
+	// ContainerConfigErrorReason means the driver container could not be created because of its configuration, e.g.
+	// because a Secret or ConfigMap it refers to does not exist.
+	ContainerConfigErrorReason TerminationReason = "ContainerConfigError"
+	// PodDeletedReason means the driver pod was deleted before it terminated.
+	PodDeletedReason TerminationReason = "PodDeleted"
+	// DeadlineExceededReason means the run was killed because it was active longer than ActiveDeadlineSeconds.
+	DeadlineExceededReason TerminationReason = "DeadlineExceeded"
+	// PendingTimeoutReason means the run was killed because its driver or executors were not scheduled within
+	// PendingTimeoutSeconds.
+	PendingTimeoutReason TerminationReason = "PendingTimeout"
+	// ProgressDeadlineExceededReason means the run was killed because it made no progress within
+	// ProgressDeadlineSeconds.
+	ProgressDeadlineExceededReason TerminationReason = "ProgressDeadlineExceeded"
+	// ExecutorFailureThresholdExceededReason means the run was killed because more of its executors failed than
+	// MaxFailures allows.
+	ExecutorFailureThresholdExceededReason TerminationReason = "ExecutorFailureThresholdExceeded"
+)
+
+// ProgressDeadlineAction is what the operator does with an application that exceeds its progress deadline.
+// +kubebuilder:validation:Enum={Warn,Kill}
+type ProgressDeadlineAction string
+
+const (
+	// ProgressDeadlineWarn records a warning event.
+	ProgressDeadlineWarn ProgressDeadlineAction = "Warn"
+	// ProgressDeadlineKill records a warning event, saves a thread dump of the driver in a ConfigMap and kills the
+	// run, which is then retried according to the RestartPolicy.
+	ProgressDeadlineKill ProgressDeadlineAction = "Kill"
+)
+
 type RestartPolicyType string
 
 const (
@@ -81,6 +222,15 @@ const (
 	Always    RestartPolicyType = "Always"
 )
 
+// BackoffStrategy is how the interval between retries of an application grows.
+type BackoffStrategy string
+
+const (
+	LinearBackoff      BackoffStrategy = "Linear"
+	ExponentialBackoff BackoffStrategy = "Exponential"
+	FixedBackoff       BackoffStrategy = "Fixed"
+)
+
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +k8s:defaulter-gen=true
@@ -284,6 +434,31 @@ type SparkApplicationSpec struct {
 	// scheduler backend since Spark 3.0.
 	// +optional
 	DynamicAllocation *DynamicAllocation `json:"dynamicAllocation,omitempty"`
+	// ActiveDeadlineSeconds is the duration in seconds a run of the application may be active, counted from its
+	// submission. A run exceeding it is killed and fails with reason DeadlineExceeded.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty"`
+	// PendingTimeoutSeconds is the duration in seconds within which the driver and the minimum number of executors
+	// of a run have to be scheduled, counted from its submission. A run exceeding it is killed and fails with reason
+	// PendingTimeout.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	PendingTimeoutSeconds *int64 `json:"pendingTimeoutSeconds,omitempty"`
+	// ProgressDeadlineSeconds is the duration in seconds a running application may go without completing a job, stage
+	// or task before it is considered hung, as observed through the REST API of its driver. It requires the operator to
+	// create UI services.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ProgressDeadlineSeconds *int64 `json:"progressDeadlineSeconds,omitempty"`
+	// ProgressDeadlineAction is what the operator does with a hung application. Defaults to Warn.
+	// +optional
+	ProgressDeadlineAction ProgressDeadlineAction `json:"progressDeadlineAction,omitempty"`
+	// Suspend tells the controller to hold the submission of the application, or to stop its current run, if set
+	// to true. A stopped run does not count as an attempt. The application is resubmitted when Suspend is cleared.
+	// +optional
+	// Defaults to false.
+	Suspend *bool `json:"suspend,omitempty"`
 }
 
 // BatchSchedulerConfiguration used to configure how to batch scheduling Spark Application
@@ -341,6 +516,8 @@ const (
 	SucceedingState       ApplicationStateType = "SUCCEEDING"
 	FailingState          ApplicationStateType = "FAILING"
 	UnknownState          ApplicationStateType = "UNKNOWN"
+	SuspendingState       ApplicationStateType = "SUSPENDING"
+	SuspendedState        ApplicationStateType = "SUSPENDED"
 )
 
 // ApplicationState tells the current state of the application and an error message in case of failures.
@@ -373,6 +550,46 @@ const (
 	ExecutorUnknownState   ExecutorState = "UNKNOWN"
 )
 
+// ExecutorStatus describes an executor of a run of an application.
+type ExecutorStatus struct {
+	// PodName is the name of the executor pod.
+	PodName string `json:"podName"`
+	// ExecutorID is the ID Spark assigned to the executor.
+	ExecutorID string `json:"executorID,omitempty"`
+	// NodeName is the name of the node the executor pod was scheduled to.
+	NodeName string `json:"nodeName,omitempty"`
+	// State is the state of the executor.
+	State ExecutorState `json:"state"`
+	// StartTime is the time the executor pod was started by the kubelet.
+	// +nullable
+	StartTime metav1.Time `json:"startTime,omitempty"`
+	// EndTime is the time the executor container terminated, or the time the controller found the executor
+	// terminated if the container state is not available.
+	// +nullable
+	EndTime metav1.Time `json:"endTime,omitempty"`
+	// ExitCode is the exit code of the executor container, if it terminated.
+	// +optional
+	ExitCode *int32 `json:"exitCode,omitempty"`
+	// Reason is why the executor container or pod terminated, e.g. OOMKilled or Evicted.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Restarts is the number of times the executor container was restarted.
+	Restarts int32 `json:"restarts,omitempty"`
+}
+
+// RolledUpExecutors counts the terminated executors of a run of an application that are no longer listed
+// individually.
+type RolledUpExecutors struct {
+	// Completed is the number of executors that completed.
+	Completed int32 `json:"completed"`
+	// Failed is the number of executors that failed.
+	Failed int32 `json:"failed"`
+	// LastEndTime is the latest end time of the executors rolled up. Terminated executor pods created before it that
+	// are not listed are assumed to be counted already.
+	// +nullable
+	LastEndTime metav1.Time `json:"lastEndTime,omitempty"`
+}
+
 // SparkApplicationStatus describes the current status of a Spark application.
 type SparkApplicationStatus struct {
 	// SparkApplicationID is set by the spark-distribution(via spark.app.id config) on the driver and executor pods
@@ -389,7 +606,17 @@ type SparkApplicationStatus struct {
 	DriverInfo DriverInfo `json:"driverInfo"`
 	// AppState tells the overall application state.
 	AppState ApplicationState `json:"applicationState,omitempty"`
-	// ExecutorState records the state of executors by executor Pod names.
+	// Executors describes the executors of the current run, in the order they were found. Active executors are always
+	// listed. Terminated executors beyond the executor status limit of the operator are counted in RolledUpExecutors
+	// instead, starting with those that terminated first.
+	// +optional
+	Executors []ExecutorStatus `json:"executors,omitempty"`
+	// RolledUpExecutors counts the terminated executors of the current run that are no longer listed in Executors.
+	// +optional
+	RolledUpExecutors *RolledUpExecutors `json:"rolledUpExecutors,omitempty"`
+	// ExecutorState records the state of the executors listed in Executors by executor Pod names.
+	// Deprecated: use Executors instead. ExecutorState will be removed in the next API version.
+	// +optional
 	ExecutorState map[string]ExecutorState `json:"executorState,omitempty"`
 	// ExecutionAttempts is the total number of attempts to run a submitted application to completion.
 	// Incremented upon each attempted run of the application and reset upon invalidation.
@@ -397,6 +624,164 @@ type SparkApplicationStatus struct {
 	// SubmissionAttempts is the total number of attempts to submit an application to run.
 	// Incremented upon each attempted submission of the application and reset upon invalidation and rerun.
 	SubmissionAttempts int32 `json:"submissionAttempts,omitempty"`
+	// NextRetryTime is the time at which the application is retried after a failed run or submission, according to
+	// its RestartPolicy.
+	// +nullable
+	NextRetryTime metav1.Time `json:"nextRetryTime,omitempty"`
+	// TerminationReason is why the driver of the last run failed, if the controller could tell.
+	TerminationReason TerminationReason `json:"terminationReason,omitempty"`
+	// DriverExitCode is the exit code of the driver container of the last run, if it failed.
+	DriverExitCode *int32 `json:"driverExitCode,omitempty"`
+	// RestartRuleRetries counts the retries allowed by restart rules with their own retry budget, by rule name.
+	// It is reset upon invalidation.
+	RestartRuleRetries map[string]int32 `json:"restartRuleRetries,omitempty"`
+	// ExecutorOOMKills is the number of executors of the current run that were OOMKilled.
+	ExecutorOOMKills int32 `json:"executorOOMKills,omitempty"`
+	// ExecutorFailureTimes records when executors of the current run failed within the failure window, if the
+	// executors have a MaxFailures.
+	ExecutorFailureTimes []metav1.Time `json:"executorFailureTimes,omitempty"`
+	// EffectiveMemory records the memory of the driver and the executors used for the most recent attempts to run
+	// the application, if the RestartPolicy escalates memory. Only the last 10 attempts are kept. It is reset upon
+	// invalidation.
+	EffectiveMemory []AttemptMemory `json:"effectiveMemory,omitempty"`
+	// Conditions are the latest observations of the state of the application.
+	// +listType=map
+	// +listMapKey=type
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	// Attempts records how the most recent attempts to run the application ended, oldest first. Only the last 10
+	// attempts are kept.
+	// +optional
+	Attempts []AttemptStatus `json:"attempts,omitempty"`
+	// Resources lists the resources the operator created for the current run of the application, which are deleted
+	// when the application is rerun, expires or is deleted.
+	// +optional
+	Resources []ResourceReference `json:"resources,omitempty"`
+	// Progress summarizes the jobs, stages, tasks and executors of the current run as reported by the REST API of the
+	// driver, if the operator polls it.
+	// +optional
+	Progress *ApplicationProgress `json:"progress,omitempty"`
+}
+
+// ApplicationProgress summarizes the progress of a run of an application, as reported by the REST API of its driver.
+type ApplicationProgress struct {
+	// LastUpdateTime is the time the progress was last read from the driver.
+	// +nullable
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+	// LastProgressTime is the time the driver was first seen to have completed more jobs, stages or tasks than before.
+	// +nullable
+	LastProgressTime metav1.Time `json:"lastProgressTime,omitempty"`
+	// ActiveJobs is the number of running jobs.
+	ActiveJobs int32 `json:"activeJobs"`
+	// CompletedJobs is the number of jobs that succeeded.
+	CompletedJobs int32 `json:"completedJobs"`
+	// FailedJobs is the number of jobs that failed.
+	FailedJobs int32 `json:"failedJobs"`
+	// ActiveStages is the number of running stages.
+	ActiveStages int32 `json:"activeStages"`
+	// CompletedStages is the number of stages that completed or were skipped.
+	CompletedStages int32 `json:"completedStages"`
+	// FailedStages is the number of stages that failed.
+	FailedStages int32 `json:"failedStages"`
+	// TotalStages is the number of stages known to the driver, including those pending.
+	TotalStages int32 `json:"totalStages"`
+	// ActiveTasks is the number of running tasks.
+	ActiveTasks int32 `json:"activeTasks"`
+	// CompletedTasks is the number of tasks that completed.
+	CompletedTasks int32 `json:"completedTasks"`
+	// FailedTasks is the number of task attempts that failed.
+	FailedTasks int32 `json:"failedTasks"`
+	// TotalTasks is the number of tasks of the stages that were not skipped.
+	TotalTasks int32 `json:"totalTasks"`
+	// ShuffleReadBytes is the number of bytes read by shuffles.
+	ShuffleReadBytes int64 `json:"shuffleReadBytes"`
+	// ShuffleWriteBytes is the number of bytes written by shuffles.
+	ShuffleWriteBytes int64 `json:"shuffleWriteBytes"`
+	// ActiveExecutors is the number of executors registered with the driver.
+	ActiveExecutors int32 `json:"activeExecutors"`
+}
+
+// ResourceReference identifies a resource the operator created for an application.
+type ResourceReference struct {
+	// APIVersion is the API version of the resource.
+	APIVersion string `json:"apiVersion"`
+	// Kind is the kind of the resource.
+	Kind string `json:"kind"`
+	// Name is the name of the resource, which is in the namespace of the application.
+	Name string `json:"name"`
+	// UID is the UID of the resource, if known.
+	UID types.UID `json:"uid,omitempty"`
+}
+
+// AttemptStatus records how an attempt to run an application ended.
+type AttemptStatus struct {
+	// ExecutionAttempt is the number of the attempt, counted since the application was last invalidated.
+	ExecutionAttempt int32 `json:"executionAttempt"`
+	// SubmissionID is the ID of the submission of the attempt.
+	SubmissionID string `json:"submissionID,omitempty"`
+	// SparkApplicationID is the ID Spark gave the attempt.
+	SparkApplicationID string `json:"sparkApplicationId,omitempty"`
+	// StartTime is the time the attempt was submitted.
+	// +nullable
+	StartTime metav1.Time `json:"startTime,omitempty"`
+	// EndTime is the time the driver of the attempt terminated, or the attempt was invalidated.
+	// +nullable
+	EndTime metav1.Time `json:"endTime,omitempty"`
+	// State is how the attempt ended, one of COMPLETED, FAILED, INVALIDATING and SUSPENDED.
+	State ApplicationStateType `json:"state"`
+	// ErrorMessage is the error the attempt failed with.
+	ErrorMessage string `json:"errorMessage,omitempty"`
+	// DriverExitCode is the exit code of the driver container, if the attempt failed.
+	DriverExitCode *int32 `json:"driverExitCode,omitempty"`
+	// TerminationReason is why the driver of the attempt failed, if the controller could tell.
+	TerminationReason TerminationReason `json:"terminationReason,omitempty"`
+	// ExecutorFailures is the number of executors of the attempt that failed.
+	ExecutorFailures int32 `json:"executorFailures,omitempty"`
+}
+
+// Types of the conditions of a SparkApplication.
+const (
+	// SparkApplicationSubmitted means the current run of the application was submitted.
+	SparkApplicationSubmitted = "Submitted"
+	// SparkApplicationDriverScheduled means the driver pod of the current run was scheduled to a node.
+	SparkApplicationDriverScheduled = "DriverScheduled"
+	// SparkApplicationDriverReady means the driver pod of the current run is ready.
+	SparkApplicationDriverReady = "DriverReady"
+	// SparkApplicationExecutorsReady means the requested executors of the current run are running.
+	SparkApplicationExecutorsReady = "ExecutorsReady"
+	// SparkApplicationSucceeded means the application completed successfully and will not be rerun.
+	SparkApplicationSucceeded = "Succeeded"
+	// SparkApplicationFailed means the application failed and will not be retried.
+	SparkApplicationFailed = "Failed"
+	// SparkApplicationRetryScheduled means the application failed and a retry is pending.
+	SparkApplicationRetryScheduled = "RetryScheduled"
+	// SparkApplicationResourcesCleanedUp means the resources of the previous run were deleted before a rerun.
+	SparkApplicationResourcesCleanedUp = "ResourcesCleanedUp"
+	// SparkApplicationSuspended means the application is suspended.
+	SparkApplicationSuspended = "Suspended"
+	// SparkApplicationProgressing means the running application completed jobs, stages or tasks within its progress
+	// deadline.
+	SparkApplicationProgressing = "Progressing"
+)
+
+// AttemptMemory is the memory of the driver and the executors used for an attempt to run an application.
+type AttemptMemory struct {
+	// ExecutionAttempt is the number of the attempt.
+	ExecutionAttempt int32 `json:"executionAttempt"`
+	// DriverMemory is the memory of the driver.
+	// +optional
+	DriverMemory *string `json:"driverMemory,omitempty"`
+	// DriverMemoryOverhead is the memory overhead of the driver.
+	// +optional
+	DriverMemoryOverhead *string `json:"driverMemoryOverhead,omitempty"`
+	// ExecutorMemory is the memory of the executors.
+	// +optional
+	ExecutorMemory *string `json:"executorMemory,omitempty"`
+	// ExecutorMemoryOverhead is the memory overhead of the executors.
+	// +optional
+	ExecutorMemoryOverhead *string `json:"executorMemoryOverhead,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -504,6 +889,10 @@ type SparkPodSpec struct {
 	// Sidecars is a list of sidecar containers that run along side the main Spark container.
 	// +optional
 	Sidecars []apiv1.Container `json:"sidecars,omitempty"`
+	// SidecarTermination configures how the operator stops the sidecars of the pod once the main Spark container has
+	// terminated, which would otherwise keep the pod running.
+	// +optional
+	SidecarTermination *SidecarTermination `json:"sidecarTermination,omitempty"`
 	// InitContainers is a list of init-containers that run to completion before the main Spark container.
 	// +optional
 	InitContainers []apiv1.Container `json:"initContainers,omitempty"`
@@ -531,6 +920,19 @@ type SparkPodSpec struct {
 	ShareProcessNamespace *bool `json:"shareProcessNamespace,omitempty"`
 }
 
+// SidecarTermination configures how the operator stops the sidecars of a driver or executor pod, e.g. an injected
+// service mesh proxy, after the main Spark container has terminated.
+type SidecarTermination struct {
+	// QuitPort is the port of an HTTP endpoint of a sidecar, on the IP of the pod, to which the operator sends a POST
+	// request to make the sidecar exit.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	QuitPort int32 `json:"quitPort"`
+	// QuitPath is the path of the HTTP endpoint. Defaults to "/quitquitquit".
+	// +optional
+	QuitPath *string `json:"quitPath,omitempty"`
+}
+
 // DriverSpec is specification of the driver.
 type DriverSpec struct {
 	SparkPodSpec `json:",inline"`
@@ -587,6 +989,16 @@ type ExecutorSpec struct {
 	// Ports settings for the pods, following the Kubernetes specifications.
 	// +optional
 	Ports []Port `json:"ports,omitempty"`
+	// MaxFailures is the number of executors of a run that may fail, either an absolute number or a percentage of
+	// the requested executors, e.g. "10%". A run with more failed executors is killed and fails with reason
+	// ExecutorFailureThresholdExceeded.
+	// +optional
+	MaxFailures *intstr.IntOrString `json:"maxFailures,omitempty"`
+	// FailureWindowSeconds is the duration in seconds within which executor failures count towards MaxFailures.
+	// Failures count for the whole run if unset.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	FailureWindowSeconds *int64 `json:"failureWindowSeconds,omitempty"`
 }
 
 // NamePath is a pair of a name and a path to which the named objects should be mounted to.
@@ -25,9 +25,29 @@ package v1beta2
 import (
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	intstr "k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationProgress) DeepCopyInto(out *ApplicationProgress) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	in.LastProgressTime.DeepCopyInto(&out.LastProgressTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationProgress.
+func (in *ApplicationProgress) DeepCopy() *ApplicationProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApplicationState) DeepCopyInto(out *ApplicationState) {
 	*out = *in
@@ -44,6 +64,65 @@ func (in *ApplicationState) DeepCopy() *ApplicationState {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AttemptMemory) DeepCopyInto(out *AttemptMemory) {
+	*out = *in
+	if in.DriverMemory != nil {
+		in, out := &in.DriverMemory, &out.DriverMemory
+		*out = new(string)
+		**out = **in
+	}
+	if in.DriverMemoryOverhead != nil {
+		in, out := &in.DriverMemoryOverhead, &out.DriverMemoryOverhead
+		*out = new(string)
+		**out = **in
+	}
+	if in.ExecutorMemory != nil {
+		in, out := &in.ExecutorMemory, &out.ExecutorMemory
+		*out = new(string)
+		**out = **in
+	}
+	if in.ExecutorMemoryOverhead != nil {
+		in, out := &in.ExecutorMemoryOverhead, &out.ExecutorMemoryOverhead
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AttemptMemory.
+func (in *AttemptMemory) DeepCopy() *AttemptMemory {
+	if in == nil {
+		return nil
+	}
+	out := new(AttemptMemory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AttemptStatus) DeepCopyInto(out *AttemptStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.EndTime.DeepCopyInto(&out.EndTime)
+	if in.DriverExitCode != nil {
+		in, out := &in.DriverExitCode, &out.DriverExitCode
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AttemptStatus.
+func (in *AttemptStatus) DeepCopy() *AttemptStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AttemptStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BatchSchedulerConfiguration) DeepCopyInto(out *BatchSchedulerConfiguration) {
 	*out = *in
@@ -258,6 +337,16 @@ func (in *ExecutorSpec) DeepCopyInto(out *ExecutorSpec) {
 		*out = make([]Port, len(*in))
 		copy(*out, *in)
 	}
+	if in.MaxFailures != nil {
+		in, out := &in.MaxFailures, &out.MaxFailures
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.FailureWindowSeconds != nil {
+		in, out := &in.FailureWindowSeconds, &out.FailureWindowSeconds
+		*out = new(int64)
+		**out = **in
+	}
 	return
 }
 
@@ -271,6 +360,50 @@ func (in *ExecutorSpec) DeepCopy() *ExecutorSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecutorStatus) DeepCopyInto(out *ExecutorStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.EndTime.DeepCopyInto(&out.EndTime)
+	if in.ExitCode != nil {
+		in, out := &in.ExitCode, &out.ExitCode
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecutorStatus.
+func (in *ExecutorStatus) DeepCopy() *ExecutorStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ExecutorStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExitCodeRange) DeepCopyInto(out *ExitCodeRange) {
+	*out = *in
+	if in.Max != nil {
+		in, out := &in.Max, &out.Max
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExitCodeRange.
+func (in *ExitCodeRange) DeepCopy() *ExitCodeRange {
+	if in == nil {
+		return nil
+	}
+	out := new(ExitCodeRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GPUSpec) DeepCopyInto(out *GPUSpec) {
 	*out = *in
@@ -287,6 +420,27 @@ func (in *GPUSpec) DeepCopy() *GPUSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemoryEscalation) DeepCopyInto(out *MemoryEscalation) {
+	*out = *in
+	if in.Factor != nil {
+		in, out := &in.Factor, &out.Factor
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemoryEscalation.
+func (in *MemoryEscalation) DeepCopy() *MemoryEscalation {
+	if in == nil {
+		return nil
+	}
+	out := new(MemoryEscalation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MonitoringSpec) DeepCopyInto(out *MonitoringSpec) {
 	*out = *in
@@ -402,6 +556,22 @@ func (in *PrometheusSpec) DeepCopy() *PrometheusSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceReference) DeepCopyInto(out *ResourceReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceReference.
+func (in *ResourceReference) DeepCopy() *ResourceReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RestartPolicy) DeepCopyInto(out *RestartPolicy) {
 	*out = *in
@@ -425,6 +595,33 @@ func (in *RestartPolicy) DeepCopyInto(out *RestartPolicy) {
 		*out = new(int64)
 		**out = **in
 	}
+	if in.BackoffMultiplier != nil {
+		in, out := &in.BackoffMultiplier, &out.BackoffMultiplier
+		*out = new(string)
+		**out = **in
+	}
+	if in.MaxRetryInterval != nil {
+		in, out := &in.MaxRetryInterval, &out.MaxRetryInterval
+		*out = new(int64)
+		**out = **in
+	}
+	if in.BackoffJitterPercent != nil {
+		in, out := &in.BackoffJitterPercent, &out.BackoffJitterPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]RestartRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MemoryEscalation != nil {
+		in, out := &in.MemoryEscalation, &out.MemoryEscalation
+		*out = new(MemoryEscalation)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -438,6 +635,56 @@ func (in *RestartPolicy) DeepCopy() *RestartPolicy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestartRule) DeepCopyInto(out *RestartRule) {
+	*out = *in
+	if in.Reasons != nil {
+		in, out := &in.Reasons, &out.Reasons
+		*out = make([]TerminationReason, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExitCodes != nil {
+		in, out := &in.ExitCodes, &out.ExitCodes
+		*out = make([]ExitCodeRange, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Retries != nil {
+		in, out := &in.Retries, &out.Retries
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestartRule.
+func (in *RestartRule) DeepCopy() *RestartRule {
+	if in == nil {
+		return nil
+	}
+	out := new(RestartRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolledUpExecutors) DeepCopyInto(out *RolledUpExecutors) {
+	*out = *in
+	in.LastEndTime.DeepCopyInto(&out.LastEndTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolledUpExecutors.
+func (in *RolledUpExecutors) DeepCopy() *RolledUpExecutors {
+	if in == nil {
+		return nil
+	}
+	out := new(RolledUpExecutors)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScheduledSparkApplication) DeepCopyInto(out *ScheduledSparkApplication) {
 	*out = *in
@@ -575,6 +822,27 @@ func (in *SecretInfo) DeepCopy() *SecretInfo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SidecarTermination) DeepCopyInto(out *SidecarTermination) {
+	*out = *in
+	if in.QuitPath != nil {
+		in, out := &in.QuitPath, &out.QuitPath
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SidecarTermination.
+func (in *SidecarTermination) DeepCopy() *SidecarTermination {
+	if in == nil {
+		return nil
+	}
+	out := new(SidecarTermination)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SparkApplication) DeepCopyInto(out *SparkApplication) {
 	*out = *in
@@ -766,6 +1034,26 @@ func (in *SparkApplicationSpec) DeepCopyInto(out *SparkApplicationSpec) {
 		*out = new(DynamicAllocation)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ActiveDeadlineSeconds != nil {
+		in, out := &in.ActiveDeadlineSeconds, &out.ActiveDeadlineSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.PendingTimeoutSeconds != nil {
+		in, out := &in.PendingTimeoutSeconds, &out.PendingTimeoutSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ProgressDeadlineSeconds != nil {
+		in, out := &in.ProgressDeadlineSeconds, &out.ProgressDeadlineSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Suspend != nil {
+		in, out := &in.Suspend, &out.Suspend
+		*out = new(bool)
+		**out = **in
+	}
 	return
 }
 
@@ -786,6 +1074,18 @@ func (in *SparkApplicationStatus) DeepCopyInto(out *SparkApplicationStatus) {
 	in.TerminationTime.DeepCopyInto(&out.TerminationTime)
 	out.DriverInfo = in.DriverInfo
 	out.AppState = in.AppState
+	if in.Executors != nil {
+		in, out := &in.Executors, &out.Executors
+		*out = make([]ExecutorStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RolledUpExecutors != nil {
+		in, out := &in.RolledUpExecutors, &out.RolledUpExecutors
+		*out = new(RolledUpExecutors)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.ExecutorState != nil {
 		in, out := &in.ExecutorState, &out.ExecutorState
 		*out = make(map[string]ExecutorState, len(*in))
@@ -793,6 +1093,57 @@ func (in *SparkApplicationStatus) DeepCopyInto(out *SparkApplicationStatus) {
 			(*out)[key] = val
 		}
 	}
+	in.NextRetryTime.DeepCopyInto(&out.NextRetryTime)
+	if in.DriverExitCode != nil {
+		in, out := &in.DriverExitCode, &out.DriverExitCode
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RestartRuleRetries != nil {
+		in, out := &in.RestartRuleRetries, &out.RestartRuleRetries
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ExecutorFailureTimes != nil {
+		in, out := &in.ExecutorFailureTimes, &out.ExecutorFailureTimes
+		*out = make([]metav1.Time, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EffectiveMemory != nil {
+		in, out := &in.EffectiveMemory, &out.EffectiveMemory
+		*out = make([]AttemptMemory, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Attempts != nil {
+		in, out := &in.Attempts, &out.Attempts
+		*out = make([]AttemptStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]ResourceReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Progress != nil {
+		in, out := &in.Progress, &out.Progress
+		*out = new(ApplicationProgress)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -932,6 +1283,11 @@ func (in *SparkPodSpec) DeepCopyInto(out *SparkPodSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.SidecarTermination != nil {
+		in, out := &in.SidecarTermination, &out.SidecarTermination
+		*out = new(SidecarTermination)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.InitContainers != nil {
 		in, out := &in.InitContainers, &out.InitContainers
 		*out = make([]v1.Container, len(*in))
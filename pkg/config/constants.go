@@ -48,12 +48,28 @@ const (
 	SparkApplicationSelectorLabel = "spark-app-selector"
 	// SparkRoleLabel is the driver/executor label set by the operator/spark-distribution on the driver/executors Pods.
 	SparkRoleLabel = "spark-role"
+	// SparkExecutorIDLabel is the label set by the spark-distribution on executor Pods to the ID of the executor.
+	SparkExecutorIDLabel = "spark-exec-id"
 	// SparkDriverRole is the value of the spark-role label for the driver.
 	SparkDriverRole = "driver"
 	// SparkExecutorRole is the value of the spark-role label for the executors.
 	SparkExecutorRole = "executor"
 	// SubmissionIDLabel is the label that records the submission ID of the current run of an application.
 	SubmissionIDLabel = LabelAnnotationPrefix + "submission-id"
+	// GatewayApplicationNameLabel is the label that records the user-facing application name of a SparkApplication
+	// submitted through the API gateway.
+	GatewayApplicationNameLabel = LabelAnnotationPrefix + "gateway-application-name"
+	// GatewayKilledAnnotation is the annotation put on a SparkApplication killed through the API gateway.
+	GatewayKilledAnnotation = LabelAnnotationPrefix + "gateway-killed"
+	// SubmitterAnnotation is the annotation that selects how a SparkApplication is submitted, either spark-submit
+	// or native. It overrides the submitter the operator is configured with.
+	SubmitterAnnotation = LabelAnnotationPrefix + "submitter"
+	// SparkApplicationFinalizer is the finalizer the controller puts on a SparkApplication so that it can delete the
+	// resources created for the application before the application goes away.
+	SparkApplicationFinalizer = LabelAnnotationPrefix + "cleanup"
+	// ForceDeleteAnnotation is the annotation that, if set to "true" on a SparkApplication being deleted, makes the
+	// controller remove its finalizer without waiting for the resources of the application to be deleted.
+	ForceDeleteAnnotation = LabelAnnotationPrefix + "force-delete"
 )
 
 const (
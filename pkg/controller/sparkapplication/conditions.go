@@ -0,0 +1,177 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// maxConditionMessageLength is the maximum length of condition messages allowed by the CRD.
+const maxConditionMessageLength = 32768
+
+const truncatedMessageSuffix = "... (truncated)"
+
+// setCondition sets a condition of the application, updating its last transition time if its status changed.
+// Messages longer than the CRD allows are truncated.
+func setCondition(app *v1beta2.SparkApplication, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&app.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            truncateConditionMessage(message),
+		ObservedGeneration: app.Generation,
+	})
+}
+
+// truncateConditionMessage truncates a message to at most maxConditionMessageLength bytes, without splitting a
+// character.
+func truncateConditionMessage(message string) string {
+	if len(message) <= maxConditionMessageLength {
+		return message
+	}
+	end := maxConditionMessageLength - len(truncatedMessageSuffix)
+	for end > 0 && !utf8.RuneStart(message[end]) {
+		end--
+	}
+	return message[:end] + truncatedMessageSuffix
+}
+
+// resetCondition sets a condition of the application to false if the application has the condition.
+func resetCondition(app *v1beta2.SparkApplication, conditionType string, reason, message string) {
+	if meta.FindStatusCondition(app.Status.Conditions, conditionType) != nil {
+		setCondition(app, conditionType, metav1.ConditionFalse, reason, message)
+	}
+}
+
+// updateStateConditions updates the conditions following from the state of the application.
+func updateStateConditions(app *v1beta2.SparkApplication) {
+	switch app.Status.AppState.State {
+	case v1beta2.SubmittedState:
+		setCondition(app, v1beta2.SparkApplicationSubmitted, metav1.ConditionTrue, "SubmissionSucceeded",
+			fmt.Sprintf("submission %s created driver pod %s", app.Status.SubmissionID, app.Status.DriverInfo.PodName))
+		resetCondition(app, v1beta2.SparkApplicationSucceeded, "Rerun", "the application is running again")
+		resetCondition(app, v1beta2.SparkApplicationFailed, "Rerun", "the application is running again")
+		resetCondition(app, v1beta2.SparkApplicationRetryScheduled, "Resubmitted", "the application was resubmitted")
+		resetCondition(app, v1beta2.SparkApplicationSuspended, "Resumed", "the application was resumed")
+	case v1beta2.FailedSubmissionState:
+		setCondition(app, v1beta2.SparkApplicationSubmitted, metav1.ConditionFalse, "SubmissionFailed", app.Status.AppState.ErrorMessage)
+		updateRetryScheduledCondition(app)
+	case v1beta2.FailingState:
+		updateRetryScheduledCondition(app)
+	case v1beta2.SuspendedState:
+		setCondition(app, v1beta2.SparkApplicationSuspended, metav1.ConditionTrue, "Suspended", "the application is suspended")
+	case v1beta2.CompletedState:
+		setCondition(app, v1beta2.SparkApplicationSucceeded, metav1.ConditionTrue, "Completed", "the application completed successfully")
+		resetCondition(app, v1beta2.SparkApplicationRetryScheduled, "Completed", "the application completed successfully")
+	case v1beta2.FailedState:
+		reason := "DriverFailed"
+		if app.Status.TerminationReason != "" {
+			reason = string(app.Status.TerminationReason)
+		} else if meta.IsStatusConditionFalse(app.Status.Conditions, v1beta2.SparkApplicationSubmitted) {
+			reason = "SubmissionFailed"
+		} else if meta.FindStatusCondition(app.Status.Conditions, v1beta2.SparkApplicationSubmitted) == nil {
+			reason = "ValidationFailed"
+		}
+		setCondition(app, v1beta2.SparkApplicationFailed, metav1.ConditionTrue, reason, app.Status.AppState.ErrorMessage)
+		resetCondition(app, v1beta2.SparkApplicationRetryScheduled, "RetriesExhausted", "the application will not be retried")
+	}
+}
+
+func updateRetryScheduledCondition(app *v1beta2.SparkApplication) {
+	if app.Status.NextRetryTime.IsZero() {
+		return
+	}
+	setCondition(app, v1beta2.SparkApplicationRetryScheduled, metav1.ConditionTrue, "RetryScheduled",
+		fmt.Sprintf("the application will be retried at %s", app.Status.NextRetryTime.UTC().Format(time.RFC3339)))
+}
+
+// updateDriverConditions updates the conditions following from the driver pod of the application.
+func updateDriverConditions(app *v1beta2.SparkApplication, driverPod *apiv1.Pod) {
+	for _, condition := range driverPod.Status.Conditions {
+		switch condition.Type {
+		case apiv1.PodScheduled:
+			if condition.Status == apiv1.ConditionTrue {
+				setCondition(app, v1beta2.SparkApplicationDriverScheduled, metav1.ConditionTrue, "Scheduled",
+					fmt.Sprintf("driver pod %s was scheduled to node %s", driverPod.Name, driverPod.Spec.NodeName))
+			} else {
+				setCondition(app, v1beta2.SparkApplicationDriverScheduled, metav1.ConditionFalse,
+					conditionReason(condition.Reason, "Pending"), condition.Message)
+			}
+		case apiv1.PodReady:
+			if condition.Status == apiv1.ConditionTrue {
+				setCondition(app, v1beta2.SparkApplicationDriverReady, metav1.ConditionTrue, "Ready",
+					fmt.Sprintf("driver pod %s is ready", driverPod.Name))
+			} else {
+				setCondition(app, v1beta2.SparkApplicationDriverReady, metav1.ConditionFalse,
+					conditionReason(condition.Reason, "NotReady"), condition.Message)
+			}
+		}
+	}
+}
+
+// updateExecutorsReadyCondition updates whether the requested executors of the application are running.
+func updateExecutorsReadyCondition(app *v1beta2.SparkApplication) {
+	if !isDriverRunning(app) {
+		resetCondition(app, v1beta2.SparkApplicationExecutorsReady, "DriverNotRunning", "the driver is not running")
+		return
+	}
+	running := countExecutors(app, v1beta2.ExecutorRunningState)
+	requested := requestedExecutors(app)
+	message := fmt.Sprintf("%d of %d requested executors are running", running, requested)
+	if running >= requested {
+		setCondition(app, v1beta2.SparkApplicationExecutorsReady, metav1.ConditionTrue, "ExecutorsRunning", message)
+	} else {
+		setCondition(app, v1beta2.SparkApplicationExecutorsReady, metav1.ConditionFalse, "ExecutorsPending", message)
+	}
+}
+
+// requestedExecutors returns the number of executors the application asks for when it starts.
+func requestedExecutors(app *v1beta2.SparkApplication) int32 {
+	if dynamicAllocation := app.Spec.DynamicAllocation; dynamicAllocation != nil && dynamicAllocation.Enabled {
+		if dynamicAllocation.InitialExecutors != nil {
+			return *dynamicAllocation.InitialExecutors
+		}
+		if dynamicAllocation.MinExecutors != nil {
+			return *dynamicAllocation.MinExecutors
+		}
+		return 0
+	}
+	if app.Spec.Executor.Instances != nil {
+		return *app.Spec.Executor.Instances
+	}
+	if instances, err := strconv.ParseInt(app.Spec.SparkConf["spark.executor.instances"], 10, 32); err == nil {
+		return int32(instances)
+	}
+	return 1
+}
+
+// conditionReason returns the reason of a pod condition, which can be empty, or else the given default.
+func conditionReason(reason, defaultReason string) string {
+	if reason == "" {
+		return defaultReason
+	}
+	return reason
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func assertCondition(t *testing.T, app *v1beta2.SparkApplication, conditionType string, status metav1.ConditionStatus, reason string) {
+	condition := meta.FindStatusCondition(app.Status.Conditions, conditionType)
+	if assert.NotNil(t, condition, conditionType) {
+		assert.Equal(t, status, condition.Status, conditionType)
+		assert.Equal(t, reason, condition.Reason, conditionType)
+	}
+}
+
+func TestUpdateStateConditions(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", Generation: 2},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{State: v1beta2.FailedSubmissionState, ErrorMessage: "spark-submit failed"},
+		},
+	}
+	updateStateConditions(app)
+	assertCondition(t, app, v1beta2.SparkApplicationSubmitted, metav1.ConditionFalse, "SubmissionFailed")
+	assert.Nil(t, meta.FindStatusCondition(app.Status.Conditions, v1beta2.SparkApplicationRetryScheduled))
+
+	app.Status.NextRetryTime = metav1.NewTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	updateStateConditions(app)
+	assertCondition(t, app, v1beta2.SparkApplicationRetryScheduled, metav1.ConditionTrue, "RetryScheduled")
+	assert.Equal(t, "the application will be retried at 2020-01-01T00:00:00Z",
+		meta.FindStatusCondition(app.Status.Conditions, v1beta2.SparkApplicationRetryScheduled).Message)
+	assert.Equal(t, int64(2), app.Status.Conditions[0].ObservedGeneration)
+
+	app.Status = v1beta2.SparkApplicationStatus{
+		AppState:     v1beta2.ApplicationState{State: v1beta2.SubmittedState},
+		SubmissionID: "1",
+		DriverInfo:   v1beta2.DriverInfo{PodName: "foo-driver"},
+		Conditions:   app.Status.Conditions,
+	}
+	updateStateConditions(app)
+	assertCondition(t, app, v1beta2.SparkApplicationSubmitted, metav1.ConditionTrue, "SubmissionSucceeded")
+	assertCondition(t, app, v1beta2.SparkApplicationRetryScheduled, metav1.ConditionFalse, "Resubmitted")
+	assert.Nil(t, meta.FindStatusCondition(app.Status.Conditions, v1beta2.SparkApplicationFailed))
+
+	app.Status.AppState.State = v1beta2.FailedState
+	app.Status.TerminationReason = v1beta2.OOMKilledReason
+	updateStateConditions(app)
+	assertCondition(t, app, v1beta2.SparkApplicationFailed, metav1.ConditionTrue, "OOMKilled")
+	assertCondition(t, app, v1beta2.SparkApplicationRetryScheduled, metav1.ConditionFalse, "RetriesExhausted")
+
+	app.Status.AppState.State = v1beta2.SubmittedState
+	updateStateConditions(app)
+	app.Status.AppState.State = v1beta2.CompletedState
+	updateStateConditions(app)
+	assertCondition(t, app, v1beta2.SparkApplicationSucceeded, metav1.ConditionTrue, "Completed")
+	assertCondition(t, app, v1beta2.SparkApplicationFailed, metav1.ConditionFalse, "Rerun")
+
+	app = &v1beta2.SparkApplication{Status: v1beta2.SparkApplicationStatus{
+		AppState: v1beta2.ApplicationState{State: v1beta2.FailedState, ErrorMessage: "invalid spec"},
+	}}
+	updateStateConditions(app)
+	assertCondition(t, app, v1beta2.SparkApplicationFailed, metav1.ConditionTrue, "ValidationFailed")
+}
+
+func TestSetConditionTruncatesMessage(t *testing.T) {
+	app := &v1beta2.SparkApplication{}
+	// The last character before the cut is two bytes long, and is not split.
+	message := strings.Repeat("a", maxConditionMessageLength-len(truncatedMessageSuffix)-1) + "é" + strings.Repeat("b", 100)
+	setCondition(app, v1beta2.SparkApplicationSubmitted, metav1.ConditionFalse, "SubmissionFailed", message)
+	truncated := app.Status.Conditions[0].Message
+	assert.True(t, len(truncated) <= maxConditionMessageLength)
+	assert.True(t, utf8.ValidString(truncated))
+	assert.True(t, strings.HasSuffix(truncated, "a"+truncatedMessageSuffix))
+
+	setCondition(app, v1beta2.SparkApplicationSubmitted, metav1.ConditionFalse, "SubmissionFailed", "spark-submit failed")
+	assert.Equal(t, "spark-submit failed", app.Status.Conditions[0].Message)
+}
+
+func TestUpdateExecutorsReadyCondition(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		Spec: v1beta2.SparkApplicationSpec{Executor: v1beta2.ExecutorSpec{Instances: int32ptr(2)}},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{State: v1beta2.RunningState},
+			Executors: []v1beta2.ExecutorStatus{
+				{PodName: "exec-1", State: v1beta2.ExecutorRunningState},
+				{PodName: "exec-2", State: v1beta2.ExecutorPendingState},
+			},
+		},
+	}
+	updateExecutorsReadyCondition(app)
+	assertCondition(t, app, v1beta2.SparkApplicationExecutorsReady, metav1.ConditionFalse, "ExecutorsPending")
+
+	app.Status.Executors[1].State = v1beta2.ExecutorRunningState
+	updateExecutorsReadyCondition(app)
+	assertCondition(t, app, v1beta2.SparkApplicationExecutorsReady, metav1.ConditionTrue, "ExecutorsRunning")
+	assert.Equal(t, "2 of 2 requested executors are running", app.Status.Conditions[0].Message)
+
+	app.Status.AppState.State = v1beta2.CompletedState
+	updateExecutorsReadyCondition(app)
+	assertCondition(t, app, v1beta2.SparkApplicationExecutorsReady, metav1.ConditionFalse, "DriverNotRunning")
+
+	app.Spec.Executor.Instances = nil
+	app.Spec.SparkConf = map[string]string{"spark.executor.instances": "5"}
+	assert.Equal(t, int32(5), requestedExecutors(app))
+	app.Spec.DynamicAllocation = &v1beta2.DynamicAllocation{Enabled: true, MinExecutors: int32ptr(3)}
+	assert.Equal(t, int32(3), requestedExecutors(app))
+}
+
+func TestSyncSparkApplication_DriverConditions(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState:   v1beta2.ApplicationState{State: v1beta2.SubmittedState},
+			DriverInfo: v1beta2.DriverInfo{PodName: "foo-driver"},
+		},
+	}
+	driverPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-driver",
+			Namespace: "default",
+			Labels: map[string]string{
+				config.SparkRoleLabel:    config.SparkDriverRole,
+				config.SparkAppNameLabel: "foo",
+			},
+		},
+		Spec: apiv1.PodSpec{NodeName: "node-1"},
+		Status: apiv1.PodStatus{
+			Phase: apiv1.PodRunning,
+			Conditions: []apiv1.PodCondition{
+				{Type: apiv1.PodScheduled, Status: apiv1.ConditionTrue},
+				{Type: apiv1.PodReady, Status: apiv1.ConditionFalse, Reason: "ContainersNotReady"},
+			},
+		},
+	}
+	ctrl, _ := newFakeController(app, driverPod)
+	_, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.RunningState, updatedApp.Status.AppState.State)
+	assertCondition(t, updatedApp, v1beta2.SparkApplicationDriverScheduled, metav1.ConditionTrue, "Scheduled")
+	assertCondition(t, updatedApp, v1beta2.SparkApplicationDriverReady, metav1.ConditionFalse, "ContainersNotReady")
+	assertCondition(t, updatedApp, v1beta2.SparkApplicationExecutorsReady, metav1.ConditionFalse, "ExecutorsPending")
+}
@@ -19,7 +19,11 @@ package sparkapplication
 import (
 	"context"
 	"fmt"
+	"math"
+	"math/rand"
 	"os/exec"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/golang/glog"
@@ -28,6 +32,7 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -54,10 +59,27 @@ import (
 )
 
 const (
-	sparkExecutorIDLabel      = "spark-exec-id"
 	podAlreadyExistsErrorCode = "code=409"
 	queueTokenRefillRate      = 50
 	queueTokenBucketSize      = 500
+	defaultBackoffMultiplier  = 2
+	// maxRetryInterval bounds exponential backoff so that the next retry time does not overflow.
+	maxRetryInterval = 365 * 24 * time.Hour
+	// podDisruptionTargetCondition is the pod condition set by Kubernetes 1.26 and later when a pod is terminated
+	// because of a disruption, e.g. preemption or a taint of its node.
+	podDisruptionTargetCondition = "DisruptionTarget"
+	// maxAttemptHistory is the number of attempts kept in the status of an application.
+	maxAttemptHistory = 10
+	// createContainerConfigErrorReason is the waiting reason of a container that cannot be created because of its
+	// configuration, e.g. a reference to a missing Secret or ConfigMap.
+	createContainerConfigErrorReason = "CreateContainerConfigError"
+	// errImagePullReason is the waiting reason of a container whose image failed to be pulled, before the kubelet
+	// backs off.
+	errImagePullReason = "ErrImagePull"
+	// driverContainerErrorGracePeriod is how long the driver container may fail to be pulled or created before the
+	// run fails, since pulls often fail transiently, and Secrets and ConfigMaps are often created along with the
+	// application.
+	driverContainerErrorGracePeriod = 2 * time.Minute
 )
 
 var (
@@ -78,6 +100,15 @@ type Controller struct {
 	ingressURLFormat  string
 	batchSchedulerMgr *batchscheduler.SchedulerManager
 	enableUIService   bool
+	submitter         SubmitterType
+	executor          *submissionExecutor
+	sidecarTerminator *sidecarTerminator
+	progressPoller    *progressPoller
+
+	namespace            string
+	orphanSweepInterval  time.Duration
+	progressPollInterval time.Duration
+	executorStatusLimit  int
 }
 
 // NewController creates a new Controller.
@@ -90,7 +121,13 @@ func NewController(
 	namespace string,
 	ingressURLFormat string,
 	batchSchedulerMgr *batchscheduler.SchedulerManager,
-	enableUIService bool) *Controller {
+	enableUIService bool,
+	submitter SubmitterType,
+	submissionWorkers int,
+	submissionTimeout time.Duration,
+	orphanSweepInterval time.Duration,
+	progressPollInterval time.Duration,
+	executorStatusLimit int) *Controller {
 	crdscheme.AddToScheme(scheme.Scheme)
 
 	eventBroadcaster := record.NewBroadcaster()
@@ -100,7 +137,7 @@ func NewController(
 	})
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, apiv1.EventSource{Component: "spark-operator"})
 
-	return newSparkApplicationController(crdClient, kubeClient, crdInformerFactory, podInformerFactory, recorder, metricsConfig, ingressURLFormat, batchSchedulerMgr, enableUIService)
+	return newSparkApplicationController(crdClient, kubeClient, crdInformerFactory, podInformerFactory, recorder, metricsConfig, namespace, ingressURLFormat, batchSchedulerMgr, enableUIService, submitter, submissionWorkers, submissionTimeout, orphanSweepInterval, progressPollInterval, executorStatusLimit)
 }
 
 func newSparkApplicationController(
@@ -110,9 +147,16 @@ func newSparkApplicationController(
 	podInformerFactory informers.SharedInformerFactory,
 	eventRecorder record.EventRecorder,
 	metricsConfig *util.MetricConfig,
+	namespace string,
 	ingressURLFormat string,
 	batchSchedulerMgr *batchscheduler.SchedulerManager,
-	enableUIService bool) *Controller {
+	enableUIService bool,
+	submitter SubmitterType,
+	submissionWorkers int,
+	submissionTimeout time.Duration,
+	orphanSweepInterval time.Duration,
+	progressPollInterval time.Duration,
+	executorStatusLimit int) *Controller {
 	queue := workqueue.NewNamedRateLimitingQueue(&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(queueTokenRefillRate), queueTokenBucketSize)},
 		"spark-application-controller")
 
@@ -124,12 +168,21 @@ func newSparkApplicationController(
 		ingressURLFormat:  ingressURLFormat,
 		batchSchedulerMgr: batchSchedulerMgr,
 		enableUIService:   enableUIService,
+		submitter:         submitter,
+
+		namespace:            namespace,
+		orphanSweepInterval:  orphanSweepInterval,
+		progressPollInterval: progressPollInterval,
+		executorStatusLimit:  executorStatusLimit,
 	}
 
 	if metricsConfig != nil {
 		controller.metrics = newSparkAppMetrics(metricsConfig)
 		controller.metrics.registerMetrics()
 	}
+	controller.executor = newSubmissionExecutor(submissionWorkers, submissionTimeout, controller.metrics)
+	controller.sidecarTerminator = newSidecarTerminator()
+	controller.progressPoller = newProgressPoller(controller.queue.AddRateLimited)
 
 	crdInformer := crdInformerFactory.Sparkoperator().V1beta2().SparkApplications()
 	crdInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -169,6 +222,10 @@ func (c *Controller) Start(workers int, stopCh <-chan struct{}) error {
 		go wait.Until(c.runWorker, time.Second, stopCh)
 	}
 
+	if c.orphanSweepInterval > 0 {
+		go wait.Until(c.sweepOrphanedResources, c.orphanSweepInterval, stopCh)
+	}
+
 	return nil
 }
 
@@ -196,8 +253,9 @@ func (c *Controller) onUpdate(oldObj, newObj interface{}) {
 	}
 
 	// The spec has changed. This is currently best effort as we can potentially miss updates
-	// and end up in an inconsistent state.
-	if !equality.Semantic.DeepEqual(oldApp.Spec, newApp.Spec) {
+	// and end up in an inconsistent state. Suspending or resuming the application is not a change
+	// that requires a rerun, syncSparkApplication handles it.
+	if !equality.Semantic.DeepEqual(specWithoutSuspend(oldApp), specWithoutSuspend(newApp)) {
 		// Force-set the application status to Invalidating which handles clean-up and application re-run.
 		if _, err := c.updateApplicationStatusWithRetries(newApp, func(status *v1beta2.SparkApplicationStatus) {
 			status.AppState.State = v1beta2.InvalidatingState
@@ -235,6 +293,10 @@ func (c *Controller) onDelete(obj interface{}) {
 	}
 
 	if app != nil {
+		if key, err := keyFunc(app); err == nil {
+			c.executor.forget(key)
+			c.progressPoller.forget(key)
+		}
 		c.handleSparkApplicationDeletion(app)
 		c.recorder.Eventf(
 			app,
@@ -323,22 +385,39 @@ func (c *Controller) getAndUpdateDriverState(app *v1beta2.SparkApplication) erro
 	}
 
 	if driverPod == nil {
+		resetCondition(app, v1beta2.SparkApplicationDriverReady, "DriverPodNotFound", "driver pod not found")
 		app.Status.AppState.ErrorMessage = "driver pod not found"
+		app.Status.TerminationReason = v1beta2.PodDeletedReason
 		app.Status.AppState.State = v1beta2.FailingState
 		app.Status.TerminationTime = metav1.Now()
 		return nil
 	}
 
+	recordResource(app, coreAPIVersion, podKind, driverPod.Name, driverPod.UID)
 	app.Status.SparkApplicationID = getSparkApplicationID(driverPod)
+	updateDriverConditions(app, driverPod)
 	driverState := podStatusToDriverState(driverPod.Status)
 
 	if hasDriverTerminated(driverState) {
+		c.sidecarTerminator.terminate(driverPod, app.Spec.Driver.SidecarTermination)
 		if app.Status.TerminationTime.IsZero() {
 			app.Status.TerminationTime = metav1.Now()
 		}
 		if driverState == v1beta2.DriverFailedState {
+			app.Status.TerminationReason = getDriverTerminationReason(driverPod)
 			state := getDriverContainerTerminatedState(driverPod.Status)
-			if state != nil {
+			if waiting := getDriverContainerWaitingError(driverPod.Status); waiting != nil {
+				// The driver container will never start, so the pod is deleted instead of being left pending.
+				app.Status.AppState.ErrorMessage = fmt.Sprintf("driver container failed to start with Reason: %s, Message: %s",
+					waiting.Reason, waiting.Message)
+				c.recorder.Eventf(app, apiv1.EventTypeWarning, "SparkDriver"+string(app.Status.TerminationReason),
+					"Driver %s failed to start: %s: %s", driverPod.Name, waiting.Reason, waiting.Message)
+				if err := c.deleteSparkResources(app); err != nil {
+					return err
+				}
+			} else if state != nil {
+				exitCode := state.ExitCode
+				app.Status.DriverExitCode = &exitCode
 				if state.ExitCode != 0 {
 					app.Status.AppState.ErrorMessage = fmt.Sprintf("driver container failed with ExitCode: %d, Reason: %s", state.ExitCode, state.Reason)
 				}
@@ -346,6 +425,10 @@ func (c *Controller) getAndUpdateDriverState(app *v1beta2.SparkApplication) erro
 				app.Status.AppState.ErrorMessage = "driver container status missing"
 			}
 		}
+	} else if waiting := getDriverContainerWaitingState(driverPod.Status); waiting != nil &&
+		(waiting.Reason == errImagePullReason || waiting.Reason == createContainerConfigErrorReason) {
+		// The status of the pod may not change again, so the run is checked when the grace period ends.
+		c.enqueueAfter(app, driverContainerErrorGraceRemaining(driverPod.Status))
 	}
 
 	newState := driverStateToApplicationState(driverState)
@@ -359,25 +442,48 @@ func (c *Controller) getAndUpdateDriverState(app *v1beta2.SparkApplication) erro
 }
 
 // getAndUpdateExecutorState lists the executor pods of the application
-// and updates the executor state based on the current phase of the pods.
+// and updates the executor status based on the current phase of the pods.
 func (c *Controller) getAndUpdateExecutorState(app *v1beta2.SparkApplication) error {
 	pods, err := c.getExecutorPods(app)
 	if err != nil {
 		return err
 	}
 
-	executorStateMap := make(map[string]v1beta2.ExecutorState)
+	// Executors are rolled up before their states are updated, so that every executor is listed with its final state
+	// at least once.
+	rollUpExecutors(app, c.executorStatusLimit)
+	executors := make(map[string]*v1beta2.ExecutorStatus, len(app.Status.Executors))
+	for i := range app.Status.Executors {
+		executors[app.Status.Executors[i].PodName] = &app.Status.Executors[i]
+	}
+
+	foundPods := make(map[string]bool)
+	var newExecutors []v1beta2.ExecutorStatus
 	var executorApplicationID string
 	for _, pod := range pods {
 		if util.IsExecutorPod(pod) {
-			newState := podPhaseToExecutorState(pod.Status.Phase)
-			oldState, exists := app.Status.ExecutorState[pod.Name]
+			if executorApplicationID == "" {
+				executorApplicationID = getSparkApplicationID(pod)
+			}
+			foundPods[pod.Name] = true
+			newState := podStatusToExecutorState(pod.Status)
+			if isExecutorTerminated(newState) {
+				c.sidecarTerminator.terminate(pod, app.Spec.Executor.SidecarTermination)
+			}
+			executor, exists := executors[pod.Name]
+			if !exists && isExecutorTerminated(newState) && isExecutorRolledUp(app, pod) {
+				continue
+			}
 			// Only record an executor event if the executor state is new or it has changed.
-			if !exists || newState != oldState {
+			if !exists || newState != executor.State {
 				if newState == v1beta2.ExecutorFailedState {
 					execContainerState := getExecutorContainerTerminatedState(pod.Status)
+					recordExecutorFailure(app, execContainerState)
 					if execContainerState != nil {
 						c.recordExecutorEvent(app, newState, pod.Name, execContainerState.ExitCode, execContainerState.Reason)
+						if execContainerState.Reason == oomKilledReason {
+							app.Status.ExecutorOOMKills++
+						}
 					} else {
 						// If we can't find the container state,
 						// we need to set the exitCode and the Reason to unambiguous values.
@@ -387,10 +493,12 @@ func (c *Controller) getAndUpdateExecutorState(app *v1beta2.SparkApplication) er
 					c.recordExecutorEvent(app, newState, pod.Name)
 				}
 			}
-			executorStateMap[pod.Name] = newState
-
-			if executorApplicationID == "" {
-				executorApplicationID = getSparkApplicationID(pod)
+			if exists {
+				updateExecutorStatus(executor, pod, newState)
+			} else {
+				newExecutor := v1beta2.ExecutorStatus{PodName: pod.Name}
+				updateExecutorStatus(&newExecutor, pod, newState)
+				newExecutors = append(newExecutors, newExecutor)
 			}
 		}
 	}
@@ -401,33 +509,34 @@ func (c *Controller) getAndUpdateExecutorState(app *v1beta2.SparkApplication) er
 		app.Status.SparkApplicationID = executorApplicationID
 	}
 
-	if app.Status.ExecutorState == nil {
-		app.Status.ExecutorState = make(map[string]v1beta2.ExecutorState)
-	}
-	for name, execStatus := range executorStateMap {
-		app.Status.ExecutorState[name] = execStatus
-	}
-
 	// Handle missing/deleted executors.
-	for name, oldStatus := range app.Status.ExecutorState {
-		_, exists := executorStateMap[name]
-		if !isExecutorTerminated(oldStatus) && !exists {
+	for i := range app.Status.Executors {
+		executor := &app.Status.Executors[i]
+		if !isExecutorTerminated(executor.State) && !foundPods[executor.PodName] {
 			if !isDriverRunning(app) {
 				// If ApplicationState is COMPLETED, in other words, the driver pod has been completed
 				// successfully. The executor pods terminate and are cleaned up, so we could not found
 				// the executor pod, under this circumstances, we assume the executor pod are completed.
 				if app.Status.AppState.State == v1beta2.CompletedState {
-					app.Status.ExecutorState[name] = v1beta2.ExecutorCompletedState
+					executor.State = v1beta2.ExecutorCompletedState
 				} else {
-					glog.Infof("Executor pod %s not found, assuming it was deleted.", name)
-					app.Status.ExecutorState[name] = v1beta2.ExecutorFailedState
+					glog.Infof("Executor pod %s not found, assuming it was deleted.", executor.PodName)
+					executor.State = v1beta2.ExecutorFailedState
 				}
+				executor.EndTime = metav1.Now()
 			} else {
-				app.Status.ExecutorState[name] = v1beta2.ExecutorUnknownState
+				executor.State = v1beta2.ExecutorUnknownState
 			}
 		}
 	}
 
+	sort.Slice(newExecutors, func(i, j int) bool {
+		return newExecutors[i].PodName < newExecutors[j].PodName
+	})
+	app.Status.Executors = append(app.Status.Executors, newExecutors...)
+	app.Status.ExecutorState = executorStates(app)
+	updateExecutorsReadyCondition(app)
+
 	return nil
 }
 
@@ -455,11 +564,30 @@ func shouldRetry(app *v1beta2.SparkApplication) bool {
 	case v1beta2.SucceedingState:
 		return app.Spec.RestartPolicy.Type == v1beta2.Always
 	case v1beta2.FailingState:
+		if app.Spec.RestartPolicy.Type == v1beta2.Never {
+			return false
+		}
+		if rule, name := matchRestartRule(app); rule != nil {
+			if rule.Action == v1beta2.RestartRuleFail {
+				return false
+			}
+			if rule.Retries != nil {
+				return app.Status.RestartRuleRetries[name] < *rule.Retries
+			}
+		} else if exceededDeadline(app) || app.Status.TerminationReason == v1beta2.ExecutorFailureThresholdExceededReason {
+			// Runs killed for exceeding a deadline or the executor failure threshold are only retried if a restart
+			// rule says so.
+			return false
+		}
 		if app.Spec.RestartPolicy.Type == v1beta2.Always {
 			return true
 		} else if app.Spec.RestartPolicy.Type == v1beta2.OnFailure {
-			// We retry if we haven't hit the retry limit.
-			if app.Spec.RestartPolicy.OnFailureRetries != nil && app.Status.ExecutionAttempts <= *app.Spec.RestartPolicy.OnFailureRetries {
+			// We retry if we haven't hit the retry limit. Retries with the budget of a restart rule don't count.
+			attempts := app.Status.ExecutionAttempts
+			for _, retries := range app.Status.RestartRuleRetries {
+				attempts -= retries
+			}
+			if app.Spec.RestartPolicy.OnFailureRetries != nil && attempts <= *app.Spec.RestartPolicy.OnFailureRetries {
 				return true
 			}
 		}
@@ -476,6 +604,168 @@ func shouldRetry(app *v1beta2.SparkApplication) bool {
 	return false
 }
 
+// matchRestartRule returns the first restart rule matching why the driver of the failed application terminated,
+// and the name its retries are counted under.
+func matchRestartRule(app *v1beta2.SparkApplication) (*v1beta2.RestartRule, string) {
+	for i := range app.Spec.RestartPolicy.Rules {
+		rule := &app.Spec.RestartPolicy.Rules[i]
+		if restartRuleMatches(rule, app.Status.TerminationReason, app.Status.DriverExitCode) {
+			return rule, restartRuleName(rule, i)
+		}
+	}
+	return nil, ""
+}
+
+func restartRuleName(rule *v1beta2.RestartRule, index int) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return fmt.Sprintf("rule-%d", index)
+}
+
+func restartRuleMatches(rule *v1beta2.RestartRule, reason v1beta2.TerminationReason, exitCode *int32) bool {
+	if len(rule.Reasons) > 0 {
+		matched := false
+		for _, r := range rule.Reasons {
+			if r == reason {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(rule.ExitCodes) > 0 {
+		if exitCode == nil {
+			return false
+		}
+		for _, codes := range rule.ExitCodes {
+			max := codes.Min
+			if codes.Max != nil {
+				max = *codes.Max
+			}
+			if *exitCode >= codes.Min && *exitCode <= max {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// countRestartRuleRetry counts a retry of the failed application against the budget of the restart rule allowing
+// it, if the rule has its own budget.
+func countRestartRuleRetry(app *v1beta2.SparkApplication) {
+	rule, name := matchRestartRule(app)
+	if rule == nil || rule.Retries == nil {
+		return
+	}
+	if app.Status.RestartRuleRetries == nil {
+		app.Status.RestartRuleRetries = make(map[string]int32)
+	}
+	app.Status.RestartRuleRetries[name]++
+}
+
+// isSuspended returns whether the application is asked to be suspended.
+func isSuspended(app *v1beta2.SparkApplication) bool {
+	return app.Spec.Suspend != nil && *app.Spec.Suspend
+}
+
+// shouldSuspend returns whether the application has to be suspended. Applications that terminated are left alone,
+// and invalidated applications and applications whose run ended are suspended once they are pending rerun.
+func shouldSuspend(app *v1beta2.SparkApplication) bool {
+	if !isSuspended(app) {
+		return false
+	}
+	switch app.Status.AppState.State {
+	case v1beta2.SuspendingState, v1beta2.SuspendedState, v1beta2.CompletedState, v1beta2.FailedState, v1beta2.InvalidatingState:
+		return false
+	case v1beta2.SucceedingState, v1beta2.FailingState:
+		// The run that ended is recorded and the retry decided first. An application that is retried is suspended
+		// once it is pending rerun.
+		return false
+	case v1beta2.FailedSubmissionState:
+		// An application that will not be retried fails first.
+		return shouldRetry(app)
+	}
+	return true
+}
+
+// specWithoutSuspend returns the spec of the application with Suspend cleared.
+func specWithoutSuspend(app *v1beta2.SparkApplication) v1beta2.SparkApplicationSpec {
+	spec := app.Spec
+	spec.Suspend = nil
+	return spec
+}
+
+// suspendSparkApplication holds the submission of a new application, or stops the current run of the application
+// by deleting its driver. The stopped run does not count as an attempt.
+func (c *Controller) suspendSparkApplication(app *v1beta2.SparkApplication) error {
+	if app.Status.AppState.State == v1beta2.NewState {
+		app.Status.AppState.State = v1beta2.SuspendedState
+		c.recordSparkApplicationEvent(app)
+		return nil
+	}
+	if err := c.deleteSparkResources(app); err != nil {
+		return err
+	}
+	switch app.Status.AppState.State {
+	case v1beta2.SubmittedState, v1beta2.RunningState, v1beta2.UnknownState:
+		recordAttempt(app, v1beta2.SuspendedState)
+		app.Status.ExecutionAttempts--
+	}
+	app.Status.AppState.State = v1beta2.SuspendingState
+	c.recordSparkApplicationEvent(app)
+	return nil
+}
+
+// resumeSparkApplication submits a suspended application again, or for the first time if it was suspended before
+// its submission. An application suspended after a failed submission is retried according to its RestartPolicy, with
+// the submission attempts it has left.
+func (c *Controller) resumeSparkApplication(app *v1beta2.SparkApplication) {
+	c.recorder.Eventf(app, apiv1.EventTypeNormal, "SparkApplicationResumed", "SparkApplication %s was resumed", app.Name)
+	if app.Status.SubmissionAttempts == 0 && app.Status.ExecutionAttempts == 0 {
+		app.Status.AppState.State = v1beta2.NewState
+	} else if meta.IsStatusConditionFalse(app.Status.Conditions, v1beta2.SparkApplicationSubmitted) {
+		app.Status.AppState.State = v1beta2.FailedSubmissionState
+	} else {
+		app.Status.AppState.State = v1beta2.PendingRerunState
+	}
+}
+
+// recordAttempt records how the current attempt to run the application ended in its history of attempts, unless the
+// attempt was never submitted. Recording an attempt again updates its entry.
+func recordAttempt(app *v1beta2.SparkApplication, state v1beta2.ApplicationStateType) {
+	if app.Status.SubmissionID == "" {
+		return
+	}
+	attempt := v1beta2.AttemptStatus{
+		ExecutionAttempt:   app.Status.ExecutionAttempts,
+		SubmissionID:       app.Status.SubmissionID,
+		SparkApplicationID: app.Status.SparkApplicationID,
+		StartTime:          app.Status.LastSubmissionAttemptTime,
+		EndTime:            app.Status.TerminationTime,
+		State:              state,
+		ErrorMessage:       app.Status.AppState.ErrorMessage,
+		DriverExitCode:     app.Status.DriverExitCode,
+		TerminationReason:  app.Status.TerminationReason,
+	}
+	if attempt.EndTime.IsZero() {
+		attempt.EndTime = metav1.Now()
+	}
+	attempt.ExecutorFailures = countExecutors(app, v1beta2.ExecutorFailedState)
+
+	if n := len(app.Status.Attempts); n > 0 && app.Status.Attempts[n-1].SubmissionID == attempt.SubmissionID {
+		app.Status.Attempts[n-1] = attempt
+		return
+	}
+	app.Status.Attempts = append(app.Status.Attempts, attempt)
+	if len(app.Status.Attempts) > maxAttemptHistory {
+		app.Status.Attempts = app.Status.Attempts[len(app.Status.Attempts)-maxAttemptHistory:]
+	}
+}
+
 // State Machine for SparkApplication:
 //+--------------------------------------------------------------------------------------------------------------------+
 //|        +---------------------------------------------------------------------------------------------+             |
@@ -511,6 +801,18 @@ func shouldRetry(app *v1beta2.SparkApplication) bool {
 //|                                             |                               |                                      |
 //|                                             +-------------------------------+                                      |
 //|                                                                                                                    |
+//|  Once spec.suspend is set, in any state but Succeeding, Failing, Invalidating, Completed and Failed:               |
+//|                                                                                                                    |
+//|      +------------+          +------------+          +------------+                                                |
+//|      |            |          |            | resumed  |    New     |                                                |
+//|  ---->            +---------->            +---------->            |                                                |
+//|      | Suspending |          | Suspended  |          | Submission |                                                |
+//|      |            |          |            |          |   Failed   |                                                |
+//|      |            |          |            |          |  Pending   |                                                |
+//|      +------------+          +-----^------+          |   Rerun    |                                                |
+//|                                    |                 +------------+                                                |
+//|  New ------------------------------+                                                                               |
+//|                                                                                                                    |
 //+--------------------------------------------------------------------------------------------------------------------+
 func (c *Controller) syncSparkApplication(key string) error {
 	namespace, name, err := cache.SplitMetaNamespaceKey(key)
@@ -526,8 +828,12 @@ func (c *Controller) syncSparkApplication(key string) error {
 		return nil
 	}
 	if !app.DeletionTimestamp.IsZero() {
-		c.handleSparkApplicationDeletion(app)
-		return nil
+		return c.finalizeSparkApplication(app)
+	}
+	if !hasFinalizer(app) {
+		if app, err = c.addFinalizer(app); err != nil {
+			return fmt.Errorf("failed to add finalizer to SparkApplication %s/%s: %v", namespace, name, err)
+		}
 	}
 
 	appCopy := app.DeepCopy()
@@ -535,6 +841,13 @@ func (c *Controller) syncSparkApplication(key string) error {
 	// won't be sent to the API server as we only update the /status subresource.
 	v1beta2.SetSparkApplicationDefaults(appCopy)
 
+	if shouldSuspend(appCopy) {
+		if err := c.suspendSparkApplication(appCopy); err != nil {
+			glog.Errorf("failed to suspend SparkApplication %s/%s: %v", appCopy.Namespace, appCopy.Name, err)
+			return err
+		}
+	}
+
 	// Take action based on application state.
 	switch appCopy.Status.AppState.State {
 	case v1beta2.NewState:
@@ -546,6 +859,7 @@ func (c *Controller) syncSparkApplication(key string) error {
 			appCopy = c.submitSparkApplication(appCopy)
 		}
 	case v1beta2.SucceedingState:
+		recordAttempt(appCopy, v1beta2.CompletedState)
 		if !shouldRetry(appCopy) {
 			appCopy.Status.AppState.State = v1beta2.CompletedState
 			c.recordSparkApplicationEvent(appCopy)
@@ -558,15 +872,22 @@ func (c *Controller) syncSparkApplication(key string) error {
 			appCopy.Status.AppState.State = v1beta2.PendingRerunState
 		}
 	case v1beta2.FailingState:
+		recordAttempt(appCopy, v1beta2.FailedState)
 		if !shouldRetry(appCopy) {
 			appCopy.Status.AppState.State = v1beta2.FailedState
 			c.recordSparkApplicationEvent(appCopy)
-		} else if isNextRetryDue(appCopy.Spec.RestartPolicy.OnFailureRetryInterval, appCopy.Status.ExecutionAttempts, appCopy.Status.TerminationTime) {
+		} else if c.isRetryDue(appCopy, appCopy.Spec.RestartPolicy.OnFailureRetryInterval, appCopy.Status.ExecutionAttempts, appCopy.Status.TerminationTime) {
 			if err := c.deleteSparkResources(appCopy); err != nil {
 				glog.Errorf("failed to delete resources associated with SparkApplication %s/%s: %v",
 					appCopy.Namespace, appCopy.Name, err)
 				return err
 			}
+			countRestartRuleRetry(appCopy)
+			if memory, err := escalateMemory(appCopy); err != nil {
+				glog.Errorf("failed to escalate the memory of SparkApplication %s/%s: %v", appCopy.Namespace, appCopy.Name, err)
+			} else if memory != nil {
+				c.recordMemoryEscalationEvent(appCopy, memory)
+			}
 			appCopy.Status.AppState.State = v1beta2.PendingRerunState
 		}
 	case v1beta2.FailedSubmissionState:
@@ -574,7 +895,7 @@ func (c *Controller) syncSparkApplication(key string) error {
 			// App will never be retried. Move to terminal FailedState.
 			appCopy.Status.AppState.State = v1beta2.FailedState
 			c.recordSparkApplicationEvent(appCopy)
-		} else if isNextRetryDue(appCopy.Spec.RestartPolicy.OnSubmissionFailureRetryInterval, appCopy.Status.SubmissionAttempts, appCopy.Status.LastSubmissionAttemptTime) {
+		} else if c.isRetryDue(appCopy, appCopy.Spec.RestartPolicy.OnSubmissionFailureRetryInterval, appCopy.Status.SubmissionAttempts, appCopy.Status.LastSubmissionAttemptTime) {
 			appCopy = c.submitSparkApplication(appCopy)
 		}
 	case v1beta2.InvalidatingState:
@@ -584,22 +905,54 @@ func (c *Controller) syncSparkApplication(key string) error {
 				appCopy.Namespace, appCopy.Name, err)
 			return err
 		}
+		recordAttempt(appCopy, v1beta2.InvalidatingState)
 		c.clearStatus(&appCopy.Status)
 		appCopy.Status.AppState.State = v1beta2.PendingRerunState
 	case v1beta2.PendingRerunState:
 		glog.V(2).Infof("SparkApplication %s/%s is pending rerun", appCopy.Namespace, appCopy.Name)
 		if c.validateSparkResourceDeletion(appCopy) {
 			glog.V(2).Infof("Resources for SparkApplication %s/%s successfully deleted", appCopy.Namespace, appCopy.Name)
+			if c.executor.isFull() {
+				// Wait for a free submission slot before the status is cleared and the rerun is recorded.
+				c.enqueueAfter(appCopy, submissionSlotRetryInterval)
+				break
+			}
+			setCondition(appCopy, v1beta2.SparkApplicationResourcesCleanedUp, metav1.ConditionTrue, "ResourcesDeleted",
+				"the resources of the previous run were deleted")
 			c.recordSparkApplicationEvent(appCopy)
 			c.clearStatus(&appCopy.Status)
 			appCopy = c.submitSparkApplication(appCopy)
+		} else {
+			setCondition(appCopy, v1beta2.SparkApplicationResourcesCleanedUp, metav1.ConditionFalse, "DeletionPending",
+				"waiting for the resources of the previous run to be deleted")
+		}
+	case v1beta2.SuspendingState:
+		if c.validateSparkResourceDeletion(appCopy) {
+			glog.V(2).Infof("Resources for SparkApplication %s/%s successfully deleted", appCopy.Namespace, appCopy.Name)
+			appCopy.Status.AppState.State = v1beta2.SuspendedState
+			c.recordSparkApplicationEvent(appCopy)
+		}
+	case v1beta2.SuspendedState:
+		if !isSuspended(appCopy) {
+			c.resumeSparkApplication(appCopy)
 		}
 	case v1beta2.SubmittedState, v1beta2.RunningState, v1beta2.UnknownState:
 		if err := c.getAndUpdateAppState(appCopy); err != nil {
 			return err
 		}
+		if err := c.enforceDeadlines(appCopy); err != nil {
+			return err
+		}
+		if err := c.enforceExecutorFailureThreshold(appCopy); err != nil {
+			return err
+		}
+		c.updateProgress(appCopy)
+		if err := c.enforceProgressDeadline(appCopy); err != nil {
+			return err
+		}
 	case v1beta2.CompletedState, v1beta2.FailedState:
 		if c.hasApplicationExpired(app) {
+			// The resources of the application are deleted by its finalizer.
 			glog.Infof("Garbage collecting expired SparkApplication %s/%s", app.Namespace, app.Name)
 			err := c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Delete(context.TODO(), app.Name, metav1.DeleteOptions{GracePeriodSeconds: int64ptr(0)})
 			if err != nil && !errors.IsNotFound(err) {
@@ -613,6 +966,7 @@ func (c *Controller) syncSparkApplication(key string) error {
 	}
 
 	if appCopy != nil {
+		updateStateConditions(appCopy)
 		err = c.updateStatusAndExportMetrics(app, appCopy)
 		if err != nil {
 			glog.Errorf("failed to update SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
@@ -631,24 +985,80 @@ func (c *Controller) syncSparkApplication(key string) error {
 	return nil
 }
 
-// Helper func to determine if the next retry the SparkApplication is due now.
-func isNextRetryDue(retryInterval *int64, attemptsDone int32, lastEventTime metav1.Time) bool {
-	if retryInterval == nil || lastEventTime.IsZero() || attemptsDone <= 0 {
-		return false
+// retryInterval returns how long to wait before retrying an application after attemptsDone attempts, according
+// to the backoff strategy of its restart policy.
+func retryInterval(policy v1beta2.RestartPolicy, intervalSeconds int64, attemptsDone int32) time.Duration {
+	interval := float64(intervalSeconds) * float64(time.Second)
+	switch policy.BackoffStrategy {
+	case v1beta2.FixedBackoff:
+	case v1beta2.ExponentialBackoff:
+		multiplier := float64(defaultBackoffMultiplier)
+		if policy.BackoffMultiplier != nil {
+			if m, err := strconv.ParseFloat(*policy.BackoffMultiplier, 64); err == nil && m >= 1 {
+				multiplier = m
+			}
+		}
+		interval *= math.Pow(multiplier, float64(attemptsDone-1))
+	default:
+		interval *= float64(attemptsDone)
+	}
+	if policy.MaxRetryInterval != nil {
+		interval = math.Min(interval, float64(*policy.MaxRetryInterval)*float64(time.Second))
 	}
+	return time.Duration(math.Min(interval, float64(maxRetryInterval)))
+}
 
-	// Retry if we have waited at-least equal to attempts*RetryInterval since we do a linear back-off.
-	interval := time.Duration(*retryInterval) * time.Second * time.Duration(attemptsDone)
-	currentTime := time.Now()
-	glog.V(3).Infof("currentTime is %v, interval is %v", currentTime, interval)
-	if currentTime.After(lastEventTime.Add(interval)) {
-		return true
+// nextRetryTime returns when to retry an application after attemptsDone attempts, the last of which ended at
+// lastEventTime, or the zero time if the application is not to be retried after an interval.
+func nextRetryTime(policy v1beta2.RestartPolicy, intervalSeconds *int64, attemptsDone int32, lastEventTime metav1.Time) metav1.Time {
+	if intervalSeconds == nil || lastEventTime.IsZero() || attemptsDone <= 0 {
+		return metav1.Time{}
 	}
-	return false
+	interval := retryInterval(policy, *intervalSeconds, attemptsDone)
+	if policy.BackoffJitterPercent != nil && *policy.BackoffJitterPercent > 0 {
+		jitter := float64(*policy.BackoffJitterPercent) / 100
+		interval = time.Duration(float64(interval) * (1 + jitter*(2*rand.Float64()-1)))
+	}
+	glog.V(3).Infof("lastEventTime is %v, interval is %v", lastEventTime, interval)
+	return metav1.NewTime(lastEventTime.Add(interval))
+}
+
+// Helper func to determine if the next retry the SparkApplication is due now.
+func isNextRetryDue(nextRetryTime metav1.Time) bool {
+	return !nextRetryTime.IsZero() && time.Now().After(nextRetryTime.Time)
+}
+
+// isRetryDue records when the next retry of the application is due in its status, and returns whether it is due
+// now. The application is enqueued again for when the retry is due, so that the retry does not wait for a resync.
+func (c *Controller) isRetryDue(app *v1beta2.SparkApplication, intervalSeconds *int64, attemptsDone int32, lastEventTime metav1.Time) bool {
+	if app.Status.NextRetryTime.IsZero() {
+		app.Status.NextRetryTime = nextRetryTime(app.Spec.RestartPolicy, intervalSeconds, attemptsDone, lastEventTime)
+		if app.Status.NextRetryTime.IsZero() {
+			return false
+		}
+		c.enqueueAfter(app, time.Until(app.Status.NextRetryTime.Time))
+	}
+	if !isNextRetryDue(app.Status.NextRetryTime) {
+		return false
+	}
+	app.Status.NextRetryTime = metav1.Time{}
+	return true
 }
 
 // submitSparkApplication creates a new submission for the given SparkApplication and submits it using spark-submit.
 func (c *Controller) submitSparkApplication(app *v1beta2.SparkApplication) *v1beta2.SparkApplication {
+	key, err := keyFunc(app)
+	if err != nil {
+		glog.Errorf("failed to get key for SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+		return app
+	}
+	if !c.executor.tryAcquire(key) {
+		glog.V(2).Infof("no submission slot is free for SparkApplication %s/%s, requeuing it", app.Namespace, app.Name)
+		c.enqueueAfter(app, submissionSlotRetryInterval)
+		return app
+	}
+	defer c.executor.release()
+
 	if app.PrometheusMonitoringEnabled() {
 		if err := configPrometheusMonitoring(app, c.kubeClient); err != nil {
 			glog.Error(err)
@@ -662,6 +1072,7 @@ func (c *Controller) submitSparkApplication(app *v1beta2.SparkApplication) *v1be
 			glog.Errorf("failed to process batch scheduler BeforeSubmitSparkApplication with error %v", err)
 			return app
 		}
+		recordPodGroup(app)
 	}
 
 	if c.enableUIService {
@@ -669,6 +1080,7 @@ func (c *Controller) submitSparkApplication(app *v1beta2.SparkApplication) *v1be
 		if err != nil {
 			glog.Errorf("failed to create UI service for SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
 		} else {
+			recordResource(app, coreAPIVersion, serviceKind, service.serviceName, service.serviceUID)
 			app.Status.DriverInfo.WebUIServiceName = service.serviceName
 			app.Status.DriverInfo.WebUIPort = service.servicePort
 			app.Status.DriverInfo.WebUIAddress = fmt.Sprintf("%s:%d", service.serviceIP, app.Status.DriverInfo.WebUIPort)
@@ -691,6 +1103,7 @@ func (c *Controller) submitSparkApplication(app *v1beta2.SparkApplication) *v1be
 					if err != nil {
 						glog.Errorf("failed to create UI Ingress for SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
 					} else {
+						recordResource(app, ingress.apiVersion, ingressKind, ingress.ingressName, ingress.ingressUID)
 						app.Status.DriverInfo.WebUIIngressAddress = ingress.ingressURL.String()
 						app.Status.DriverInfo.WebUIIngressName = ingress.ingressName
 					}
@@ -701,29 +1114,23 @@ func (c *Controller) submitSparkApplication(app *v1beta2.SparkApplication) *v1be
 
 	driverPodName := getDriverPodName(app)
 	submissionID := uuid.New().String()
-	submissionCmdArgs, err := buildSubmissionCommandArgs(app, driverPodName, submissionID)
+	// Escalated memory only applies to the submitted copy of the application, its spec is left unchanged.
+	submittedApp := withAttemptMemory(app, getAttemptMemory(app))
+	submissionCmdArgs, err := buildSubmissionCommandArgs(submittedApp, driverPodName, submissionID)
 	if err != nil {
-		app.Status = v1beta2.SparkApplicationStatus{
-			AppState: v1beta2.ApplicationState{
-				State:        v1beta2.FailedSubmissionState,
-				ErrorMessage: err.Error(),
-			},
-			SubmissionAttempts:        app.Status.SubmissionAttempts + 1,
-			LastSubmissionAttemptTime: metav1.Now(),
-		}
+		app.Status.AppState.State = v1beta2.FailedSubmissionState
+		app.Status.AppState.ErrorMessage = err.Error()
+		app.Status.SubmissionAttempts++
+		app.Status.LastSubmissionAttemptTime = metav1.Now()
 		return app
 	}
-	// Try submitting the application by running spark-submit.
-	submitted, err := runSparkSubmit(newSubmission(submissionCmdArgs, app))
+	// Try submitting the application by running spark-submit, or by creating the driver natively.
+	submitted, err := c.runSubmission(submittedApp, submissionCmdArgs, driverPodName, submissionID)
 	if err != nil {
-		app.Status = v1beta2.SparkApplicationStatus{
-			AppState: v1beta2.ApplicationState{
-				State:        v1beta2.FailedSubmissionState,
-				ErrorMessage: err.Error(),
-			},
-			SubmissionAttempts:        app.Status.SubmissionAttempts + 1,
-			LastSubmissionAttemptTime: metav1.Now(),
-		}
+		app.Status.AppState.State = v1beta2.FailedSubmissionState
+		app.Status.AppState.ErrorMessage = err.Error()
+		app.Status.SubmissionAttempts++
+		app.Status.LastSubmissionAttemptTime = metav1.Now()
 		c.recordSparkApplicationEvent(app)
 		glog.Errorf("failed to run spark-submit for SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
 		return app
@@ -736,23 +1143,50 @@ func (c *Controller) submitSparkApplication(app *v1beta2.SparkApplication) *v1be
 	}
 
 	glog.Infof("SparkApplication %s/%s has been submitted", app.Namespace, app.Name)
-	app.Status = v1beta2.SparkApplicationStatus{
-		SubmissionID: submissionID,
-		AppState: v1beta2.ApplicationState{
-			State: v1beta2.SubmittedState,
-		},
-		DriverInfo: v1beta2.DriverInfo{
-			PodName: driverPodName,
-		},
-		SubmissionAttempts:        app.Status.SubmissionAttempts + 1,
-		ExecutionAttempts:         app.Status.ExecutionAttempts + 1,
-		LastSubmissionAttemptTime: metav1.Now(),
-	}
+	app.Status.SubmissionID = submissionID
+	app.Status.AppState.State = v1beta2.SubmittedState
+	app.Status.AppState.ErrorMessage = ""
+	app.Status.DriverInfo.PodName = driverPodName
+	app.Status.SubmissionAttempts++
+	app.Status.ExecutionAttempts++
+	app.Status.LastSubmissionAttemptTime = metav1.Now()
 	c.recordSparkApplicationEvent(app)
 
 	return app
 }
 
+// runSubmission submits the application with the submitter selected by the operator or the application. The
+// native submitter falls back to spark-submit for applications it does not support.
+func (c *Controller) runSubmission(app *v1beta2.SparkApplication, args []string, driverPodName string, submissionID string) (bool, error) {
+	if c.getSubmitter(app) == NativeSubmitter {
+		submission, err := newNativeSubmission(args, app)
+		if err != nil {
+			return false, err
+		}
+		reason := submission.unsupportedReason()
+		if reason == "" {
+			return runNativeSubmission(c.kubeClient, submission, app, driverPodName, submissionID)
+		}
+		glog.Infof("submitting SparkApplication %s/%s with spark-submit because the native submitter does not support %s", app.Namespace, app.Name, reason)
+	}
+	return c.executor.run(newSubmission(args, app))
+}
+
+// getSubmitter returns the submitter selected with the submitter annotation of the application, or the default
+// submitter of the operator.
+func (c *Controller) getSubmitter(app *v1beta2.SparkApplication) SubmitterType {
+	value, ok := app.Annotations[config.SubmitterAnnotation]
+	if !ok {
+		return c.submitter
+	}
+	switch submitter := SubmitterType(value); submitter {
+	case SparkSubmitSubmitter, NativeSubmitter:
+		return submitter
+	}
+	glog.Warningf("ignoring invalid value %q of annotation %s on SparkApplication %s/%s", value, config.SubmitterAnnotation, app.Namespace, app.Name)
+	return c.submitter
+}
+
 func (c *Controller) shouldDoBatchScheduling(app *v1beta2.SparkApplication) (bool, schedulerinterface.BatchScheduler) {
 	if c.batchSchedulerMgr == nil || app.Spec.BatchScheduler == nil || *app.Spec.BatchScheduler == "" {
 		return false, nil
@@ -846,51 +1280,6 @@ func (c *Controller) getSparkApplication(namespace string, name string) (*v1beta
 	return app, nil
 }
 
-// Delete the driver pod and optional UI resources (Service/Ingress) created for the application.
-func (c *Controller) deleteSparkResources(app *v1beta2.SparkApplication) error {
-	driverPodName := app.Status.DriverInfo.PodName
-	// Derive the driver pod name in case the driver pod name was not recorded in the status,
-	// which could happen if the status update right after submission failed.
-	if driverPodName == "" {
-		driverPodName = getDriverPodName(app)
-	}
-
-	glog.V(2).Infof("Deleting pod %s in namespace %s", driverPodName, app.Namespace)
-	err := c.kubeClient.CoreV1().Pods(app.Namespace).Delete(context.TODO(), driverPodName, metav1.DeleteOptions{})
-	if err != nil && !errors.IsNotFound(err) {
-		return err
-	}
-
-	sparkUIServiceName := app.Status.DriverInfo.WebUIServiceName
-	if sparkUIServiceName != "" {
-		glog.V(2).Infof("Deleting Spark UI Service %s in namespace %s", sparkUIServiceName, app.Namespace)
-		err := c.kubeClient.CoreV1().Services(app.Namespace).Delete(context.TODO(), sparkUIServiceName, metav1.DeleteOptions{GracePeriodSeconds: int64ptr(0)})
-		if err != nil && !errors.IsNotFound(err) {
-			return err
-		}
-	}
-
-	sparkUIIngressName := app.Status.DriverInfo.WebUIIngressName
-	if sparkUIIngressName != "" {
-		if util.IngressCapabilities.Has("networking.k8s.io/v1") {
-			glog.V(2).Infof("Deleting Spark UI Ingress %s in namespace %s", sparkUIIngressName, app.Namespace)
-			err := c.kubeClient.NetworkingV1().Ingresses(app.Namespace).Delete(context.TODO(), sparkUIIngressName, metav1.DeleteOptions{GracePeriodSeconds: int64ptr(0)})
-			if err != nil && !errors.IsNotFound(err) {
-				return err
-			}
-		}
-		if util.IngressCapabilities.Has("extensions/v1beta1") {
-			glog.V(2).Infof("Deleting extensions/v1beta1 Spark UI Ingress %s in namespace %s", sparkUIIngressName, app.Namespace)
-			err := c.kubeClient.ExtensionsV1beta1().Ingresses(app.Namespace).Delete(context.TODO(), sparkUIIngressName, metav1.DeleteOptions{GracePeriodSeconds: int64ptr(0)})
-			if err != nil && !errors.IsNotFound(err) {
-				return err
-			}
-		}
-	}
-
-	return nil
-}
-
 func (c *Controller) validateSparkApplication(app *v1beta2.SparkApplication) error {
 	appSpec := app.Spec
 	driverSpec := appSpec.Driver
@@ -898,40 +1287,44 @@ func (c *Controller) validateSparkApplication(app *v1beta2.SparkApplication) err
 	if appSpec.NodeSelector != nil && (driverSpec.NodeSelector != nil || executorSpec.NodeSelector != nil) {
 		return fmt.Errorf("NodeSelector property can be defined at SparkApplication or at any of Driver,Executor")
 	}
-
-	return nil
-}
-
-// Validate that any Spark resources (driver/Service/Ingress) created for the application have been deleted.
-func (c *Controller) validateSparkResourceDeletion(app *v1beta2.SparkApplication) bool {
-	driverPodName := app.Status.DriverInfo.PodName
-	// Derive the driver pod name in case the driver pod name was not recorded in the status,
-	// which could happen if the status update right after submission failed.
-	if driverPodName == "" {
-		driverPodName = getDriverPodName(app)
-	}
-	_, err := c.kubeClient.CoreV1().Pods(app.Namespace).Get(context.TODO(), driverPodName, metav1.GetOptions{})
-	if err == nil || !errors.IsNotFound(err) {
-		return false
+	if multiplier := appSpec.RestartPolicy.BackoffMultiplier; multiplier != nil {
+		if m, err := strconv.ParseFloat(*multiplier, 64); err != nil || m < 1 {
+			return fmt.Errorf("RestartPolicy.BackoffMultiplier must be a number greater than or equal to 1, got %q", *multiplier)
+		}
 	}
-
-	sparkUIServiceName := app.Status.DriverInfo.WebUIServiceName
-	if sparkUIServiceName != "" {
-		_, err := c.kubeClient.CoreV1().Services(app.Namespace).Get(context.TODO(), sparkUIServiceName, metav1.GetOptions{})
-		if err == nil || !errors.IsNotFound(err) {
-			return false
+	if escalation := appSpec.RestartPolicy.MemoryEscalation; escalation != nil {
+		if _, err := parseSparkMemoryMiB(escalation.MaxMemory); err != nil {
+			return fmt.Errorf("RestartPolicy.MemoryEscalation.MaxMemory is invalid: %v", err)
+		}
+		if factor := escalation.Factor; factor != nil {
+			if f, err := strconv.ParseFloat(*factor, 64); err != nil || f < 1 {
+				return fmt.Errorf("RestartPolicy.MemoryEscalation.Factor must be a number greater than or equal to 1, got %q", *factor)
+			}
 		}
 	}
-
-	sparkUIIngressName := app.Status.DriverInfo.WebUIIngressName
-	if sparkUIIngressName != "" {
-		_, err := c.kubeClient.NetworkingV1().Ingresses(app.Namespace).Get(context.TODO(), sparkUIIngressName, metav1.GetOptions{})
-		if err == nil || !errors.IsNotFound(err) {
-			return false
+	if appSpec.ProgressDeadlineSeconds != nil && !c.enableUIService {
+		// The progress is read through the UI service of the driver.
+		return fmt.Errorf("ProgressDeadlineSeconds requires the operator to create UI services, which is disabled with -enable-ui-service=false")
+	}
+	ruleNames := make(map[string]bool)
+	for i := range appSpec.RestartPolicy.Rules {
+		rule := &appSpec.RestartPolicy.Rules[i]
+		name := restartRuleName(rule, i)
+		if ruleNames[name] {
+			return fmt.Errorf("RestartPolicy.Rules has more than one rule named %q", name)
+		}
+		ruleNames[name] = true
+		if rule.Action != v1beta2.RestartRuleRetry && rule.Action != v1beta2.RestartRuleFail {
+			return fmt.Errorf("RestartPolicy rule %q has invalid action %q", name, rule.Action)
+		}
+		for _, codes := range rule.ExitCodes {
+			if codes.Max != nil && *codes.Max < codes.Min {
+				return fmt.Errorf("RestartPolicy rule %q has an exit code range with max %d less than min %d", name, *codes.Max, codes.Min)
+			}
 		}
 	}
 
-	return true
+	return nil
 }
 
 func (c *Controller) enqueue(obj interface{}) {
@@ -944,6 +1337,16 @@ func (c *Controller) enqueue(obj interface{}) {
 	c.queue.AddRateLimited(key)
 }
 
+func (c *Controller) enqueueAfter(obj interface{}, after time.Duration) {
+	key, err := keyFunc(obj)
+	if err != nil {
+		glog.Errorf("failed to get key for %v: %v", obj, err)
+		return
+	}
+
+	c.queue.AddAfter(key, after)
+}
+
 func (c *Controller) recordSparkApplicationEvent(app *v1beta2.SparkApplication) {
 	switch app.Status.AppState.State {
 	case v1beta2.NewState:
@@ -990,9 +1393,35 @@ func (c *Controller) recordSparkApplicationEvent(app *v1beta2.SparkApplication)
 			"SparkApplicationPendingRerun",
 			"SparkApplication %s is pending rerun",
 			app.Name)
+	case v1beta2.SuspendingState:
+		c.recorder.Eventf(
+			app,
+			apiv1.EventTypeNormal,
+			"SparkApplicationSuspending",
+			"SparkApplication %s is being suspended",
+			app.Name)
+	case v1beta2.SuspendedState:
+		c.recorder.Eventf(
+			app,
+			apiv1.EventTypeNormal,
+			"SparkApplicationSuspended",
+			"SparkApplication %s was suspended",
+			app.Name)
 	}
 }
 
+func (c *Controller) recordMemoryEscalationEvent(app *v1beta2.SparkApplication, memory *v1beta2.AttemptMemory) {
+	c.recorder.Eventf(
+		app,
+		apiv1.EventTypeNormal,
+		"SparkApplicationMemoryEscalated",
+		"SparkApplication %s ran out of memory, attempt %d runs with driver memory %s and executor memory %s",
+		app.Name,
+		memory.ExecutionAttempt,
+		*memory.DriverMemory,
+		*memory.ExecutorMemory)
+}
+
 func (c *Controller) recordDriverEvent(app *v1beta2.SparkApplication, phase v1beta2.DriverState, name string) {
 	switch phase {
 	case v1beta2.DriverCompletedState:
@@ -1030,15 +1459,35 @@ func (c *Controller) clearStatus(status *v1beta2.SparkApplicationStatus) {
 		status.ExecutionAttempts = 0
 		status.LastSubmissionAttemptTime = metav1.Time{}
 		status.TerminationTime = metav1.Time{}
+		status.NextRetryTime = metav1.Time{}
+		status.TerminationReason = ""
+		status.DriverExitCode = nil
+		status.RestartRuleRetries = nil
+		status.ExecutorOOMKills = 0
+		status.ExecutorFailureTimes = nil
+		status.EffectiveMemory = nil
 		status.AppState.ErrorMessage = ""
+		status.Executors = nil
 		status.ExecutorState = nil
+		status.RolledUpExecutors = nil
+		status.Progress = nil
 	} else if status.AppState.State == v1beta2.PendingRerunState {
 		status.SparkApplicationID = ""
 		status.SubmissionAttempts = 0
 		status.LastSubmissionAttemptTime = metav1.Time{}
 		status.DriverInfo = v1beta2.DriverInfo{}
+		status.TerminationTime = metav1.Time{}
+		status.NextRetryTime = metav1.Time{}
+		status.TerminationReason = ""
+		status.DriverExitCode = nil
+		status.ExecutorOOMKills = 0
+		status.ExecutorFailureTimes = nil
 		status.AppState.ErrorMessage = ""
+		status.Executors = nil
 		status.ExecutorState = nil
+		status.RolledUpExecutors = nil
+		status.Resources = nil
+		status.Progress = nil
 	}
 }
 
@@ -30,6 +30,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/informers"
 	kubeclientfake "k8s.io/client-go/kubernetes/fake"
@@ -68,7 +69,7 @@ func newFakeController(app *v1beta2.SparkApplication, pods ...*apiv1.Pod) (*Cont
 
 	podInformerFactory := informers.NewSharedInformerFactory(kubeClient, 0*time.Second)
 	controller := newSparkApplicationController(crdClient, kubeClient, informerFactory, podInformerFactory, recorder,
-		&util.MetricConfig{}, "", nil, true)
+		&util.MetricConfig{}, "", "", nil, true, SparkSubmitSubmitter, 1, time.Minute, 0, 0, 0)
 
 	informer := informerFactory.Sparkoperator().V1beta2().SparkApplications().Informer()
 	if app != nil {
@@ -175,6 +176,40 @@ func TestOnUpdate(t *testing.T) {
 	assert.Equal(t, v1beta2.InvalidatingState, app.Status.AppState.State)
 }
 
+func TestOnUpdateSuspend(t *testing.T) {
+	ctrl, recorder := newFakeController(nil)
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "foo",
+			Namespace:       "default",
+			ResourceVersion: "1",
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{State: v1beta2.RunningState},
+		},
+	}
+	ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+
+	suspendedApp := app.DeepCopy()
+	suspendedApp.Spec.Suspend = boolptr(true)
+	suspendedApp.ResourceVersion = "2"
+	ctrl.onUpdate(app, suspendedApp)
+
+	// Verify that the SparkApplication was enqueued without being invalidated.
+	item, _ := ctrl.queue.Get()
+	key, ok := item.(string)
+	assert.True(t, ok)
+	expectedKey, _ := cache.MetaNamespaceKeyFunc(app)
+	assert.Equal(t, expectedKey, key)
+	ctrl.queue.Forget(item)
+	ctrl.queue.Done(item)
+	assert.Equal(t, 0, len(recorder.Events))
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.RunningState, updatedApp.Status.AppState.State)
+}
+
 func TestOnDelete(t *testing.T) {
 	ctrl, recorder := newFakeController(nil)
 
@@ -232,6 +267,361 @@ type executorMetrics struct {
 	failedMetricCount  float64
 }
 
+func TestShouldRetryWithRestartRules(t *testing.T) {
+	policy := v1beta2.RestartPolicy{
+		Type:                   v1beta2.OnFailure,
+		OnFailureRetries:       int32ptr(1),
+		OnFailureRetryInterval: int64ptr(10),
+		Rules: []v1beta2.RestartRule{
+			{Name: "spot", Reasons: []v1beta2.TerminationReason{v1beta2.NodeLostReason, v1beta2.PreemptedReason}, Action: v1beta2.RestartRuleRetry, Retries: int32ptr(3)},
+			{Reasons: []v1beta2.TerminationReason{v1beta2.ImagePullFailureReason}, Action: v1beta2.RestartRuleFail},
+			{ExitCodes: []v1beta2.ExitCodeRange{{Min: 2, Max: int32ptr(9)}}, Action: v1beta2.RestartRuleFail},
+			{ExitCodes: []v1beta2.ExitCodeRange{{Min: 137}}, Action: v1beta2.RestartRuleRetry},
+		},
+	}
+	newApp := func(reason v1beta2.TerminationReason, exitCode *int32, executionAttempts int32, ruleRetries map[string]int32) *v1beta2.SparkApplication {
+		return &v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec:       v1beta2.SparkApplicationSpec{RestartPolicy: policy},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState:           v1beta2.ApplicationState{State: v1beta2.FailingState},
+				TerminationReason:  reason,
+				DriverExitCode:     exitCode,
+				ExecutionAttempts:  executionAttempts,
+				RestartRuleRetries: ruleRetries,
+			},
+		}
+	}
+
+	// Runs lost with their node are retried with the budget of the spot rule.
+	assert.True(t, shouldRetry(newApp(v1beta2.NodeLostReason, nil, 3, map[string]int32{"spot": 2})))
+	assert.False(t, shouldRetry(newApp(v1beta2.PreemptedReason, nil, 4, map[string]int32{"spot": 3})))
+	// Retries of the spot rule don't count against OnFailureRetries.
+	assert.True(t, shouldRetry(newApp("", int32ptr(1), 3, map[string]int32{"spot": 2})))
+	assert.False(t, shouldRetry(newApp("", int32ptr(1), 2, nil)))
+	// Image pull failures and exit codes from 2 to 9 give up right away.
+	assert.False(t, shouldRetry(newApp(v1beta2.ImagePullFailureReason, nil, 1, nil)))
+	assert.False(t, shouldRetry(newApp("", int32ptr(5), 1, nil)))
+	// A retry rule without a budget uses OnFailureRetries.
+	assert.True(t, shouldRetry(newApp("", int32ptr(137), 1, nil)))
+	assert.False(t, shouldRetry(newApp("", int32ptr(137), 2, nil)))
+
+	app := newApp(v1beta2.NodeLostReason, nil, 1, nil)
+	countRestartRuleRetry(app)
+	assert.Equal(t, map[string]int32{"spot": 1}, app.Status.RestartRuleRetries)
+	app = newApp("", int32ptr(137), 1, nil)
+	countRestartRuleRetry(app)
+	assert.Nil(t, app.Status.RestartRuleRetries)
+
+	// Rule names must be unique and exit code ranges valid.
+	ctrl, _ := newFakeController(nil)
+	app = newApp("", nil, 0, nil)
+	assert.NoError(t, ctrl.validateSparkApplication(app))
+	app.Spec.RestartPolicy.Rules[1].Name = "spot"
+	assert.Error(t, ctrl.validateSparkApplication(app))
+	app = newApp("", nil, 0, nil)
+	app.Spec.RestartPolicy.Rules[2].ExitCodes[0].Max = int32ptr(1)
+	assert.Error(t, ctrl.validateSparkApplication(app))
+}
+
+func TestSyncSparkApplication_DriverTerminationReason(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			RestartPolicy: v1beta2.RestartPolicy{
+				Type:                   v1beta2.OnFailure,
+				OnFailureRetries:       int32ptr(3),
+				OnFailureRetryInterval: int64ptr(10),
+				Rules: []v1beta2.RestartRule{
+					{Reasons: []v1beta2.TerminationReason{v1beta2.OOMKilledReason}, Action: v1beta2.RestartRuleFail},
+				},
+			},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{
+				State: v1beta2.RunningState,
+			},
+			DriverInfo: v1beta2.DriverInfo{
+				PodName: "foo-driver",
+			},
+			ExecutionAttempts: 1,
+		},
+	}
+	driverPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-driver",
+			Namespace: "default",
+			Labels: map[string]string{
+				config.SparkRoleLabel:    config.SparkDriverRole,
+				config.SparkAppNameLabel: "foo",
+			},
+		},
+		Status: apiv1.PodStatus{
+			Phase: apiv1.PodFailed,
+			ContainerStatuses: []apiv1.ContainerStatus{
+				{
+					Name: config.SparkDriverContainerName,
+					State: apiv1.ContainerState{
+						Terminated: &apiv1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"},
+					},
+				},
+			},
+		},
+	}
+	ctrl, _ := newFakeController(app, driverPod)
+	_, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.FailingState, updatedApp.Status.AppState.State)
+	assert.Equal(t, v1beta2.OOMKilledReason, updatedApp.Status.TerminationReason)
+	assert.Equal(t, int32(137), *updatedApp.Status.DriverExitCode)
+
+	// The OOMKilled rule gives up although OnFailureRetries are left.
+	updatedApp.Status.SubmissionID = "submission-1"
+	ctrl, _ = newFakeController(updatedApp, driverPod)
+	_, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), updatedApp, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.FailedState, updatedApp.Status.AppState.State)
+	assert.Len(t, updatedApp.Status.Attempts, 1)
+	attempt := updatedApp.Status.Attempts[0]
+	assert.Equal(t, "submission-1", attempt.SubmissionID)
+	assert.Equal(t, int32(1), attempt.ExecutionAttempt)
+	assert.Equal(t, v1beta2.FailedState, attempt.State)
+	assert.Equal(t, v1beta2.OOMKilledReason, attempt.TerminationReason)
+	assert.Equal(t, int32(137), *attempt.DriverExitCode)
+	assert.False(t, attempt.EndTime.IsZero())
+}
+
+func TestSyncSparkApplication_DriverContainerError(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState:          v1beta2.ApplicationState{State: v1beta2.SubmittedState},
+			DriverInfo:        v1beta2.DriverInfo{PodName: "foo-driver"},
+			ExecutionAttempts: 1,
+		},
+	}
+	driverPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-driver",
+			Namespace: "default",
+			Labels: map[string]string{
+				config.SparkRoleLabel:    config.SparkDriverRole,
+				config.SparkAppNameLabel: "foo",
+			},
+		},
+		Status: apiv1.PodStatus{
+			Phase:     apiv1.PodPending,
+			StartTime: &metav1.Time{Time: time.Now().Add(-time.Minute)},
+			ContainerStatuses: []apiv1.ContainerStatus{
+				{
+					Name: config.SparkDriverContainerName,
+					State: apiv1.ContainerState{
+						Waiting: &apiv1.ContainerStateWaiting{
+							Reason:  "CreateContainerConfigError",
+							Message: `secret "spark-secret" not found`,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// The missing Secret may still be created within the grace period.
+	ctrl, _ := newFakeController(app, driverPod)
+	_, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, ctrl.syncSparkApplication("default/foo"))
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.SubmittedState, updatedApp.Status.AppState.State)
+	assert.Empty(t, updatedApp.Status.TerminationReason)
+
+	driverPod.Status.StartTime = &metav1.Time{Time: time.Now().Add(-driverContainerErrorGracePeriod)}
+	ctrl, recorder := newFakeController(app, driverPod)
+	_, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ctrl.kubeClient.CoreV1().Pods(app.Namespace).Create(context.TODO(), driverPod, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.FailingState, updatedApp.Status.AppState.State)
+	assert.Equal(t, v1beta2.ContainerConfigErrorReason, updatedApp.Status.TerminationReason)
+	assert.Equal(t, `driver container failed to start with Reason: CreateContainerConfigError, Message: secret "spark-secret" not found`,
+		updatedApp.Status.AppState.ErrorMessage)
+	assert.Contains(t, <-recorder.Events, "SparkDriverContainerConfigError")
+	assert.Contains(t, <-recorder.Events, "SparkDriverFailed")
+	assert.Equal(t, float64(1), fetchCounterValue(ctrl.metrics.sparkAppDriverErrorCount,
+		map[string]string{reasonLabel: string(v1beta2.ContainerConfigErrorReason)}))
+
+	// The pending driver pod is deleted as its container will never start.
+	_, err = ctrl.kubeClient.CoreV1().Pods(app.Namespace).Get(context.TODO(), driverPod.Name, metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestSyncSparkApplication_Suspend(t *testing.T) {
+	sync := func(app *v1beta2.SparkApplication, pods ...*apiv1.Pod) (*Controller, *v1beta2.SparkApplication) {
+		ctrl, _ := newFakeController(app, pods...)
+		_, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if app.Status.AppState.State == v1beta2.RunningState {
+			ctrl.metrics.sparkAppRunningCount.Inc(map[string]string{})
+		}
+		err = ctrl.syncSparkApplication(fmt.Sprintf("%s/%s", app.Namespace, app.Name))
+		assert.Nil(t, err)
+		updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		return ctrl, updatedApp
+	}
+
+	// A new application is held without being submitted.
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec:       v1beta2.SparkApplicationSpec{Suspend: boolptr(true)},
+	}
+	_, updatedApp := sync(app)
+	assert.Equal(t, v1beta2.SuspendedState, updatedApp.Status.AppState.State)
+	assert.Equal(t, int32(0), updatedApp.Status.SubmissionAttempts)
+	updatedApp.Spec.Suspend = nil
+	_, updatedApp = sync(updatedApp)
+	assert.Equal(t, v1beta2.NewState, updatedApp.Status.AppState.State)
+
+	// A running application is stopped without counting the run as an attempt.
+	app = &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec:       v1beta2.SparkApplicationSpec{Suspend: boolptr(true)},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState:           v1beta2.ApplicationState{State: v1beta2.RunningState},
+			SubmissionID:       "submission-2",
+			DriverInfo:         v1beta2.DriverInfo{PodName: "foo-driver"},
+			SubmissionAttempts: 1,
+			ExecutionAttempts:  2,
+		},
+	}
+	driverPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-driver",
+			Namespace: "default",
+			Labels: map[string]string{
+				config.SparkRoleLabel:    config.SparkDriverRole,
+				config.SparkAppNameLabel: "foo",
+			},
+		},
+		Status: apiv1.PodStatus{Phase: apiv1.PodRunning},
+	}
+	// The fake client deletes the driver pod right away, so the application does not stay SUSPENDING.
+	ctrl, updatedApp := sync(app, driverPod)
+	assert.Equal(t, v1beta2.SuspendedState, updatedApp.Status.AppState.State)
+	assert.Equal(t, int32(1), updatedApp.Status.ExecutionAttempts)
+	assert.Len(t, updatedApp.Status.Attempts, 1)
+	assert.Equal(t, v1beta2.SuspendedState, updatedApp.Status.Attempts[0].State)
+	_, err := ctrl.kubeClient.CoreV1().Pods("default").Get(context.TODO(), "foo-driver", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	assert.True(t, meta.IsStatusConditionTrue(updatedApp.Status.Conditions, v1beta2.SparkApplicationSuspended))
+	assert.Equal(t, float64(0), ctrl.metrics.sparkAppRunningCount.Value(map[string]string{}))
+
+	// Resuming the application reruns it.
+	updatedApp.Spec.Suspend = boolptr(false)
+	_, updatedApp = sync(updatedApp)
+	assert.Equal(t, v1beta2.PendingRerunState, updatedApp.Status.AppState.State)
+	assert.Equal(t, int32(1), updatedApp.Status.ExecutionAttempts)
+
+	// Terminated applications are left alone.
+	app = &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec:       v1beta2.SparkApplicationSpec{Suspend: boolptr(true)},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{State: v1beta2.CompletedState},
+		},
+	}
+	_, updatedApp = sync(app)
+	assert.Equal(t, v1beta2.CompletedState, updatedApp.Status.AppState.State)
+
+	// Applications that will not be retried terminate instead of being suspended.
+	app.Status.AppState.State = v1beta2.SucceedingState
+	_, updatedApp = sync(app)
+	assert.Equal(t, v1beta2.CompletedState, updatedApp.Status.AppState.State)
+	app.Status.AppState.State = v1beta2.FailedSubmissionState
+	_, updatedApp = sync(app)
+	assert.Equal(t, v1beta2.FailedState, updatedApp.Status.AppState.State)
+
+	// An application suspended after a failed submission keeps the submission attempts it used.
+	app.Spec.RestartPolicy = v1beta2.RestartPolicy{
+		Type:                             v1beta2.OnFailure,
+		OnSubmissionFailureRetries:       int32ptr(3),
+		OnSubmissionFailureRetryInterval: int64ptr(3600),
+	}
+	app.Status.SubmissionAttempts = 2
+	app.Status.LastSubmissionAttemptTime = metav1.Now()
+	app.Status.Conditions = []metav1.Condition{{
+		Type: v1beta2.SparkApplicationSubmitted, Status: metav1.ConditionFalse, Reason: "SubmissionFailed"}}
+	_, updatedApp = sync(app)
+	assert.Equal(t, v1beta2.SuspendedState, updatedApp.Status.AppState.State)
+	updatedApp.Spec.Suspend = nil
+	_, updatedApp = sync(updatedApp)
+	assert.Equal(t, v1beta2.FailedSubmissionState, updatedApp.Status.AppState.State)
+	assert.Equal(t, int32(2), updatedApp.Status.SubmissionAttempts)
+}
+
+func TestRecordAttempt(t *testing.T) {
+	app := &v1beta2.SparkApplication{}
+	recordAttempt(app, v1beta2.FailedState)
+	assert.Empty(t, app.Status.Attempts)
+
+	for i := 1; i <= maxAttemptHistory+2; i++ {
+		app.Status = v1beta2.SparkApplicationStatus{
+			SubmissionID:       fmt.Sprintf("submission-%d", i),
+			SparkApplicationID: fmt.Sprintf("spark-%d", i),
+			ExecutionAttempts:  int32(i),
+			AppState:           v1beta2.ApplicationState{State: v1beta2.FailingState, ErrorMessage: "driver failed"},
+			Executors: []v1beta2.ExecutorStatus{
+				{PodName: "exec-1", State: v1beta2.ExecutorFailedState},
+				{PodName: "exec-3", State: v1beta2.ExecutorCompletedState},
+			},
+			RolledUpExecutors: &v1beta2.RolledUpExecutors{Failed: 1},
+			Attempts:          app.Status.Attempts,
+		}
+		recordAttempt(app, v1beta2.FailedState)
+		// Recording the same attempt again updates its entry.
+		app.Status.AppState.ErrorMessage = "driver failed again"
+		recordAttempt(app, v1beta2.FailedState)
+	}
+
+	assert.Len(t, app.Status.Attempts, maxAttemptHistory)
+	assert.Equal(t, "submission-3", app.Status.Attempts[0].SubmissionID)
+	last := app.Status.Attempts[maxAttemptHistory-1]
+	assert.Equal(t, int32(maxAttemptHistory+2), last.ExecutionAttempt)
+	assert.Equal(t, "spark-12", last.SparkApplicationID)
+	assert.Equal(t, "driver failed again", last.ErrorMessage)
+	assert.Equal(t, int32(2), last.ExecutorFailures)
+}
+
 func TestSyncSparkApplication_SubmissionFailed(t *testing.T) {
 	os.Setenv(sparkHomeEnvVar, "/spark")
 	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
@@ -325,6 +715,95 @@ func TestSyncSparkApplication_SubmissionFailed(t *testing.T) {
 	assert.Equal(t, int32(2), updatedApp.Status.SubmissionAttempts)
 }
 
+func TestSyncSparkApplication_SubmissionKeepsStatus(t *testing.T) {
+	os.Setenv(sparkHomeEnvVar, "/spark")
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			RestartPolicy: v1beta2.RestartPolicy{
+				Type:                             v1beta2.OnFailure,
+				OnSubmissionFailureRetries:       int32ptr(3),
+				OnSubmissionFailureRetryInterval: int64ptr(100),
+			},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{
+				State:        v1beta2.FailedSubmissionState,
+				ErrorMessage: "spark-submit failed",
+			},
+			SubmissionAttempts:        1,
+			LastSubmissionAttemptTime: metav1.Time{Time: metav1.Now().Add(-100 * time.Second)},
+			RestartRuleRetries:        map[string]int32{"oom": 1},
+			EffectiveMemory: []v1beta2.AttemptMemory{
+				{ExecutionAttempt: 1, DriverMemory: stringptr("2g")},
+			},
+			Conditions: []metav1.Condition{
+				{Type: v1beta2.SparkApplicationResourcesCleanedUp, Status: metav1.ConditionTrue, Reason: "ResourcesDeleted"},
+			},
+			Attempts: []v1beta2.AttemptStatus{
+				{ExecutionAttempt: 1, State: v1beta2.FailedState},
+			},
+			Resources: []v1beta2.ResourceReference{
+				{APIVersion: "v1", Kind: "ConfigMap", Name: "foo-config"},
+			},
+		},
+	}
+
+	assertKept := func(t *testing.T, status v1beta2.SparkApplicationStatus) {
+		assert.Equal(t, app.Status.RestartRuleRetries, status.RestartRuleRetries)
+		assert.Equal(t, app.Status.EffectiveMemory, status.EffectiveMemory)
+		assert.Contains(t, status.Conditions, app.Status.Conditions[0])
+		assert.Equal(t, app.Status.Attempts, status.Attempts)
+		assert.Contains(t, status.Resources, app.Status.Resources[0])
+	}
+
+	submit := func(t *testing.T, app *v1beta2.SparkApplication, helper string) *v1beta2.SparkApplication {
+		ctrl, _ := newFakeController(app)
+		_, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		execCommand = func(command string, args ...string) *exec.Cmd {
+			cs := []string{"-test.run=" + helper, "--", command}
+			cs = append(cs, args...)
+			cmd := exec.Command(os.Args[0], cs...)
+			cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+			return cmd
+		}
+		assert.Nil(t, ctrl.syncSparkApplication("default/foo"))
+		updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return updatedApp
+	}
+
+	// A failed submission only updates the state and the submission attempt.
+	updatedApp := submit(t, app, "TestHelperProcessFailure")
+	assert.Equal(t, v1beta2.FailedSubmissionState, updatedApp.Status.AppState.State)
+	assert.Equal(t, int32(2), updatedApp.Status.SubmissionAttempts)
+	assertKept(t, updatedApp.Status)
+
+	// A successful submission also records the submission and the driver, and keeps the UI service information.
+	updatedApp.Status.LastSubmissionAttemptTime = metav1.Time{Time: metav1.Now().Add(-100 * time.Second)}
+	updatedApp.Status.NextRetryTime = updatedApp.Status.LastSubmissionAttemptTime
+	updatedApp = submit(t, updatedApp, "TestHelperProcessSuccess")
+	assert.Equal(t, v1beta2.SubmittedState, updatedApp.Status.AppState.State)
+	assert.Empty(t, updatedApp.Status.AppState.ErrorMessage)
+	assert.NotEmpty(t, updatedApp.Status.SubmissionID)
+	assert.Equal(t, int32(3), updatedApp.Status.SubmissionAttempts)
+	assert.Equal(t, int32(1), updatedApp.Status.ExecutionAttempts)
+	assert.Equal(t, getDriverPodName(app), updatedApp.Status.DriverInfo.PodName)
+	assert.NotEmpty(t, updatedApp.Status.DriverInfo.WebUIServiceName)
+	assertKept(t, updatedApp.Status)
+}
+
 func TestValidateDetectsNodeSelectorSuccessNoSelector(t *testing.T) {
 	ctrl, _ := newFakeController(nil)
 
@@ -417,6 +896,24 @@ func TestValidateDetectsNodeSelectorFailsAppAndPodLevel(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestValidateProgressDeadlineRequiresUIService(t *testing.T) {
+	ctrl, _ := newFakeController(nil)
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			ProgressDeadlineSeconds: int64ptr(3600),
+		},
+	}
+	assert.Nil(t, ctrl.validateSparkApplication(app))
+
+	ctrl.enableUIService = false
+	assert.NotNil(t, ctrl.validateSparkApplication(app))
+}
+
 func TestShouldRetry(t *testing.T) {
 	type testcase struct {
 		app         *v1beta2.SparkApplication
@@ -924,6 +1421,15 @@ func TestSyncSparkApplication_SubmissionSuccess(t *testing.T) {
 	}
 }
 
+// executorStatuses returns the status of executors in the given states.
+func executorStatuses(states map[string]v1beta2.ExecutorState) []v1beta2.ExecutorStatus {
+	var executors []v1beta2.ExecutorStatus
+	for name, state := range states {
+		executors = append(executors, v1beta2.ExecutorStatus{PodName: name, State: state})
+	}
+	return executors
+}
+
 func TestSyncSparkApplication_ExecutingState(t *testing.T) {
 	type testcase struct {
 		appName                 string
@@ -961,7 +1467,7 @@ func TestSyncSparkApplication_ExecutingState(t *testing.T) {
 			DriverInfo: v1beta2.DriverInfo{
 				PodName: driverPodName,
 			},
-			ExecutorState: map[string]v1beta2.ExecutorState{"exec-1": v1beta2.ExecutorRunningState},
+			Executors: []v1beta2.ExecutorStatus{{PodName: "exec-1", State: v1beta2.ExecutorRunningState}},
 		},
 	}
 
@@ -1459,6 +1965,7 @@ func TestSyncSparkApplication_ExecutingState(t *testing.T) {
 
 	testFn := func(test testcase, t *testing.T) {
 		app.Status.AppState.State = test.oldAppStatus
+		app.Status.Executors = executorStatuses(test.oldExecutorStatus)
 		app.Status.ExecutorState = test.oldExecutorStatus
 		app.Name = test.appName
 		app.Status.ExecutionAttempts = 1
@@ -1479,6 +1986,7 @@ func TestSyncSparkApplication_ExecutingState(t *testing.T) {
 		// Verify application and executor states.
 		updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
 		assert.Equal(t, test.expectedAppState, updatedApp.Status.AppState.State)
+		assert.Equal(t, test.expectedExecutorState, executorStates(updatedApp))
 		assert.Equal(t, test.expectedExecutorState, updatedApp.Status.ExecutorState)
 
 		// Validate error message if the driver pod failed.
@@ -1541,7 +2049,7 @@ func TestSyncSparkApplication_ApplicationExpired(t *testing.T) {
 			TerminationTime: metav1.Time{
 				Time: terminationTime,
 			},
-			ExecutorState: map[string]v1beta2.ExecutorState{"exec-1": v1beta2.ExecutorCompletedState},
+			Executors: []v1beta2.ExecutorStatus{{PodName: "exec-1", State: v1beta2.ExecutorCompletedState}},
 		},
 	}
 
@@ -1558,13 +2066,83 @@ func TestSyncSparkApplication_ApplicationExpired(t *testing.T) {
 }
 
 func TestIsNextRetryDue(t *testing.T) {
+	policy := v1beta2.RestartPolicy{}
 	// Failure cases.
-	assert.False(t, isNextRetryDue(nil, 3, metav1.Time{Time: metav1.Now().Add(-100 * time.Second)}))
-	assert.False(t, isNextRetryDue(int64ptr(5), 0, metav1.Time{Time: metav1.Now().Add(-100 * time.Second)}))
-	assert.False(t, isNextRetryDue(int64ptr(5), 3, metav1.Time{}))
+	assert.False(t, isNextRetryDue(nextRetryTime(policy, nil, 3, metav1.Time{Time: metav1.Now().Add(-100 * time.Second)})))
+	assert.False(t, isNextRetryDue(nextRetryTime(policy, int64ptr(5), 0, metav1.Time{Time: metav1.Now().Add(-100 * time.Second)})))
+	assert.False(t, isNextRetryDue(nextRetryTime(policy, int64ptr(5), 3, metav1.Time{})))
 	// Not enough time passed.
-	assert.False(t, isNextRetryDue(int64ptr(50), 3, metav1.Time{Time: metav1.Now().Add(-100 * time.Second)}))
-	assert.True(t, isNextRetryDue(int64ptr(50), 3, metav1.Time{Time: metav1.Now().Add(-151 * time.Second)}))
+	assert.False(t, isNextRetryDue(nextRetryTime(policy, int64ptr(50), 3, metav1.Time{Time: metav1.Now().Add(-100 * time.Second)})))
+	assert.True(t, isNextRetryDue(nextRetryTime(policy, int64ptr(50), 3, metav1.Time{Time: metav1.Now().Add(-151 * time.Second)})))
+}
+
+func TestRetryInterval(t *testing.T) {
+	type testcase struct {
+		policy   v1beta2.RestartPolicy
+		attempts int32
+		expected time.Duration
+	}
+	multiplier := "1.5"
+	testcases := []testcase{
+		{v1beta2.RestartPolicy{}, 3, 30 * time.Second},
+		{v1beta2.RestartPolicy{BackoffStrategy: v1beta2.LinearBackoff, MaxRetryInterval: int64ptr(25)}, 3, 25 * time.Second},
+		{v1beta2.RestartPolicy{BackoffStrategy: v1beta2.FixedBackoff}, 3, 10 * time.Second},
+		{v1beta2.RestartPolicy{BackoffStrategy: v1beta2.ExponentialBackoff}, 1, 10 * time.Second},
+		{v1beta2.RestartPolicy{BackoffStrategy: v1beta2.ExponentialBackoff}, 4, 80 * time.Second},
+		{v1beta2.RestartPolicy{BackoffStrategy: v1beta2.ExponentialBackoff, BackoffMultiplier: &multiplier}, 3, 22500 * time.Millisecond},
+		{v1beta2.RestartPolicy{BackoffStrategy: v1beta2.ExponentialBackoff, MaxRetryInterval: int64ptr(600)}, 20, 10 * time.Minute},
+		{v1beta2.RestartPolicy{BackoffStrategy: v1beta2.ExponentialBackoff}, 1000, maxRetryInterval},
+	}
+	for _, test := range testcases {
+		assert.Equal(t, test.expected, retryInterval(test.policy, 10, test.attempts))
+	}
+
+	// Jitter varies the interval within the given percentage.
+	lastEventTime := metav1.Now()
+	policy := v1beta2.RestartPolicy{BackoffStrategy: v1beta2.FixedBackoff, BackoffJitterPercent: int32ptr(20)}
+	for i := 0; i < 20; i++ {
+		next := nextRetryTime(policy, int64ptr(100), 1, lastEventTime)
+		assert.False(t, next.Before(&metav1.Time{Time: lastEventTime.Add(80 * time.Second)}))
+		assert.False(t, next.After(lastEventTime.Add(120*time.Second)))
+	}
+}
+
+func TestSyncSparkApplication_NextRetryTime(t *testing.T) {
+	lastSubmissionAttemptTime := metav1.NewTime(time.Now().Add(-15 * time.Second))
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: v1beta2.SparkApplicationSpec{
+			RestartPolicy: v1beta2.RestartPolicy{
+				Type:                             v1beta2.OnFailure,
+				OnSubmissionFailureRetries:       int32ptr(5),
+				OnSubmissionFailureRetryInterval: int64ptr(10),
+				BackoffStrategy:                  v1beta2.ExponentialBackoff,
+			},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{
+				State: v1beta2.FailedSubmissionState,
+			},
+			SubmissionAttempts:        2,
+			LastSubmissionAttemptTime: lastSubmissionAttemptTime,
+		},
+	}
+	ctrl, _ := newFakeController(app)
+	_, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With exponential backoff the third attempt is due 20 seconds after the second one.
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.FailedSubmissionState, updatedApp.Status.AppState.State)
+	assert.Equal(t, lastSubmissionAttemptTime.Add(20*time.Second).Unix(), updatedApp.Status.NextRetryTime.Unix())
 }
 
 func TestIngressWithSubpathAffectsSparkConfiguration(t *testing.T) {
@@ -1623,6 +2201,10 @@ func stringptr(s string) *string {
 	return &s
 }
 
+func boolptr(b bool) *bool {
+	return &b
+}
+
 func int32ptr(n int32) *int32 {
 	return &n
 }
@@ -0,0 +1,271 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+const (
+	threadDumpConfigMapNameSuffix = "thread-dump"
+	threadDumpKey                 = "driver-threads.txt"
+	// maxThreadDumpBytes keeps thread dumps below the size limit of ConfigMaps.
+	maxThreadDumpBytes = 900 * 1024
+)
+
+// enforceDeadlines kills the current run of the application if it has been active longer than ActiveDeadlineSeconds,
+// or if its driver or minimum number of executors were not scheduled within PendingTimeoutSeconds. Otherwise, it
+// enqueues the application to be checked again when the next deadline passes.
+func (c *Controller) enforceDeadlines(app *v1beta2.SparkApplication) error {
+	switch app.Status.AppState.State {
+	case v1beta2.SubmittedState, v1beta2.RunningState, v1beta2.UnknownState:
+	default:
+		return nil
+	}
+	if app.Status.LastSubmissionAttemptTime.IsZero() {
+		return nil
+	}
+	elapsed := time.Since(app.Status.LastSubmissionAttemptTime.Time)
+
+	var next time.Duration
+	if deadline := app.Spec.ActiveDeadlineSeconds; deadline != nil {
+		remaining := time.Duration(*deadline)*time.Second - elapsed
+		if remaining <= 0 {
+			return c.killRun(app, v1beta2.DeadlineExceededReason,
+				fmt.Sprintf("the application was active longer than its deadline of %ds", *deadline))
+		}
+		next = remaining
+	}
+	if timeout := app.Spec.PendingTimeoutSeconds; timeout != nil {
+		pending, message, err := c.getUnscheduledPods(app)
+		if err != nil {
+			return err
+		}
+		if pending != "" {
+			remaining := time.Duration(*timeout)*time.Second - elapsed
+			if remaining <= 0 {
+				errorMessage := fmt.Sprintf("%s within %ds", pending, *timeout)
+				if message != "" {
+					errorMessage = fmt.Sprintf("%s: %s", errorMessage, message)
+				}
+				return c.killRun(app, v1beta2.PendingTimeoutReason, errorMessage)
+			}
+			if next == 0 || remaining < next {
+				next = remaining
+			}
+		}
+	}
+	if next > 0 {
+		c.enqueueAfter(app, next)
+	}
+	return nil
+}
+
+// killRun deletes the driver of the current run of the application and fails the run for the given reason.
+func (c *Controller) killRun(app *v1beta2.SparkApplication, reason v1beta2.TerminationReason, message string) error {
+	glog.Infof("Killing SparkApplication %s/%s: %s", app.Namespace, app.Name, message)
+	if err := c.deleteSparkResources(app); err != nil {
+		return err
+	}
+	app.Status.AppState.State = v1beta2.FailingState
+	app.Status.AppState.ErrorMessage = message
+	app.Status.TerminationReason = reason
+	app.Status.TerminationTime = metav1.Now()
+	c.recorder.Eventf(app, apiv1.EventTypeWarning, "SparkApplication"+string(reason), "SparkApplication %s was killed: %s",
+		app.Name, message)
+	return nil
+}
+
+// enforceProgressDeadline records a warning event if the running application completed no job, stage or task within
+// ProgressDeadlineSeconds, and kills the run if the ProgressDeadlineAction says so. Otherwise, it enqueues the
+// application to be checked again when the deadline passes.
+func (c *Controller) enforceProgressDeadline(app *v1beta2.SparkApplication) error {
+	deadline := app.Spec.ProgressDeadlineSeconds
+	if deadline == nil || app.Status.AppState.State != v1beta2.RunningState || app.Status.Progress == nil ||
+		app.Status.Progress.LastProgressTime.IsZero() {
+		return nil
+	}
+	lastProgressTime := app.Status.Progress.LastProgressTime
+	remaining := time.Duration(*deadline)*time.Second - time.Since(lastProgressTime.Time)
+	if remaining > 0 {
+		setCondition(app, v1beta2.SparkApplicationProgressing, metav1.ConditionTrue, "NewProgress",
+			fmt.Sprintf("the application last completed jobs, stages or tasks at %s", lastProgressTime.UTC().Format(time.RFC3339)))
+		c.enqueueAfter(app, remaining)
+		return nil
+	}
+	if meta.IsStatusConditionFalse(app.Status.Conditions, v1beta2.SparkApplicationProgressing) {
+		// The application was already found hung, and made no progress since.
+		return nil
+	}
+
+	message := fmt.Sprintf("the application completed no job, stage or task since %s, exceeding its progress deadline of %ds",
+		lastProgressTime.UTC().Format(time.RFC3339), *deadline)
+	setCondition(app, v1beta2.SparkApplicationProgressing, metav1.ConditionFalse, string(v1beta2.ProgressDeadlineExceededReason), message)
+	c.recorder.Eventf(app, apiv1.EventTypeWarning, "SparkApplicationProgressDeadlineExceeded", "SparkApplication %s is hung: %s",
+		app.Name, message)
+	if app.Spec.ProgressDeadlineAction != v1beta2.ProgressDeadlineKill {
+		return nil
+	}
+
+	if configMapName, err := c.saveThreadDump(app); err != nil {
+		glog.Warningf("failed to save a thread dump of the driver of SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+	} else {
+		message = fmt.Sprintf("%s; a thread dump of the driver was saved in ConfigMap %s", message, configMapName)
+	}
+	return c.killRun(app, v1beta2.ProgressDeadlineExceededReason, message)
+}
+
+// saveThreadDump saves a thread dump of the driver of the application in a ConfigMap, replacing the dump of an earlier
+// run, and returns the name of the ConfigMap.
+func (c *Controller) saveThreadDump(app *v1beta2.SparkApplication) (string, error) {
+	dump, err := getDriverThreadDump(app.Status.DriverInfo.WebUIAddress, app.Status.SparkApplicationID)
+	if err != nil {
+		return "", err
+	}
+	if len(dump) > maxThreadDumpBytes {
+		dump = dump[:maxThreadDumpBytes] + "\n... truncated\n"
+	}
+
+	configMap := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            fmt.Sprintf("%s-%s", app.Name, threadDumpConfigMapNameSuffix),
+			Namespace:       app.Namespace,
+			Labels:          map[string]string{config.SparkAppNameLabel: app.Name},
+			OwnerReferences: []metav1.OwnerReference{*getOwnerReference(app)},
+		},
+		Data: map[string]string{threadDumpKey: dump},
+	}
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := c.kubeClient.CoreV1().ConfigMaps(app.Namespace).Get(context.TODO(), configMap.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			_, err = c.kubeClient.CoreV1().ConfigMaps(app.Namespace).Create(context.TODO(), configMap, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		existing.Data = configMap.Data
+		_, err = c.kubeClient.CoreV1().ConfigMaps(app.Namespace).Update(context.TODO(), existing, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return configMap.Name, nil
+}
+
+// exceededDeadline returns whether the failed run of the application was killed for exceeding a deadline.
+func exceededDeadline(app *v1beta2.SparkApplication) bool {
+	return app.Status.TerminationReason == v1beta2.DeadlineExceededReason ||
+		app.Status.TerminationReason == v1beta2.PendingTimeoutReason
+}
+
+// getUnscheduledPods describes the pods of the current run of the application that are still waiting to be
+// scheduled, if the driver is not scheduled yet or fewer than the minimum number of executors have ever been
+// scheduled, along with the message of the PodScheduled condition of one of them. It returns empty strings if the
+// run is not waiting for pods to be scheduled.
+func (c *Controller) getUnscheduledPods(app *v1beta2.SparkApplication) (string, string, error) {
+	driverPod, err := c.getDriverPod(app)
+	if err != nil {
+		return "", "", err
+	}
+	if driverPod == nil {
+		return "", "", nil
+	}
+	if !isPodScheduled(driverPod) {
+		return fmt.Sprintf("driver pod %s was not scheduled", driverPod.Name), podScheduledMessage(driverPod), nil
+	}
+	minExecutors := minimumExecutors(app)
+	if minExecutors == 0 || driverPod.Status.Phase != apiv1.PodRunning {
+		return "", "", nil
+	}
+
+	// Executors that got past pending were scheduled, even if their pods are gone since.
+	scheduled := make(map[string]bool)
+	for _, executor := range app.Status.Executors {
+		if executor.State != v1beta2.ExecutorPendingState {
+			scheduled[executor.PodName] = true
+		}
+	}
+	executorPods, err := c.getExecutorPods(app)
+	if err != nil {
+		return "", "", err
+	}
+	var message string
+	for _, pod := range executorPods {
+		if isPodScheduled(pod) {
+			scheduled[pod.Name] = true
+		} else if message == "" {
+			message = podScheduledMessage(pod)
+		}
+	}
+	scheduledCount := int32(len(scheduled))
+	if rolledUp := app.Status.RolledUpExecutors; rolledUp != nil {
+		scheduledCount += rolledUp.Completed + rolledUp.Failed
+	}
+	if scheduledCount >= minExecutors {
+		return "", "", nil
+	}
+	return fmt.Sprintf("%d of %d minimum executors were scheduled", scheduledCount, minExecutors), message, nil
+}
+
+// minimumExecutors returns the number of executors the application needs to have scheduled to make progress.
+func minimumExecutors(app *v1beta2.SparkApplication) int32 {
+	if dynamicAllocation := app.Spec.DynamicAllocation; dynamicAllocation != nil && dynamicAllocation.Enabled {
+		if dynamicAllocation.MinExecutors != nil {
+			return *dynamicAllocation.MinExecutors
+		}
+		return 0
+	}
+	return requestedExecutors(app)
+}
+
+// isPodScheduled returns whether the pod was bound to a node.
+func isPodScheduled(pod *apiv1.Pod) bool {
+	if pod.Spec.NodeName != "" {
+		return true
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == apiv1.PodScheduled {
+			return condition.Status == apiv1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// podScheduledMessage returns the message of the PodScheduled condition of the pod, which explains why the
+// scheduler could not place it.
+func podScheduledMessage(pod *apiv1.Pod) string {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == apiv1.PodScheduled {
+			return condition.Message
+		}
+	}
+	return ""
+}
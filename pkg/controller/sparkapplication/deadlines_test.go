@@ -0,0 +1,223 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func newDeadlineTestApp(state v1beta2.ApplicationStateType, submitted time.Duration) *v1beta2.SparkApplication {
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Executor: v1beta2.ExecutorSpec{Instances: int32ptr(2)},
+			RestartPolicy: v1beta2.RestartPolicy{
+				Type:                   v1beta2.OnFailure,
+				OnFailureRetries:       int32ptr(3),
+				OnFailureRetryInterval: int64ptr(10),
+			},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState:                  v1beta2.ApplicationState{State: state},
+			SparkApplicationID:        "spark-123",
+			SubmissionID:              "1",
+			ExecutionAttempts:         1,
+			LastSubmissionAttemptTime: metav1.NewTime(time.Now().Add(-submitted)),
+			DriverInfo:                v1beta2.DriverInfo{PodName: "foo-driver"},
+		},
+	}
+}
+
+func newDeadlineTestPod(name, role string, phase apiv1.PodPhase, scheduled *apiv1.PodCondition) *apiv1.Pod {
+	pod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels: map[string]string{
+				config.SparkRoleLabel:                role,
+				config.SparkAppNameLabel:             "foo",
+				config.SparkApplicationSelectorLabel: "spark-123",
+				config.SubmissionIDLabel:             "1",
+			},
+		},
+		Status: apiv1.PodStatus{Phase: phase},
+	}
+	if scheduled != nil {
+		pod.Status.Conditions = []apiv1.PodCondition{*scheduled}
+	} else {
+		pod.Spec.NodeName = "node1"
+	}
+	return pod
+}
+
+func syncDeadlineTestApp(t *testing.T, app *v1beta2.SparkApplication, pods ...*apiv1.Pod) (*v1beta2.SparkApplication, *Controller, chan string) {
+	ctrl, recorder := newFakeController(app, pods...)
+	_, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pod := range pods {
+		if _, err := ctrl.kubeClient.CoreV1().Pods(pod.Namespace).Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	return updatedApp, ctrl, recorder.Events
+}
+
+func TestSyncSparkApplication_ActiveDeadline(t *testing.T) {
+	driverPod := newDeadlineTestPod("foo-driver", config.SparkDriverRole, apiv1.PodRunning, nil)
+
+	// A run within its deadline is left alone.
+	app := newDeadlineTestApp(v1beta2.RunningState, time.Minute)
+	app.Spec.ActiveDeadlineSeconds = int64ptr(600)
+	updatedApp, _, _ := syncDeadlineTestApp(t, app, driverPod)
+	assert.Equal(t, v1beta2.RunningState, updatedApp.Status.AppState.State)
+
+	app = newDeadlineTestApp(v1beta2.RunningState, 20*time.Minute)
+	app.Spec.ActiveDeadlineSeconds = int64ptr(600)
+	updatedApp, ctrl, events := syncDeadlineTestApp(t, app, driverPod)
+	assert.Equal(t, v1beta2.FailingState, updatedApp.Status.AppState.State)
+	assert.Equal(t, v1beta2.DeadlineExceededReason, updatedApp.Status.TerminationReason)
+	assert.Equal(t, "the application was active longer than its deadline of 600s", updatedApp.Status.AppState.ErrorMessage)
+	assert.False(t, updatedApp.Status.TerminationTime.IsZero())
+	assert.Contains(t, <-events, "SparkApplicationDeadlineExceeded")
+	_, err := ctrl.kubeClient.CoreV1().Pods("default").Get(context.TODO(), "foo-driver", metav1.GetOptions{})
+	assert.Error(t, err)
+
+	// The run is not retried even though the RestartPolicy has retries left.
+	assert.False(t, shouldRetry(updatedApp))
+	updatedApp.Spec.RestartPolicy.Rules = []v1beta2.RestartRule{{
+		Action:  v1beta2.RestartRuleRetry,
+		Reasons: []v1beta2.TerminationReason{v1beta2.DeadlineExceededReason},
+	}}
+	assert.True(t, shouldRetry(updatedApp))
+}
+
+func TestSyncSparkApplication_PendingTimeout(t *testing.T) {
+	unschedulable := &apiv1.PodCondition{
+		Type:    apiv1.PodScheduled,
+		Status:  apiv1.ConditionFalse,
+		Reason:  apiv1.PodReasonUnschedulable,
+		Message: "0/3 nodes are available: 3 node(s) didn't match node selector.",
+	}
+
+	driverPod := newDeadlineTestPod("foo-driver", config.SparkDriverRole, apiv1.PodPending, unschedulable)
+	app := newDeadlineTestApp(v1beta2.SubmittedState, 10*time.Minute)
+	app.Spec.PendingTimeoutSeconds = int64ptr(300)
+	updatedApp, _, events := syncDeadlineTestApp(t, app, driverPod)
+	assert.Equal(t, v1beta2.FailingState, updatedApp.Status.AppState.State)
+	assert.Equal(t, v1beta2.PendingTimeoutReason, updatedApp.Status.TerminationReason)
+	assert.Equal(t, "driver pod foo-driver was not scheduled within 300s: 0/3 nodes are available: 3 node(s) didn't match node selector.",
+		updatedApp.Status.AppState.ErrorMessage)
+	assert.Contains(t, <-events, "SparkApplicationPendingTimeout")
+	assert.False(t, shouldRetry(updatedApp))
+
+	// Fewer than the minimum number of executors were scheduled.
+	driverPod = newDeadlineTestPod("foo-driver", config.SparkDriverRole, apiv1.PodRunning, nil)
+	executorPods := []*apiv1.Pod{
+		driverPod,
+		newDeadlineTestPod("exec-1", config.SparkExecutorRole, apiv1.PodRunning, nil),
+		newDeadlineTestPod("exec-2", config.SparkExecutorRole, apiv1.PodPending, unschedulable),
+	}
+	app = newDeadlineTestApp(v1beta2.RunningState, 10*time.Minute)
+	app.Spec.PendingTimeoutSeconds = int64ptr(300)
+	updatedApp, _, _ = syncDeadlineTestApp(t, app, executorPods...)
+	assert.Equal(t, v1beta2.FailingState, updatedApp.Status.AppState.State)
+	assert.Equal(t, "1 of 2 minimum executors were scheduled within 300s: 0/3 nodes are available: 3 node(s) didn't match node selector.",
+		updatedApp.Status.AppState.ErrorMessage)
+
+	// Executors that were scheduled once still count after their pods are gone.
+	app = newDeadlineTestApp(v1beta2.RunningState, 10*time.Minute)
+	app.Spec.PendingTimeoutSeconds = int64ptr(300)
+	app.Status.Executors = []v1beta2.ExecutorStatus{{PodName: "exec-0", State: v1beta2.ExecutorFailedState}}
+	updatedApp, _, _ = syncDeadlineTestApp(t, app, executorPods...)
+	assert.Equal(t, v1beta2.RunningState, updatedApp.Status.AppState.State)
+
+	// Pods still waiting within the timeout are left alone.
+	app = newDeadlineTestApp(v1beta2.SubmittedState, time.Minute)
+	app.Spec.PendingTimeoutSeconds = int64ptr(300)
+	updatedApp, _, _ = syncDeadlineTestApp(t, app, newDeadlineTestPod("foo-driver", config.SparkDriverRole, apiv1.PodPending, unschedulable))
+	assert.Equal(t, v1beta2.SubmittedState, updatedApp.Status.AppState.State)
+}
+
+func TestSyncSparkApplication_ProgressDeadline(t *testing.T) {
+	var requests int
+	server := newFakeDriverAPI("spark-123", &requests)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	driverPod := newDeadlineTestPod("foo-driver", config.SparkDriverRole, apiv1.PodRunning, nil)
+	newHungApp := func(lastProgress time.Duration) *v1beta2.SparkApplication {
+		app := newDeadlineTestApp(v1beta2.RunningState, 3*time.Hour)
+		app.Spec.ProgressDeadlineSeconds = int64ptr(3600)
+		app.Status.DriverInfo.WebUIAddress = serverURL.Host
+		// The driver reports as many completed jobs, stages and tasks as it did at the last poll.
+		app.Status.Progress = &v1beta2.ApplicationProgress{
+			LastUpdateTime:   metav1.NewTime(time.Now().Add(-time.Minute)),
+			LastProgressTime: metav1.NewTime(time.Now().Add(-lastProgress)),
+			CompletedJobs:    1,
+			CompletedStages:  2,
+			CompletedTasks:   16,
+		}
+		return app
+	}
+
+	// An application that made progress within its deadline is left alone.
+	updatedApp, _, _ := syncDeadlineTestApp(t, newHungApp(10*time.Minute), driverPod)
+	assert.Equal(t, v1beta2.RunningState, updatedApp.Status.AppState.State)
+	assert.True(t, meta.IsStatusConditionTrue(updatedApp.Status.Conditions, v1beta2.SparkApplicationProgressing))
+
+	// By default, a hung application is only reported.
+	updatedApp, _, events := syncDeadlineTestApp(t, newHungApp(2*time.Hour), driverPod)
+	assert.Equal(t, v1beta2.RunningState, updatedApp.Status.AppState.State)
+	condition := meta.FindStatusCondition(updatedApp.Status.Conditions, v1beta2.SparkApplicationProgressing)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, "ProgressDeadlineExceeded", condition.Reason)
+	}
+	assert.Contains(t, <-events, "SparkApplicationProgressDeadlineExceeded")
+
+	app := newHungApp(2 * time.Hour)
+	app.Spec.ProgressDeadlineAction = v1beta2.ProgressDeadlineKill
+	updatedApp, ctrl, events := syncDeadlineTestApp(t, app, driverPod)
+	assert.Equal(t, v1beta2.FailingState, updatedApp.Status.AppState.State)
+	assert.Equal(t, v1beta2.ProgressDeadlineExceededReason, updatedApp.Status.TerminationReason)
+	assert.Contains(t, updatedApp.Status.AppState.ErrorMessage, "a thread dump of the driver was saved in ConfigMap foo-thread-dump")
+	assert.Contains(t, <-events, "SparkApplicationProgressDeadlineExceeded")
+	configMap, err := ctrl.kubeClient.CoreV1().ConfigMaps("default").Get(context.TODO(), "foo-thread-dump", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, configMap.Data["driver-threads.txt"], "at org.example.Job.main(Job.scala:10)")
+	_, err = ctrl.kubeClient.CoreV1().Pods("default").Get(context.TODO(), "foo-driver", metav1.GetOptions{})
+	assert.Error(t, err)
+
+	// Unlike other deadlines, the run is retried according to the RestartPolicy.
+	assert.True(t, shouldRetry(updatedApp))
+}
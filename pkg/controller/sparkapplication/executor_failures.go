@@ -0,0 +1,75 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+// recordExecutorFailure records when an executor of the application failed, if the executors have a MaxFailures.
+func recordExecutorFailure(app *v1beta2.SparkApplication, terminatedState *apiv1.ContainerStateTerminated) {
+	if app.Spec.Executor.MaxFailures == nil {
+		return
+	}
+	failureTime := metav1.Now()
+	if terminatedState != nil && !terminatedState.FinishedAt.IsZero() {
+		failureTime = terminatedState.FinishedAt
+	}
+	app.Status.ExecutorFailureTimes = append(app.Status.ExecutorFailureTimes, failureTime)
+}
+
+// enforceExecutorFailureThreshold kills the current run of the application if more of its executors failed within
+// the failure window than MaxFailures allows. Failures that left the window are forgotten.
+func (c *Controller) enforceExecutorFailureThreshold(app *v1beta2.SparkApplication) error {
+	maxFailures := app.Spec.Executor.MaxFailures
+	if maxFailures == nil || app.Status.AppState.State != v1beta2.RunningState {
+		return nil
+	}
+
+	window := ""
+	if windowSeconds := app.Spec.Executor.FailureWindowSeconds; windowSeconds != nil {
+		windowStart := time.Now().Add(-time.Duration(*windowSeconds) * time.Second)
+		var failureTimes []metav1.Time
+		for _, failureTime := range app.Status.ExecutorFailureTimes {
+			if failureTime.Time.After(windowStart) {
+				failureTimes = append(failureTimes, failureTime)
+			}
+		}
+		app.Status.ExecutorFailureTimes = failureTimes
+		window = fmt.Sprintf(" within %ds", *windowSeconds)
+	}
+
+	allowed, err := intstr.GetValueFromIntOrPercent(maxFailures, int(requestedExecutors(app)), true)
+	if err != nil {
+		glog.Warningf("invalid executor maxFailures of SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+		return nil
+	}
+	failures := len(app.Status.ExecutorFailureTimes)
+	if failures <= allowed {
+		return nil
+	}
+	return c.killRun(app, v1beta2.ExecutorFailureThresholdExceededReason,
+		fmt.Sprintf("%d executors failed%s, more than the %d allowed", failures, window, allowed))
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestSyncSparkApplication_ExecutorFailureThreshold(t *testing.T) {
+	pods := []*apiv1.Pod{
+		newDeadlineTestPod("foo-driver", config.SparkDriverRole, apiv1.PodRunning, nil),
+		newDeadlineTestPod("exec-1", config.SparkExecutorRole, apiv1.PodFailed, nil),
+		newDeadlineTestPod("exec-2", config.SparkExecutorRole, apiv1.PodFailed, nil),
+	}
+
+	// Half of the 2 requested executors may fail.
+	app := newDeadlineTestApp(v1beta2.RunningState, time.Hour)
+	maxFailures := intstr.FromString("50%")
+	app.Spec.Executor.MaxFailures = &maxFailures
+	updatedApp, ctrl, events := syncDeadlineTestApp(t, app, pods...)
+	assert.Equal(t, v1beta2.FailingState, updatedApp.Status.AppState.State)
+	assert.Equal(t, v1beta2.ExecutorFailureThresholdExceededReason, updatedApp.Status.TerminationReason)
+	assert.Equal(t, "2 executors failed, more than the 1 allowed", updatedApp.Status.AppState.ErrorMessage)
+	assert.Len(t, updatedApp.Status.ExecutorFailureTimes, 2)
+	assert.Contains(t, <-events, "SparkExecutorFailed")
+	assert.Contains(t, <-events, "SparkExecutorFailed")
+	assert.Contains(t, <-events, "SparkApplicationExecutorFailureThresholdExceeded")
+	_, err := ctrl.kubeClient.CoreV1().Pods("default").Get(context.TODO(), "foo-driver", metav1.GetOptions{})
+	assert.Error(t, err)
+	assert.False(t, shouldRetry(updatedApp))
+
+	// Failures that left the window are forgotten.
+	app = newDeadlineTestApp(v1beta2.RunningState, time.Hour)
+	maxFailures = intstr.FromInt(2)
+	app.Spec.Executor.MaxFailures = &maxFailures
+	app.Spec.Executor.FailureWindowSeconds = int64ptr(600)
+	app.Status.ExecutorFailureTimes = []metav1.Time{metav1.NewTime(time.Now().Add(-time.Hour))}
+	updatedApp, _, _ = syncDeadlineTestApp(t, app, pods...)
+	assert.Equal(t, v1beta2.RunningState, updatedApp.Status.AppState.State)
+	assert.Len(t, updatedApp.Status.ExecutorFailureTimes, 2)
+
+	// Failures of executors whose failure was already recorded are not counted again.
+	updatedApp, _, _ = syncDeadlineTestApp(t, updatedApp, pods...)
+	assert.Equal(t, v1beta2.RunningState, updatedApp.Status.AppState.State)
+	assert.Len(t, updatedApp.Status.ExecutorFailureTimes, 2)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"sort"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// updateExecutorStatus updates the status of an executor from its pod.
+func updateExecutorStatus(executor *v1beta2.ExecutorStatus, pod *apiv1.Pod, state v1beta2.ExecutorState) {
+	executor.ExecutorID = pod.Labels[config.SparkExecutorIDLabel]
+	executor.NodeName = pod.Spec.NodeName
+	executor.State = state
+	if pod.Status.StartTime != nil {
+		executor.StartTime = *pod.Status.StartTime
+	}
+	if containerStatus := getExecutorContainerStatus(pod.Status); containerStatus != nil {
+		executor.Restarts = containerStatus.RestartCount
+	}
+	if !isExecutorTerminated(state) {
+		return
+	}
+
+	if terminatedState := getExecutorContainerTerminatedState(pod.Status); terminatedState != nil {
+		exitCode := terminatedState.ExitCode
+		executor.ExitCode = &exitCode
+		executor.Reason = terminatedState.Reason
+		executor.EndTime = terminatedState.FinishedAt
+	} else if executor.Reason == "" {
+		executor.Reason = pod.Status.Reason
+	}
+	if executor.EndTime.IsZero() {
+		executor.EndTime = metav1.Now()
+	}
+}
+
+// rollUpExecutors removes the terminated executors beyond the limit from the status of the application, starting with
+// those that terminated first, and counts them in RolledUpExecutors instead. Active executors are always kept. A limit
+// of zero keeps all executors.
+func rollUpExecutors(app *v1beta2.SparkApplication, limit int) {
+	excess := len(app.Status.Executors) - limit
+	if limit <= 0 || excess <= 0 {
+		return
+	}
+
+	var terminated []int
+	for i, executor := range app.Status.Executors {
+		if isExecutorTerminated(executor.State) {
+			terminated = append(terminated, i)
+		}
+	}
+	sort.SliceStable(terminated, func(i, j int) bool {
+		return app.Status.Executors[terminated[i]].EndTime.Time.Before(app.Status.Executors[terminated[j]].EndTime.Time)
+	})
+	if excess > len(terminated) {
+		excess = len(terminated)
+	}
+	if excess == 0 {
+		return
+	}
+
+	rolledUp := app.Status.RolledUpExecutors
+	if rolledUp == nil {
+		rolledUp = &v1beta2.RolledUpExecutors{}
+		app.Status.RolledUpExecutors = rolledUp
+	}
+	removed := make(map[int]bool)
+	for _, i := range terminated[:excess] {
+		executor := app.Status.Executors[i]
+		if executor.State == v1beta2.ExecutorFailedState {
+			rolledUp.Failed++
+		} else {
+			rolledUp.Completed++
+		}
+		if executor.EndTime.Time.After(rolledUp.LastEndTime.Time) {
+			rolledUp.LastEndTime = executor.EndTime
+		}
+		removed[i] = true
+	}
+	executors := make([]v1beta2.ExecutorStatus, 0, len(app.Status.Executors)-excess)
+	for i, executor := range app.Status.Executors {
+		if !removed[i] {
+			executors = append(executors, executor)
+		}
+	}
+	app.Status.Executors = executors
+}
+
+// isExecutorRolledUp returns whether a terminated executor pod that is not listed in the status of the application
+// was counted in RolledUpExecutors already. Executors are rolled up after they terminated, so any executor pod created
+// before the last executor rolled up terminated is assumed to be counted.
+func isExecutorRolledUp(app *v1beta2.SparkApplication, pod *apiv1.Pod) bool {
+	rolledUp := app.Status.RolledUpExecutors
+	return rolledUp != nil && !pod.CreationTimestamp.Time.After(rolledUp.LastEndTime.Time)
+}
+
+// countExecutors returns the number of executors of the current run in the given state, including those rolled up.
+func countExecutors(app *v1beta2.SparkApplication, state v1beta2.ExecutorState) int32 {
+	var count int32
+	for _, executor := range app.Status.Executors {
+		if executor.State == state {
+			count++
+		}
+	}
+	if rolledUp := app.Status.RolledUpExecutors; rolledUp != nil {
+		switch state {
+		case v1beta2.ExecutorCompletedState:
+			count += rolledUp.Completed
+		case v1beta2.ExecutorFailedState:
+			count += rolledUp.Failed
+		}
+	}
+	return count
+}
+
+// executorStates returns the states of the executors listed in the status of the application by pod name.
+func executorStates(app *v1beta2.SparkApplication) map[string]v1beta2.ExecutorState {
+	if len(app.Status.Executors) == 0 {
+		return nil
+	}
+	states := make(map[string]v1beta2.ExecutorState)
+	for _, executor := range app.Status.Executors {
+		states[executor.PodName] = executor.State
+	}
+	return states
+}
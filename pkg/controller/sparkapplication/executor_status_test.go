@@ -0,0 +1,150 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestRollUpExecutors(t *testing.T) {
+	now := time.Now()
+	app := &v1beta2.SparkApplication{
+		Status: v1beta2.SparkApplicationStatus{
+			Executors: []v1beta2.ExecutorStatus{
+				{PodName: "exec-1", State: v1beta2.ExecutorFailedState, EndTime: metav1.NewTime(now.Add(-time.Minute))},
+				{PodName: "exec-2", State: v1beta2.ExecutorRunningState},
+				{PodName: "exec-3", State: v1beta2.ExecutorCompletedState, EndTime: metav1.NewTime(now.Add(-time.Hour))},
+				{PodName: "exec-4", State: v1beta2.ExecutorPendingState},
+			},
+		},
+	}
+
+	// A limit of zero keeps all executors.
+	rollUpExecutors(app, 0)
+	assert.Len(t, app.Status.Executors, 4)
+	assert.Nil(t, app.Status.RolledUpExecutors)
+
+	// The executors that terminated first are rolled up first.
+	rollUpExecutors(app, 3)
+	assert.Equal(t, []string{"exec-1", "exec-2", "exec-4"}, executorPodNames(app))
+	assert.Equal(t, &v1beta2.RolledUpExecutors{Completed: 1, LastEndTime: metav1.NewTime(now.Add(-time.Hour))},
+		app.Status.RolledUpExecutors)
+	assert.Equal(t, int32(1), countExecutors(app, v1beta2.ExecutorCompletedState))
+
+	// Active executors are kept beyond the limit.
+	rollUpExecutors(app, 1)
+	assert.Equal(t, []string{"exec-2", "exec-4"}, executorPodNames(app))
+	assert.Equal(t, &v1beta2.RolledUpExecutors{Completed: 1, Failed: 1, LastEndTime: metav1.NewTime(now.Add(-time.Minute))},
+		app.Status.RolledUpExecutors)
+	assert.Equal(t, int32(1), countExecutors(app, v1beta2.ExecutorFailedState))
+}
+
+func executorPodNames(app *v1beta2.SparkApplication) []string {
+	var names []string
+	for _, executor := range app.Status.Executors {
+		names = append(names, executor.PodName)
+	}
+	return names
+}
+
+// eventReason returns the reason of an event recorded by a FakeRecorder.
+func eventReason(event string) string {
+	return strings.Fields(event)[1]
+}
+
+func TestSyncSparkApplication_ExecutorStatus(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	sync := func(app *v1beta2.SparkApplication, pods ...*apiv1.Pod) (*v1beta2.SparkApplication, chan string) {
+		ctrl, recorder := newFakeController(app, pods...)
+		ctrl.executorStatusLimit = 2
+		_, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Nil(t, ctrl.syncSparkApplication("default/foo"))
+		updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		return updatedApp, recorder.Events
+	}
+
+	// The pod of exec-1 is kept after it completed.
+	completedPod := newDeadlineTestPod("exec-1", config.SparkExecutorRole, apiv1.PodSucceeded, nil)
+	completedPod.CreationTimestamp = metav1.NewTime(now.Add(-3 * time.Hour))
+	oomKilledPod := newDeadlineTestPod("exec-3", config.SparkExecutorRole, apiv1.PodFailed, nil)
+	oomKilledPod.Labels[config.SparkExecutorIDLabel] = "3"
+	oomKilledPod.Status.StartTime = &metav1.Time{Time: now.Add(-time.Minute)}
+	oomKilledPod.Status.ContainerStatuses = []apiv1.ContainerStatus{{
+		Name:         config.SparkExecutorContainerName,
+		RestartCount: 1,
+		State: apiv1.ContainerState{Terminated: &apiv1.ContainerStateTerminated{
+			ExitCode:   137,
+			Reason:     oomKilledReason,
+			FinishedAt: metav1.NewTime(now),
+		}},
+	}}
+	pods := []*apiv1.Pod{
+		newDeadlineTestPod("foo-driver", config.SparkDriverRole, apiv1.PodRunning, nil),
+		completedPod,
+		oomKilledPod,
+		newDeadlineTestPod("exec-4", config.SparkExecutorRole, apiv1.PodRunning, nil),
+	}
+
+	app := newDeadlineTestApp(v1beta2.RunningState, time.Hour)
+	app.Status.Executors = []v1beta2.ExecutorStatus{
+		{PodName: "exec-1", State: v1beta2.ExecutorCompletedState, EndTime: metav1.NewTime(now.Add(-2 * time.Hour))},
+		{PodName: "exec-2", State: v1beta2.ExecutorFailedState, EndTime: metav1.NewTime(now.Add(-time.Hour))},
+		{PodName: "exec-3", State: v1beta2.ExecutorRunningState},
+	}
+	updatedApp, events := sync(app, pods...)
+	assert.Equal(t, []string{"exec-2", "exec-3", "exec-4"}, executorPodNames(updatedApp))
+	assert.Equal(t, &v1beta2.RolledUpExecutors{Completed: 1, LastEndTime: metav1.NewTime(now.Add(-2 * time.Hour))},
+		updatedApp.Status.RolledUpExecutors)
+	assert.Equal(t, v1beta2.ExecutorStatus{
+		PodName:    "exec-3",
+		ExecutorID: "3",
+		NodeName:   "node1",
+		State:      v1beta2.ExecutorFailedState,
+		StartTime:  metav1.NewTime(now.Add(-time.Minute)),
+		EndTime:    metav1.NewTime(now),
+		ExitCode:   int32ptr(137),
+		Reason:     oomKilledReason,
+		Restarts:   1,
+	}, updatedApp.Status.Executors[1])
+	assert.Equal(t, v1beta2.ExecutorRunningState, updatedApp.Status.Executors[2].State)
+	// The rolled up exec-1 is not found again. Pods are listed in no particular order.
+	assert.ElementsMatch(t, []string{"SparkExecutorFailed", "SparkExecutorRunning"},
+		[]string{eventReason(<-events), eventReason(<-events)})
+	assert.Empty(t, events)
+
+	// Executors the last sync found terminated are rolled up by the next.
+	updatedApp, events = sync(updatedApp, pods...)
+	assert.Equal(t, []string{"exec-3", "exec-4"}, executorPodNames(updatedApp))
+	assert.Equal(t, &v1beta2.RolledUpExecutors{Completed: 1, Failed: 1, LastEndTime: metav1.NewTime(now.Add(-time.Hour))},
+		updatedApp.Status.RolledUpExecutors)
+	assert.Equal(t, int32(2), countExecutors(updatedApp, v1beta2.ExecutorFailedState))
+	assert.Empty(t, events)
+}
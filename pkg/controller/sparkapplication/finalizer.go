@@ -0,0 +1,140 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/glog"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// cleanupCheckInterval is how often the controller checks whether the resources of a deleted application are gone.
+const cleanupCheckInterval = 5 * time.Second
+
+// hasFinalizer returns whether the application carries the finalizer of the controller.
+func hasFinalizer(app *v1beta2.SparkApplication) bool {
+	for _, finalizer := range app.Finalizers {
+		if finalizer == config.SparkApplicationFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// isForceDeleted returns whether the application is annotated to be deleted without waiting for its resources.
+func isForceDeleted(app *v1beta2.SparkApplication) bool {
+	return app.Annotations[config.ForceDeleteAnnotation] == "true"
+}
+
+// addFinalizer puts the finalizer of the controller on the application, and returns the updated application.
+func (c *Controller) addFinalizer(app *v1beta2.SparkApplication) (*v1beta2.SparkApplication, error) {
+	return c.updateFinalizers(app, func(finalizers []string) []string {
+		for _, finalizer := range finalizers {
+			if finalizer == config.SparkApplicationFinalizer {
+				return finalizers
+			}
+		}
+		return append(finalizers, config.SparkApplicationFinalizer)
+	})
+}
+
+// removeFinalizer removes the finalizer of the controller from the application, which lets it go away.
+func (c *Controller) removeFinalizer(app *v1beta2.SparkApplication) error {
+	_, err := c.updateFinalizers(app, func(finalizers []string) []string {
+		var remaining []string
+		for _, finalizer := range finalizers {
+			if finalizer != config.SparkApplicationFinalizer {
+				remaining = append(remaining, finalizer)
+			}
+		}
+		return remaining
+	})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (c *Controller) updateFinalizers(
+	app *v1beta2.SparkApplication,
+	updateFunc func(finalizers []string) []string) (*v1beta2.SparkApplication, error) {
+	toUpdate := app.DeepCopy()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		toUpdate.Finalizers = updateFunc(toUpdate.Finalizers)
+		updated, err := c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Update(context.TODO(), toUpdate, metav1.UpdateOptions{})
+		if err == nil {
+			toUpdate = updated
+			return nil
+		}
+		if !errors.IsConflict(err) {
+			return err
+		}
+		// There was a conflict updating the SparkApplication, retry with the latest version from the API server.
+		latest, getErr := c.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+		if getErr != nil {
+			return getErr
+		}
+		toUpdate = latest
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toUpdate, nil
+}
+
+// finalizeSparkApplication deletes the resources created for an application being deleted, and removes the
+// finalizer of the controller once all of them are confirmed gone, or right away if the application is annotated
+// to be force-deleted.
+func (c *Controller) finalizeSparkApplication(app *v1beta2.SparkApplication) error {
+	if !hasFinalizer(app) {
+		// The application predates the finalizer, delete its resources on a best effort basis.
+		if err := c.deleteSparkResources(app); err != nil {
+			glog.Errorf("failed to delete resources associated with deleted SparkApplication %s/%s: %v", app.Namespace, app.Name, err)
+		}
+		return nil
+	}
+
+	if isForceDeleted(app) {
+		glog.Warningf("Removing the finalizer of SparkApplication %s/%s without waiting for its resources to be deleted", app.Namespace, app.Name)
+		c.recorder.Eventf(app, apiv1.EventTypeWarning, "SparkApplicationForceDeleted",
+			"SparkApplication %s was force-deleted, its resources may be left behind", app.Name)
+		return c.removeFinalizer(app)
+	}
+
+	if err := c.deleteSparkResources(app); err != nil {
+		c.recorder.Eventf(app, apiv1.EventTypeWarning, "SparkApplicationCleanupFailed",
+			"failed to delete resources of SparkApplication %s: %v", app.Name, err)
+		return err
+	}
+	if !c.validateSparkResourceDeletion(app) {
+		glog.V(2).Infof("Waiting for the resources of deleted SparkApplication %s/%s to go away", app.Namespace, app.Name)
+		c.enqueueAfter(app, cleanupCheckInterval)
+		return nil
+	}
+
+	glog.Infof("Resources of deleted SparkApplication %s/%s were deleted, removing its finalizer", app.Namespace, app.Name)
+	return c.removeFinalizer(app)
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+	kubetesting "k8s.io/client-go/testing"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestSyncSparkApplication_AddFinalizer(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState: v1beta2.ApplicationState{State: v1beta2.CompletedState},
+		},
+	}
+	ctrl, _ := newFakeController(app)
+	_, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{config.SparkApplicationFinalizer}, updatedApp.Finalizers)
+}
+
+func TestSyncSparkApplication_Finalize(t *testing.T) {
+	newDeletedApp := func(annotations map[string]string) *v1beta2.SparkApplication {
+		now := metav1.Now()
+		return &v1beta2.SparkApplication{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "foo",
+				Namespace:         "default",
+				Annotations:       annotations,
+				DeletionTimestamp: &now,
+				Finalizers:        []string{"example.com/other", config.SparkApplicationFinalizer},
+			},
+			Spec: v1beta2.SparkApplicationSpec{
+				Monitoring: &v1beta2.MonitoringSpec{Prometheus: &v1beta2.PrometheusSpec{}},
+			},
+			Status: v1beta2.SparkApplicationStatus{
+				AppState: v1beta2.ApplicationState{State: v1beta2.RunningState},
+				DriverInfo: v1beta2.DriverInfo{
+					PodName:          "foo-driver",
+					WebUIServiceName: "foo-ui-svc",
+				},
+			},
+		}
+	}
+	sync := func(app *v1beta2.SparkApplication, stuckPods bool) (*Controller, *v1beta2.SparkApplication) {
+		ctrl, _ := newFakeController(app)
+		_, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		kubeClient := ctrl.kubeClient.(*kubeclientfake.Clientset)
+		kubeClient.CoreV1().Pods(app.Namespace).Create(context.TODO(), &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo-driver", Namespace: "default"}}, metav1.CreateOptions{})
+		kubeClient.CoreV1().Services(app.Namespace).Create(context.TODO(), &apiv1.Service{ObjectMeta: metav1.ObjectMeta{Name: "foo-ui-svc", Namespace: "default"}}, metav1.CreateOptions{})
+		kubeClient.CoreV1().ConfigMaps(app.Namespace).Create(context.TODO(), &apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: config.GetPrometheusConfigMapName(app), Namespace: "default"}}, metav1.CreateOptions{})
+		if stuckPods {
+			// Pods are never deleted, e.g. because the kubelet of their node is down.
+			kubeClient.PrependReactor("delete", "pods", func(action kubetesting.Action) (bool, runtime.Object, error) {
+				return true, nil, nil
+			})
+		}
+
+		err = ctrl.syncSparkApplication("default/foo")
+		assert.Nil(t, err)
+		updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		return ctrl, updatedApp
+	}
+
+	// The finalizer is removed once every resource of the application is gone.
+	ctrl, updatedApp := sync(newDeletedApp(nil), false)
+	assert.Equal(t, []string{"example.com/other"}, updatedApp.Finalizers)
+	_, err := ctrl.kubeClient.CoreV1().Pods("default").Get(context.TODO(), "foo-driver", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	_, err = ctrl.kubeClient.CoreV1().Services("default").Get(context.TODO(), "foo-ui-svc", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	_, err = ctrl.kubeClient.CoreV1().ConfigMaps("default").Get(context.TODO(), "foo-prom-conf", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+
+	// The finalizer is kept while the driver pod is still there.
+	_, updatedApp = sync(newDeletedApp(nil), true)
+	assert.Equal(t, []string{"example.com/other", config.SparkApplicationFinalizer}, updatedApp.Finalizers)
+
+	// Force-deleted applications don't wait for their resources.
+	_, updatedApp = sync(newDeletedApp(map[string]string{config.ForceDeleteAnnotation: "true"}), true)
+	assert.Equal(t, []string{"example.com/other"}, updatedApp.Finalizers)
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+const (
+	defaultMemoryEscalationFactor = 1.5
+	// defaultSparkMemory is the memory Spark gives the driver and the executors if none is configured.
+	defaultSparkMemory = "1g"
+	oomKilledReason    = "OOMKilled"
+)
+
+// getAttemptMemory returns the memory of the driver and the executors for the next attempt to run the application,
+// recording it in the status if the RestartPolicy escalates memory. It returns nil if memory is not escalated.
+func getAttemptMemory(app *v1beta2.SparkApplication) *v1beta2.AttemptMemory {
+	if app.Spec.RestartPolicy.MemoryEscalation == nil {
+		return nil
+	}
+	attempt := app.Status.ExecutionAttempts + 1
+	if n := len(app.Status.EffectiveMemory); n > 0 && app.Status.EffectiveMemory[n-1].ExecutionAttempt == attempt {
+		return &app.Status.EffectiveMemory[n-1]
+	}
+	memory := memoryForAttempt(app, attempt)
+	recordAttemptMemory(app, memory)
+	return &memory
+}
+
+// recordAttemptMemory records the memory of an attempt in the status, keeping only the most recent attempts.
+func recordAttemptMemory(app *v1beta2.SparkApplication, memory v1beta2.AttemptMemory) {
+	app.Status.EffectiveMemory = append(app.Status.EffectiveMemory, memory)
+	if len(app.Status.EffectiveMemory) > maxAttemptHistory {
+		app.Status.EffectiveMemory = app.Status.EffectiveMemory[len(app.Status.EffectiveMemory)-maxAttemptHistory:]
+	}
+}
+
+// memoryForAttempt returns the memory of the last recorded attempt, or else the memory configured in the spec, for
+// the given attempt.
+func memoryForAttempt(app *v1beta2.SparkApplication, attempt int32) v1beta2.AttemptMemory {
+	if n := len(app.Status.EffectiveMemory); n > 0 {
+		memory := *app.Status.EffectiveMemory[n-1].DeepCopy()
+		memory.ExecutionAttempt = attempt
+		return memory
+	}
+	return v1beta2.AttemptMemory{
+		ExecutionAttempt:       attempt,
+		DriverMemory:           configuredMemory(app, app.Spec.Driver.Memory, "spark.driver.memory", defaultSparkMemory),
+		DriverMemoryOverhead:   configuredMemory(app, app.Spec.Driver.MemoryOverhead, "spark.driver.memoryOverhead", ""),
+		ExecutorMemory:         configuredMemory(app, app.Spec.Executor.Memory, "spark.executor.memory", defaultSparkMemory),
+		ExecutorMemoryOverhead: configuredMemory(app, app.Spec.Executor.MemoryOverhead, "spark.executor.memoryOverhead", ""),
+	}
+}
+
+// configuredMemory returns the memory set by the given field of the spec, or else by the given Spark configuration
+// property, or else the given default.
+func configuredMemory(app *v1beta2.SparkApplication, field *string, key string, defaultValue string) *string {
+	if field != nil {
+		value := *field
+		return &value
+	}
+	if value, ok := app.Spec.SparkConf[key]; ok {
+		return &value
+	}
+	if defaultValue == "" {
+		return nil
+	}
+	return &defaultValue
+}
+
+// withAttemptMemory returns a copy of the application using the given memory for the driver and the executors.
+func withAttemptMemory(app *v1beta2.SparkApplication, memory *v1beta2.AttemptMemory) *v1beta2.SparkApplication {
+	if memory == nil {
+		return app
+	}
+	appCopy := app.DeepCopy()
+	appCopy.Spec.Driver.Memory = memory.DriverMemory
+	appCopy.Spec.Driver.MemoryOverhead = memory.DriverMemoryOverhead
+	appCopy.Spec.Executor.Memory = memory.ExecutorMemory
+	appCopy.Spec.Executor.MemoryOverhead = memory.ExecutorMemoryOverhead
+	return appCopy
+}
+
+// escalateMemory records more memory for the next attempt to run a failed application if its driver or executors
+// were OOMKilled and the RestartPolicy escalates memory. It returns the memory recorded, or nil if memory is not
+// escalated.
+func escalateMemory(app *v1beta2.SparkApplication) (*v1beta2.AttemptMemory, error) {
+	escalation := app.Spec.RestartPolicy.MemoryEscalation
+	driverOOMKilled := app.Status.TerminationReason == v1beta2.OOMKilledReason
+	executorOOMKilled := app.Status.ExecutorOOMKills > 0
+	if escalation == nil || (!driverOOMKilled && !executorOOMKilled) {
+		return nil, nil
+	}
+	maxMiB, err := parseSparkMemoryMiB(escalation.MaxMemory)
+	if err != nil {
+		return nil, err
+	}
+	factor := defaultMemoryEscalationFactor
+	if escalation.Factor != nil {
+		if f, err := strconv.ParseFloat(*escalation.Factor, 64); err == nil && f >= 1 {
+			factor = f
+		}
+	}
+
+	// The memory of the failed attempt is the last one recorded, unless the attempt was submitted before memory
+	// escalation was enabled.
+	next := memoryForAttempt(app, app.Status.ExecutionAttempts+1)
+	if driverOOMKilled {
+		if next.DriverMemory, next.DriverMemoryOverhead, err = escalate(next.DriverMemory, next.DriverMemoryOverhead, factor, maxMiB); err != nil {
+			return nil, err
+		}
+	}
+	if executorOOMKilled {
+		if next.ExecutorMemory, next.ExecutorMemoryOverhead, err = escalate(next.ExecutorMemory, next.ExecutorMemoryOverhead, factor, maxMiB); err != nil {
+			return nil, err
+		}
+	}
+	recordAttemptMemory(app, next)
+	return &next, nil
+}
+
+// escalate multiplies the memory by the factor, rounded up to the next MiB, up to the maximum, and the memory
+// overhead, if set, by the same ratio as the memory.
+func escalate(memory *string, overhead *string, factor float64, maxMiB int64) (*string, *string, error) {
+	memoryMiB, err := parseSparkMemoryMiB(*memory)
+	if err != nil {
+		return nil, nil, err
+	}
+	escalatedMiB := int64(math.Ceil(float64(memoryMiB) * factor))
+	if escalatedMiB > maxMiB {
+		escalatedMiB = maxMiB
+	}
+	if escalatedMiB <= memoryMiB {
+		return memory, overhead, nil
+	}
+	escalatedMemory := fmt.Sprintf("%dm", escalatedMiB)
+	if overhead == nil {
+		return &escalatedMemory, nil, nil
+	}
+	overheadMiB, err := parseSparkMemoryMiB(*overhead)
+	if err != nil {
+		return nil, nil, err
+	}
+	escalatedOverhead := fmt.Sprintf("%dm", overheadMiB*escalatedMiB/memoryMiB)
+	return &escalatedMemory, &escalatedOverhead, nil
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+func newMemoryEscalationTestApp() *v1beta2.SparkApplication {
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{Memory: stringptr("512m"), MemoryOverhead: stringptr("256m")},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{Memory: stringptr("4g")},
+			},
+			RestartPolicy: v1beta2.RestartPolicy{
+				Type:                   v1beta2.OnFailure,
+				OnFailureRetries:       int32ptr(3),
+				OnFailureRetryInterval: int64ptr(10),
+				MemoryEscalation:       &v1beta2.MemoryEscalation{Factor: stringptr("2"), MaxMemory: "6g"},
+			},
+		},
+	}
+}
+
+func TestEscalateMemory(t *testing.T) {
+	app := newMemoryEscalationTestApp()
+	app.Spec.SparkConf = map[string]string{"spark.executor.memoryOverhead": "1g"}
+
+	// The first attempt runs with the memory of the spec.
+	memory := getAttemptMemory(app)
+	assert.Equal(t, v1beta2.AttemptMemory{
+		ExecutionAttempt:       1,
+		DriverMemory:           stringptr("512m"),
+		DriverMemoryOverhead:   stringptr("256m"),
+		ExecutorMemory:         stringptr("4g"),
+		ExecutorMemoryOverhead: stringptr("1g"),
+	}, *memory)
+	assert.Equal(t, memory, getAttemptMemory(app))
+	assert.Len(t, app.Status.EffectiveMemory, 1)
+	app.Status.ExecutionAttempts = 1
+
+	// Runs that fail for other reasons are rerun with the same memory.
+	memory, err := escalateMemory(app)
+	assert.NoError(t, err)
+	assert.Nil(t, memory)
+	assert.Equal(t, "512m", *getAttemptMemory(app).DriverMemory)
+	app.Status.ExecutionAttempts = 2
+
+	app.Status.TerminationReason = v1beta2.OOMKilledReason
+	memory, err = escalateMemory(app)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), memory.ExecutionAttempt)
+	assert.Equal(t, "1024m", *memory.DriverMemory)
+	assert.Equal(t, "512m", *memory.DriverMemoryOverhead)
+	assert.Equal(t, "4g", *memory.ExecutorMemory)
+	assert.Equal(t, memory, getAttemptMemory(app))
+	app.Status.ExecutionAttempts = 3
+
+	// Executor memory is capped at the maximum and the overhead grows by the same ratio.
+	app.Status.TerminationReason = ""
+	app.Status.ExecutorOOMKills = 2
+	memory, err = escalateMemory(app)
+	assert.NoError(t, err)
+	assert.Equal(t, "1024m", *memory.DriverMemory)
+	assert.Equal(t, "6144m", *memory.ExecutorMemory)
+	assert.Equal(t, "1536m", *memory.ExecutorMemoryOverhead)
+	assert.Len(t, app.Status.EffectiveMemory, 4)
+
+	// The spec is left unchanged.
+	assert.Equal(t, "512m", *app.Spec.Driver.Memory)
+	assert.Equal(t, "4g", *app.Spec.Executor.Memory)
+	submittedApp := withAttemptMemory(app, memory)
+	assert.Equal(t, "6144m", *submittedApp.Spec.Executor.Memory)
+	assert.Equal(t, "4g", *app.Spec.Executor.Memory)
+
+	// Only the memory of the most recent attempts is kept.
+	for attempt := int32(4); attempt <= maxAttemptHistory+5; attempt++ {
+		app.Status.ExecutionAttempts = attempt
+		getAttemptMemory(app)
+	}
+	assert.Len(t, app.Status.EffectiveMemory, maxAttemptHistory)
+	last := app.Status.EffectiveMemory[maxAttemptHistory-1]
+	assert.Equal(t, int32(maxAttemptHistory+6), last.ExecutionAttempt)
+	assert.Equal(t, "6144m", *last.ExecutorMemory)
+
+	app.Spec.RestartPolicy.MemoryEscalation = nil
+	assert.Nil(t, getAttemptMemory(app))
+	assert.Equal(t, app, withAttemptMemory(app, nil))
+}
+
+func TestEscalateSubMiBMemory(t *testing.T) {
+	// Memory below 1MiB counts as 1MiB and still grows by at least 1MiB.
+	memory, overhead, err := escalate(stringptr("512k"), nil, 1.5, 1024)
+	assert.NoError(t, err)
+	assert.Equal(t, "2m", *memory)
+	assert.Nil(t, overhead)
+}
+
+func TestSyncSparkApplication_MemoryEscalation(t *testing.T) {
+	os.Setenv(sparkHomeEnvVar, "/spark")
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+
+	app := newMemoryEscalationTestApp()
+	app.Status = v1beta2.SparkApplicationStatus{
+		AppState:          v1beta2.ApplicationState{State: v1beta2.FailingState},
+		ExecutionAttempts: 1,
+		TerminationReason: v1beta2.OOMKilledReason,
+		TerminationTime:   metav1.Now(),
+		NextRetryTime:     metav1.Now(),
+	}
+	ctrl, recorder := newFakeController(app)
+	_, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.PendingRerunState, updatedApp.Status.AppState.State)
+	assert.Equal(t, []v1beta2.AttemptMemory{{
+		ExecutionAttempt:     2,
+		DriverMemory:         stringptr("1024m"),
+		DriverMemoryOverhead: stringptr("512m"),
+		ExecutorMemory:       stringptr("4g"),
+	}}, updatedApp.Status.EffectiveMemory)
+	assert.Contains(t, <-recorder.Events, "SparkApplicationMemoryEscalated")
+
+	var submittedArgs []string
+	defer func() { execCommand = exec.Command }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		submittedArgs = args
+		return helperCommand("TestHelperProcessSuccess")(command, args...)
+	}
+	ctrl, _ = newFakeController(updatedApp)
+	_, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), updatedApp, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.SubmittedState, updatedApp.Status.AppState.State)
+	assert.Equal(t, int32(2), updatedApp.Status.ExecutionAttempts)
+	assert.Len(t, updatedApp.Status.EffectiveMemory, 1)
+	assert.Contains(t, submittedArgs, "spark.driver.memory=1024m")
+	assert.Contains(t, submittedArgs, "spark.driver.memoryOverhead=512m")
+	assert.Contains(t, submittedArgs, "spark.executor.memory=4g")
+	assert.Equal(t, "512m", *updatedApp.Spec.Driver.Memory)
+}
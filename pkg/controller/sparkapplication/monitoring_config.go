@@ -50,10 +50,12 @@ func configPrometheusMonitoring(app *v1beta2.SparkApplication, kubeClient client
 		glog.V(2).Infof("Creating a ConfigMap for metrics and Prometheus configurations.")
 		configMapName := config.GetPrometheusConfigMapName(app)
 		configMap := buildPrometheusConfigMap(app, configMapName)
+		var applied *corev1.ConfigMap
 		retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 			cm, err := kubeClient.CoreV1().ConfigMaps(app.Namespace).Get(context.TODO(), configMapName, metav1.GetOptions{})
 			if apiErrors.IsNotFound(err) {
-				_, createErr := kubeClient.CoreV1().ConfigMaps(app.Namespace).Create(context.TODO(), configMap, metav1.CreateOptions{})
+				var createErr error
+				applied, createErr = kubeClient.CoreV1().ConfigMaps(app.Namespace).Create(context.TODO(), configMap, metav1.CreateOptions{})
 				return createErr
 			}
 			if err != nil {
@@ -61,13 +63,19 @@ func configPrometheusMonitoring(app *v1beta2.SparkApplication, kubeClient client
 			}
 
 			cm.Data = configMap.Data
-			_, updateErr := kubeClient.CoreV1().ConfigMaps(app.Namespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+			if cm.Labels == nil {
+				cm.Labels = make(map[string]string)
+			}
+			cm.Labels[config.SparkAppNameLabel] = app.Name
+			var updateErr error
+			applied, updateErr = kubeClient.CoreV1().ConfigMaps(app.Namespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
 			return updateErr
 		})
 
 		if retryErr != nil {
 			return fmt.Errorf("failed to apply %s in namespace %s: %v", configMapName, app.Namespace, retryErr)
 		}
+		recordResource(app, coreAPIVersion, configMapKind, applied.Name, applied.UID)
 	}
 
 	var javaOption string
@@ -154,6 +162,7 @@ func buildPrometheusConfigMap(app *v1beta2.SparkApplication, prometheusConfigMap
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            prometheusConfigMapName,
 			Namespace:       app.Namespace,
+			Labels:          map[string]string{config.SparkAppNameLabel: app.Name},
 			OwnerReferences: []metav1.OwnerReference{*getOwnerReference(app)},
 		},
 		Data: configMapData,
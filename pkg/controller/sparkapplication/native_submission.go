@@ -0,0 +1,709 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// SubmitterType is the way the operator submits SparkApplications.
+type SubmitterType string
+
+const (
+	// SparkSubmitSubmitter runs spark-submit for every application.
+	SparkSubmitSubmitter SubmitterType = "spark-submit"
+	// NativeSubmitter creates the driver pod, driver service and Spark configuration ConfigMap directly, the
+	// way the Kubernetes client of spark-submit does. Applications it cannot submit fall back to spark-submit.
+	NativeSubmitter SubmitterType = "native"
+)
+
+// The values below mirror the Spark 3 Kubernetes resource manager.
+const (
+	driverRPCPortName          = "driver-rpc-port"
+	driverBlockManagerPortName = "blockmanager"
+	driverUIPortName           = "spark-ui"
+	defaultDriverRPCPort       = 7078
+	defaultBlockManagerPort    = 7079
+	defaultDriverUIPort        = 4040
+	driverServiceSuffix        = "-driver-svc"
+	driverConfigMapSuffix      = "-driver-conf-map"
+	sparkConfVolumeName        = "spark-conf-volume-driver"
+	sparkConfDirInDriver       = "/opt/spark/conf"
+	sparkPropertiesFileName    = "spark.properties"
+	defaultLocalDirPath        = "/var/data/spark-"
+	maxKubernetesNameLength    = 63
+	minMemoryOverheadMiB       = 384
+	defaultDriverMemoryMiB     = 1024
+	jvmMemoryOverheadFactor    = 0.1
+	nonJVMMemoryOverhead       = 0.4
+	pythonRunnerClass          = "org.apache.spark.deploy.PythonRunner"
+	rRunnerClass               = "org.apache.spark.deploy.RRunner"
+	localDirsTmpfsKey          = "spark.kubernetes.local.dirs.tmpfs"
+	sparkMasterKey             = "spark.master"
+	sparkDeployModeKey         = "spark.submit.deployMode"
+	sparkDriverHostKey         = "spark.driver.host"
+	sparkDriverPortKey         = "spark.driver.port"
+	sparkBlockManagerPortKey   = "spark.driver.blockManager.port"
+	sparkUIPortKey             = "spark.ui.port"
+	sparkAppIDKey              = "spark.app.id"
+	sparkDriverCoresKey        = "spark.driver.cores"
+	sparkDriverMemoryKey       = "spark.driver.memory"
+	sparkDriverOverheadKey     = "spark.driver.memoryOverhead"
+	sparkSubmitInDriverKey     = "spark.kubernetes.submitInDriver"
+	sparkExecutorPrefixKey     = "spark.kubernetes.executor.podNamePrefix"
+	sparkResourceTypeKey       = "spark.kubernetes.resource.type"
+)
+
+// unsupportedNativeConfPrefixes are Spark configuration properties that need files on the submitting machine or
+// otherwise rely on spark-submit. Applications setting them are submitted with spark-submit.
+var unsupportedNativeConfPrefixes = []string{
+	"spark.kubernetes.driver.podTemplateFile",
+	"spark.kubernetes.driver.podTemplateContainerName",
+	"spark.kubernetes.driver.pod.featureSteps",
+	"spark.kubernetes.kerberos.",
+	"spark.kerberos.",
+	"spark.kubernetes.hadoop.configMapName",
+}
+
+var (
+	sparkMemoryPattern         = regexp.MustCompile(`^([0-9]+)([kmgtp]?)(b?)$`)
+	invalidResourceNameChars   = regexp.MustCompile(`[^a-z0-9\-]`)
+	repeatedResourceNameDashes = regexp.MustCompile(`-+`)
+)
+
+// nativeSubmission is a submission rendered from the spark-submit arguments of an application, so that both
+// submitters derive the application configuration in the same way.
+type nativeSubmission struct {
+	namespace       string
+	name            string
+	appType         v1beta2.SparkApplicationType
+	deployMode      string
+	mainClass       string
+	proxyUser       string
+	primaryResource string
+	arguments       []string
+	properties      map[string]string
+}
+
+// newNativeSubmission parses arguments built by buildSubmissionCommandArgs.
+func newNativeSubmission(args []string, app *v1beta2.SparkApplication) (*nativeSubmission, error) {
+	submission := &nativeSubmission{
+		namespace:  app.Namespace,
+		name:       app.Name,
+		appType:    app.Spec.Type,
+		properties: make(map[string]string),
+	}
+	optionProperties := map[string]string{
+		"--master":           sparkMasterKey,
+		"--deploy-mode":      sparkDeployModeKey,
+		"--jars":             "spark.jars",
+		"--files":            "spark.files",
+		"--py-files":         "spark.submit.pyFiles",
+		"--packages":         "spark.jars.packages",
+		"--exclude-packages": "spark.jars.excludes",
+		"--repositories":     "spark.jars.repositories",
+	}
+
+	i := 0
+	for ; i+1 < len(args) && strings.HasPrefix(args[i], "--"); i += 2 {
+		option, value := args[i], args[i+1]
+		switch option {
+		case "--class":
+			submission.mainClass = value
+		case "--proxy-user":
+			submission.proxyUser = value
+		case "--conf":
+			kv := strings.SplitN(value, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid Spark configuration property %q", value)
+			}
+			submission.properties[kv[0]] = kv[1]
+		default:
+			key, ok := optionProperties[option]
+			if !ok {
+				return nil, fmt.Errorf("unsupported spark-submit option %s", option)
+			}
+			submission.properties[key] = value
+		}
+	}
+	if i < len(args) && app.Spec.MainApplicationFile != nil {
+		submission.primaryResource = args[i]
+		i++
+	}
+	submission.arguments = args[i:]
+	submission.deployMode = submission.properties[sparkDeployModeKey]
+	return submission, nil
+}
+
+// unsupportedReason returns why the submission needs spark-submit, or an empty string if it can be submitted
+// natively.
+func (s *nativeSubmission) unsupportedReason() string {
+	if s.deployMode != string(v1beta2.ClusterMode) {
+		return fmt.Sprintf("deploy mode %s", s.deployMode)
+	}
+	if s.primaryResource == "" {
+		return "no main application file"
+	}
+	for key := range s.properties {
+		for _, prefix := range unsupportedNativeConfPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				return fmt.Sprintf("configuration property %s", key)
+			}
+		}
+		if strings.HasPrefix(key, config.SparkDriverVolumesPrefix) {
+			volumeType := strings.SplitN(strings.TrimPrefix(key, config.SparkDriverVolumesPrefix), ".", 2)[0]
+			if !isSupportedNativeVolumeType(volumeType) {
+				return fmt.Sprintf("driver volume type %s", volumeType)
+			}
+		}
+	}
+	dependencies := []string{s.primaryResource}
+	for _, key := range []string{"spark.jars", "spark.files", "spark.submit.pyFiles"} {
+		if value := s.properties[key]; value != "" {
+			dependencies = append(dependencies, strings.Split(value, ",")...)
+		}
+	}
+	for _, dependency := range dependencies {
+		if parsed, err := url.Parse(dependency); err == nil && (parsed.Scheme == "" || parsed.Scheme == "file") {
+			return fmt.Sprintf("local dependency %s that spark-submit would upload", dependency)
+		}
+	}
+	return ""
+}
+
+func isSupportedNativeVolumeType(volumeType string) bool {
+	switch volumeType {
+	case "hostPath", "emptyDir", "persistentVolumeClaim":
+		return true
+	}
+	return false
+}
+
+// runNativeSubmission creates the driver pod of the submission, and the driver service and Spark configuration
+// ConfigMap owned by it. It returns false without an error if the driver pod already exists.
+func runNativeSubmission(kubeClient clientset.Interface, submission *nativeSubmission, app *v1beta2.SparkApplication,
+	driverPodName string, submissionID string) (bool, error) {
+	resources, err := renderNativeSubmission(submission, app, driverPodName, submissionID)
+	if err != nil {
+		return false, err
+	}
+
+	pod, err := kubeClient.CoreV1().Pods(submission.namespace).Create(context.TODO(), resources.pod, metav1.CreateOptions{})
+	if err != nil {
+		if errors.IsAlreadyExists(err) {
+			glog.Warningf("trying to resubmit an already submitted SparkApplication %s/%s", submission.namespace, submission.name)
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to create driver pod for SparkApplication %s/%s: %v", submission.namespace, submission.name, err)
+	}
+
+	// The driver pod owns the service and the ConfigMap, so they are deleted along with it.
+	controller := true
+	podOwnerReference := metav1.OwnerReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Name:       pod.Name,
+		UID:        pod.UID,
+		Controller: &controller,
+	}
+	resources.service.OwnerReferences = []metav1.OwnerReference{podOwnerReference}
+	resources.configMap.OwnerReferences = []metav1.OwnerReference{podOwnerReference}
+
+	_, err = kubeClient.CoreV1().ConfigMaps(submission.namespace).Create(context.TODO(), resources.configMap, metav1.CreateOptions{})
+	if err == nil {
+		_, err = kubeClient.CoreV1().Services(submission.namespace).Create(context.TODO(), resources.service, metav1.CreateOptions{})
+	}
+	if err != nil {
+		if deleteErr := kubeClient.CoreV1().Pods(submission.namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{}); deleteErr != nil {
+			glog.Errorf("failed to delete driver pod %s/%s after a failed submission: %v", pod.Namespace, pod.Name, deleteErr)
+		}
+		return false, fmt.Errorf("failed to create driver resources for SparkApplication %s/%s: %v", submission.namespace, submission.name, err)
+	}
+	return true, nil
+}
+
+// nativeResources are the resources the native submitter creates for a submission.
+type nativeResources struct {
+	pod       *apiv1.Pod
+	service   *apiv1.Service
+	configMap *apiv1.ConfigMap
+}
+
+func renderNativeSubmission(submission *nativeSubmission, app *v1beta2.SparkApplication, driverPodName string,
+	submissionID string) (*nativeResources, error) {
+	properties := make(map[string]string, len(submission.properties))
+	for key, value := range submission.properties {
+		properties[key] = value
+	}
+
+	uniqueID := strings.ReplaceAll(submissionID, "-", "")
+	appID := "spark-" + uniqueID
+	resourceNamePrefix := kubernetesResourceNamePrefix(submission.name, uniqueID)
+	serviceName := resourceNamePrefix + driverServiceSuffix
+	if len(serviceName) > maxKubernetesNameLength {
+		serviceName = fmt.Sprintf("spark-%s%s", uniqueID[:16], driverServiceSuffix)
+	}
+	configMapName := resourceNamePrefix + driverConfigMapSuffix
+
+	image := properties[config.SparkDriverContainerImageKey]
+	if image == "" {
+		image = properties[config.SparkContainerImageKey]
+	}
+	if image == "" {
+		return nil, fmt.Errorf("no container image specified for the driver of SparkApplication %s/%s", submission.namespace, submission.name)
+	}
+
+	driverPort, err := intProperty(properties, sparkDriverPortKey, defaultDriverRPCPort)
+	if err != nil {
+		return nil, err
+	}
+	blockManagerPort, err := intProperty(properties, sparkBlockManagerPortKey, defaultBlockManagerPort)
+	if err != nil {
+		return nil, err
+	}
+	uiPort, err := intProperty(properties, sparkUIPortKey, defaultDriverUIPort)
+	if err != nil {
+		return nil, err
+	}
+
+	mainClass := submission.mainClass
+	resourceType := "java"
+	overheadFactor := jvmMemoryOverheadFactor
+	switch submission.appType {
+	case v1beta2.PythonApplicationType:
+		mainClass, resourceType, overheadFactor = pythonRunnerClass, "python", nonJVMMemoryOverhead
+	case v1beta2.RApplicationType:
+		mainClass, resourceType, overheadFactor = rRunnerClass, "r", nonJVMMemoryOverhead
+	}
+	if value, ok := properties[config.SparkMemoryOverheadFactor]; ok {
+		overheadFactor, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s: %v", value, config.SparkMemoryOverheadFactor, err)
+		}
+	}
+
+	properties[sparkAppIDKey] = appID
+	properties[config.SparkDriverPodNameKey] = driverPodName
+	properties[sparkSubmitInDriverKey] = "true"
+	properties[sparkDriverHostKey] = fmt.Sprintf("%s.%s.svc", serviceName, submission.namespace)
+	properties[sparkDriverPortKey] = strconv.Itoa(driverPort)
+	properties[sparkBlockManagerPortKey] = strconv.Itoa(blockManagerPort)
+	properties[sparkExecutorPrefixKey] = resourceNamePrefix
+	properties[sparkResourceTypeKey] = resourceType
+	properties[config.SparkMemoryOverheadFactor] = strconv.FormatFloat(overheadFactor, 'f', -1, 64)
+
+	resources, err := driverResourceRequirements(properties, overheadFactor)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := propertiesWithPrefix(properties, config.SparkDriverLabelKeyPrefix)
+	labels[config.SparkApplicationSelectorLabel] = appID
+	labels[config.SparkRoleLabel] = config.SparkDriverRole
+
+	env := []apiv1.EnvVar{
+		{Name: "SPARK_APPLICATION_ID", Value: appID},
+		{Name: "SPARK_DRIVER_BIND_ADDRESS", ValueFrom: &apiv1.EnvVarSource{
+			FieldRef: &apiv1.ObjectFieldSelector{APIVersion: "v1", FieldPath: "status.podIP"},
+		}},
+		{Name: config.SparkConfDirEnvVar, Value: sparkConfDirInDriver},
+	}
+	if submission.proxyUser != "" {
+		env = append(env, apiv1.EnvVar{Name: "SPARK_USER", Value: submission.proxyUser})
+	}
+	if submission.appType == v1beta2.PythonApplicationType {
+		pythonVersion := properties[config.SparkPythonVersion]
+		if pythonVersion == "" {
+			pythonVersion = "3"
+		}
+		env = append(env, apiv1.EnvVar{Name: "PYSPARK_MAJOR_PYTHON_VERSION", Value: pythonVersion})
+	}
+	for _, name := range sortedKeys(propertiesWithPrefix(properties, config.SparkDriverEnvVarConfigKeyPrefix)) {
+		env = append(env, apiv1.EnvVar{Name: name, Value: properties[config.SparkDriverEnvVarConfigKeyPrefix+name]})
+	}
+	secretKeyRefs := propertiesWithPrefix(properties, config.SparkDriverSecretKeyRefKeyPrefix)
+	for _, name := range sortedKeys(secretKeyRefs) {
+		ref := strings.SplitN(secretKeyRefs[name], ":", 2)
+		if len(ref) != 2 {
+			return nil, fmt.Errorf("invalid secret key reference %q for environment variable %s", secretKeyRefs[name], name)
+		}
+		env = append(env, apiv1.EnvVar{Name: name, ValueFrom: &apiv1.EnvVarSource{
+			SecretKeyRef: &apiv1.SecretKeySelector{LocalObjectReference: apiv1.LocalObjectReference{Name: ref[0]}, Key: ref[1]},
+		}})
+	}
+
+	volumes := []apiv1.Volume{{
+		Name: sparkConfVolumeName,
+		VolumeSource: apiv1.VolumeSource{
+			ConfigMap: &apiv1.ConfigMapVolumeSource{LocalObjectReference: apiv1.LocalObjectReference{Name: configMapName}},
+		},
+	}}
+	volumeMounts := []apiv1.VolumeMount{{Name: sparkConfVolumeName, MountPath: sparkConfDirInDriver}}
+	userVolumes, userVolumeMounts, err := driverVolumes(properties)
+	if err != nil {
+		return nil, err
+	}
+	volumes = append(volumes, userVolumes...)
+	volumeMounts = append(volumeMounts, userVolumeMounts...)
+
+	secrets := propertiesWithPrefix(properties, config.SparkDriverSecretKeyPrefix)
+	for _, name := range sortedKeys(secrets) {
+		volumeName := name + "-volume"
+		volumes = append(volumes, apiv1.Volume{
+			Name:         volumeName,
+			VolumeSource: apiv1.VolumeSource{Secret: &apiv1.SecretVolumeSource{SecretName: name}},
+		})
+		volumeMounts = append(volumeMounts, apiv1.VolumeMount{Name: volumeName, MountPath: secrets[name]})
+	}
+
+	// Spark uses the volumes named spark-local-dir-* as local directories, or an emptyDir volume if there is none.
+	var localDirs []string
+	for _, mount := range volumeMounts {
+		if strings.HasPrefix(mount.Name, config.SparkLocalDirVolumePrefix) {
+			localDirs = append(localDirs, mount.MountPath)
+		}
+	}
+	if len(localDirs) == 0 {
+		localDir := apiv1.Volume{Name: config.SparkLocalDirVolumePrefix + "1", VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{}}}
+		if properties[localDirsTmpfsKey] == "true" {
+			localDir.EmptyDir.Medium = apiv1.StorageMediumMemory
+		}
+		localDirs = append(localDirs, defaultLocalDirPath+submissionID)
+		volumes = append(volumes, localDir)
+		volumeMounts = append(volumeMounts, apiv1.VolumeMount{Name: localDir.Name, MountPath: localDirs[0]})
+	}
+	env = append(env, apiv1.EnvVar{Name: "SPARK_LOCAL_DIRS", Value: strings.Join(localDirs, ",")})
+
+	pullPolicy := apiv1.PullIfNotPresent
+	if value := properties[config.SparkContainerImagePullPolicyKey]; value != "" {
+		pullPolicy = apiv1.PullPolicy(value)
+	}
+	var pullSecrets []apiv1.LocalObjectReference
+	if value := properties[config.SparkImagePullSecretKey]; value != "" {
+		for _, name := range strings.Split(value, ",") {
+			pullSecrets = append(pullSecrets, apiv1.LocalObjectReference{Name: strings.TrimSpace(name)})
+		}
+	}
+
+	args := []string{"driver", "--properties-file", sparkConfDirInDriver + "/" + sparkPropertiesFileName}
+	if submission.proxyUser != "" {
+		args = append(args, "--proxy-user", submission.proxyUser)
+	}
+	args = append(args, "--class", mainClass, submission.primaryResource)
+	args = append(args, submission.arguments...)
+
+	pod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            driverPodName,
+			Namespace:       submission.namespace,
+			Labels:          labels,
+			Annotations:     propertiesWithPrefix(properties, config.SparkDriverAnnotationKeyPrefix),
+			OwnerReferences: []metav1.OwnerReference{*getOwnerReference(app)},
+		},
+		Spec: apiv1.PodSpec{
+			RestartPolicy:      apiv1.RestartPolicyNever,
+			ServiceAccountName: properties[config.SparkDriverServiceAccountName],
+			NodeSelector:       propertiesWithPrefix(properties, config.SparkNodeSelectorKeyPrefix),
+			ImagePullSecrets:   pullSecrets,
+			Volumes:            volumes,
+			Containers: []apiv1.Container{{
+				Name:            config.SparkDriverContainerName,
+				Image:           image,
+				ImagePullPolicy: pullPolicy,
+				Args:            args,
+				Env:             env,
+				Resources:       resources,
+				VolumeMounts:    volumeMounts,
+				Ports: []apiv1.ContainerPort{
+					{Name: driverRPCPortName, ContainerPort: int32(driverPort), Protocol: apiv1.ProtocolTCP},
+					{Name: driverBlockManagerPortName, ContainerPort: int32(blockManagerPort), Protocol: apiv1.ProtocolTCP},
+					{Name: driverUIPortName, ContainerPort: int32(uiPort), Protocol: apiv1.ProtocolTCP},
+				},
+			}},
+		},
+	}
+
+	service := &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        serviceName,
+			Namespace:   submission.namespace,
+			Annotations: propertiesWithPrefix(properties, config.SparkDriverServiceAnnotationKeyPrefix),
+		},
+		Spec: apiv1.ServiceSpec{
+			ClusterIP: apiv1.ClusterIPNone,
+			Selector: map[string]string{
+				config.SparkApplicationSelectorLabel: appID,
+				config.SparkRoleLabel:                config.SparkDriverRole,
+			},
+			Ports: []apiv1.ServicePort{
+				{Name: driverRPCPortName, Port: int32(driverPort), Protocol: apiv1.ProtocolTCP},
+				{Name: driverBlockManagerPortName, Port: int32(blockManagerPort), Protocol: apiv1.ProtocolTCP},
+				{Name: driverUIPortName, Port: int32(uiPort), Protocol: apiv1.ProtocolTCP},
+			},
+		},
+	}
+
+	configMap := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configMapName,
+			Namespace: submission.namespace,
+		},
+		Data: map[string]string{sparkPropertiesFileName: formatSparkProperties(properties)},
+	}
+
+	return &nativeResources{pod: pod, service: service, configMap: configMap}, nil
+}
+
+// kubernetesResourceNamePrefix returns a prefix for resource names derived from the application name, as Spark
+// does: lower case, with characters not allowed in DNS labels replaced.
+func kubernetesResourceNamePrefix(appName string, uniqueID string) string {
+	name := strings.ToLower(appName)
+	name = invalidResourceNameChars.ReplaceAllString(name, "-")
+	name = repeatedResourceNameDashes.ReplaceAllString(name, "-")
+	name = strings.Trim(name, "-")
+	if len(uniqueID) > 16 {
+		uniqueID = uniqueID[:16]
+	}
+	if name == "" {
+		return "spark-" + uniqueID
+	}
+	return name + "-" + uniqueID
+}
+
+func driverResourceRequirements(properties map[string]string, overheadFactor float64) (apiv1.ResourceRequirements, error) {
+	requirements := apiv1.ResourceRequirements{
+		Requests: apiv1.ResourceList{},
+		Limits:   apiv1.ResourceList{},
+	}
+
+	cores := properties[config.SparkDriverCoreRequestKey]
+	if cores == "" {
+		cores = properties[sparkDriverCoresKey]
+	}
+	if cores == "" {
+		cores = "1"
+	}
+	cpuRequest, err := resource.ParseQuantity(cores)
+	if err != nil {
+		return requirements, fmt.Errorf("invalid driver CPU request %q: %v", cores, err)
+	}
+	requirements.Requests[apiv1.ResourceCPU] = cpuRequest
+	if value := properties[config.SparkDriverCoreLimitKey]; value != "" {
+		cpuLimit, err := resource.ParseQuantity(value)
+		if err != nil {
+			return requirements, fmt.Errorf("invalid driver CPU limit %q: %v", value, err)
+		}
+		requirements.Limits[apiv1.ResourceCPU] = cpuLimit
+	}
+
+	memoryMiB := int64(defaultDriverMemoryMiB)
+	if value, ok := properties[sparkDriverMemoryKey]; ok {
+		if memoryMiB, err = parseSparkMemoryMiB(value); err != nil {
+			return requirements, err
+		}
+	}
+	overheadMiB := int64(math.Max(overheadFactor*float64(memoryMiB), minMemoryOverheadMiB))
+	if value, ok := properties[sparkDriverOverheadKey]; ok {
+		if overheadMiB, err = parseSparkMemoryMiB(value); err != nil {
+			return requirements, err
+		}
+	}
+	memory := resource.MustParse(fmt.Sprintf("%dMi", memoryMiB+overheadMiB))
+	requirements.Requests[apiv1.ResourceMemory] = memory
+	requirements.Limits[apiv1.ResourceMemory] = memory
+	return requirements, nil
+}
+
+// parseSparkMemoryMiB parses a Spark memory size like 512m or 2g, where sizes without unit are in MiB and sizes in
+// bytes end in b. Sizes that are not a whole number of MiB are rounded up, so that no positive size parses to zero.
+func parseSparkMemoryMiB(value string) (int64, error) {
+	matches := sparkMemoryPattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(value)))
+	if matches == nil {
+		return 0, fmt.Errorf("invalid memory size %q, examples: 512m, 2g", value)
+	}
+	size, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory size %q: %v", value, err)
+	}
+	// The power of two of the unit in bytes.
+	var unitShift uint
+	switch matches[2] {
+	case "":
+		if matches[3] == "" {
+			unitShift = 20
+		}
+	case "k":
+		unitShift = 10
+	case "m":
+		unitShift = 20
+	case "g":
+		unitShift = 30
+	case "t":
+		unitShift = 40
+	default:
+		unitShift = 50
+	}
+	if unitShift >= 20 {
+		return size << (unitShift - 20), nil
+	}
+	return (size + 1<<(20-unitShift) - 1) >> (20 - unitShift), nil
+}
+
+// driverVolumes returns the volumes configured with spark.kubernetes.driver.volumes.[type].[name].* properties.
+func driverVolumes(properties map[string]string) ([]apiv1.Volume, []apiv1.VolumeMount, error) {
+	type volumeKey struct{ volumeType, name string }
+	settings := make(map[volumeKey]map[string]string)
+	for key, value := range propertiesWithPrefix(properties, config.SparkDriverVolumesPrefix) {
+		parts := strings.SplitN(key, ".", 3)
+		if len(parts) != 3 {
+			return nil, nil, fmt.Errorf("invalid volume configuration property %s%s", config.SparkDriverVolumesPrefix, key)
+		}
+		volume := volumeKey{parts[0], parts[1]}
+		if settings[volume] == nil {
+			settings[volume] = make(map[string]string)
+		}
+		settings[volume][parts[2]] = value
+	}
+
+	keys := make([]volumeKey, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].name < keys[j].name })
+
+	var volumes []apiv1.Volume
+	var mounts []apiv1.VolumeMount
+	for _, key := range keys {
+		options := settings[key]
+		volume := apiv1.Volume{Name: key.name}
+		switch key.volumeType {
+		case "hostPath":
+			volume.HostPath = &apiv1.HostPathVolumeSource{Path: options["options.path"]}
+			if value := options["options.type"]; value != "" {
+				hostPathType := apiv1.HostPathType(value)
+				volume.HostPath.Type = &hostPathType
+			}
+		case "emptyDir":
+			volume.EmptyDir = &apiv1.EmptyDirVolumeSource{Medium: apiv1.StorageMedium(options["options.medium"])}
+			if value := options["options.sizeLimit"]; value != "" {
+				sizeLimit, err := resource.ParseQuantity(value)
+				if err != nil {
+					return nil, nil, fmt.Errorf("invalid size limit %q of volume %s: %v", value, key.name, err)
+				}
+				volume.EmptyDir.SizeLimit = &sizeLimit
+			}
+		case "persistentVolumeClaim":
+			volume.PersistentVolumeClaim = &apiv1.PersistentVolumeClaimVolumeSource{ClaimName: options["options.claimName"]}
+		default:
+			return nil, nil, fmt.Errorf("unsupported volume type %s of volume %s", key.volumeType, key.name)
+		}
+		readOnly, _ := strconv.ParseBool(options["mount.readOnly"])
+		volumes = append(volumes, volume)
+		mounts = append(mounts, apiv1.VolumeMount{
+			Name:      key.name,
+			MountPath: options["mount.path"],
+			SubPath:   options["mount.subPath"],
+			ReadOnly:  readOnly,
+		})
+	}
+	return volumes, mounts, nil
+}
+
+// propertiesWithPrefix returns the properties starting with the prefix, with the prefix removed from their keys.
+func propertiesWithPrefix(properties map[string]string, prefix string) map[string]string {
+	result := make(map[string]string)
+	for key, value := range properties {
+		if strings.HasPrefix(key, prefix) {
+			result[strings.TrimPrefix(key, prefix)] = value
+		}
+	}
+	return result
+}
+
+func intProperty(properties map[string]string, key string, defaultValue int) (int, error) {
+	value, ok := properties[key]
+	if !ok {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q for %s: %v", value, key, err)
+	}
+	return parsed, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatSparkProperties formats the properties in the Java properties file format, sorted by key.
+func formatSparkProperties(properties map[string]string) string {
+	var builder strings.Builder
+	for _, key := range sortedKeys(properties) {
+		builder.WriteString(escapeJavaProperty(key, true))
+		builder.WriteByte('=')
+		builder.WriteString(escapeJavaProperty(properties[key], false))
+		builder.WriteByte('\n')
+	}
+	return builder.String()
+}
+
+func escapeJavaProperty(value string, isKey bool) string {
+	var builder strings.Builder
+	for i, r := range value {
+		switch {
+		case r == '\\':
+			builder.WriteString(`\\`)
+		case r == '\n':
+			builder.WriteString(`\n`)
+		case r == '\r':
+			builder.WriteString(`\r`)
+		case r == '\t':
+			builder.WriteString(`\t`)
+		case r == ' ' && (isKey || i == 0):
+			builder.WriteString(`\ `)
+		case isKey && (r == '=' || r == ':' || r == '#' || r == '!'):
+			builder.WriteByte('\\')
+			builder.WriteRune(r)
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+const nativeTestSubmissionID = "0f6b1a4e-5d2c-4b1e-9a3f-7c8d9e0a1b2c"
+
+func newNativeTestApp() *v1beta2.SparkApplication {
+	mainClass := "org.apache.spark.examples.SparkPi"
+	mainFile := "local:///opt/spark/examples/jars/spark-examples.jar"
+	image := "spark:3.0.0"
+	memory := "512m"
+	cores := int32(2)
+	serviceAccount := "spark"
+	return &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "spark-pi", Namespace: "default", UID: "uid-1"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Type:                v1beta2.ScalaApplicationType,
+			Mode:                v1beta2.ClusterMode,
+			Image:               &image,
+			MainClass:           &mainClass,
+			MainApplicationFile: &mainFile,
+			Arguments:           []string{"1000"},
+			SparkConf:           map[string]string{"spark.eventLog.enabled": "true"},
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					Cores:          &cores,
+					Memory:         &memory,
+					ServiceAccount: &serviceAccount,
+					Labels:         map[string]string{"team": "data"},
+					EnvSecretKeyRefs: map[string]v1beta2.NameKey{
+						"PASSWORD": {Name: "db", Key: "password"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func buildNativeTestSubmission(t *testing.T, app *v1beta2.SparkApplication) *nativeSubmission {
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+	args, err := buildSubmissionCommandArgs(app, getDriverPodName(app), nativeTestSubmissionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	submission, err := newNativeSubmission(args, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return submission
+}
+
+func TestRunNativeSubmission(t *testing.T) {
+	app := newNativeTestApp()
+	submission := buildNativeTestSubmission(t, app)
+	assert.Equal(t, "", submission.unsupportedReason())
+
+	kubeClient := kubeclientfake.NewSimpleClientset()
+	submitted, err := runNativeSubmission(kubeClient, submission, app, "spark-pi-driver", nativeTestSubmissionID)
+	assert.NoError(t, err)
+	assert.True(t, submitted)
+
+	pod, err := kubeClient.CoreV1().Pods("default").Get(context.TODO(), "spark-pi-driver", metav1.GetOptions{})
+	assert.NoError(t, err)
+	appID := "spark-0f6b1a4e5d2c4b1e9a3f7c8d9e0a1b2c"
+	assert.Equal(t, appID, pod.Labels[config.SparkApplicationSelectorLabel])
+	assert.Equal(t, config.SparkDriverRole, pod.Labels[config.SparkRoleLabel])
+	assert.Equal(t, "data", pod.Labels["team"])
+	assert.Equal(t, "true", pod.Labels[config.LaunchedBySparkOperatorLabel])
+	assert.Equal(t, nativeTestSubmissionID, pod.Labels[config.SubmissionIDLabel])
+	assert.Equal(t, "spark-pi", pod.OwnerReferences[0].Name)
+	assert.Equal(t, "spark", pod.Spec.ServiceAccountName)
+	assert.Equal(t, apiv1.RestartPolicyNever, pod.Spec.RestartPolicy)
+
+	container := pod.Spec.Containers[0]
+	assert.Equal(t, config.SparkDriverContainerName, container.Name)
+	assert.Equal(t, "spark:3.0.0", container.Image)
+	assert.Equal(t, []string{
+		"driver", "--properties-file", "/opt/spark/conf/spark.properties",
+		"--class", "org.apache.spark.examples.SparkPi",
+		"local:///opt/spark/examples/jars/spark-examples.jar", "1000",
+	}, container.Args)
+	// 512Mi of memory and the minimum overhead of 384Mi.
+	assert.True(t, resource.MustParse("896Mi").Equal(container.Resources.Limits[apiv1.ResourceMemory]))
+	assert.True(t, resource.MustParse("2").Equal(container.Resources.Requests[apiv1.ResourceCPU]))
+	env := map[string]apiv1.EnvVar{}
+	for _, e := range container.Env {
+		env[e.Name] = e
+	}
+	assert.Equal(t, appID, env["SPARK_APPLICATION_ID"].Value)
+	assert.Equal(t, "status.podIP", env["SPARK_DRIVER_BIND_ADDRESS"].ValueFrom.FieldRef.FieldPath)
+	assert.Equal(t, "db", env["PASSWORD"].ValueFrom.SecretKeyRef.Name)
+	assert.Equal(t, "/var/data/spark-"+nativeTestSubmissionID, env["SPARK_LOCAL_DIRS"].Value)
+
+	services, err := kubeClient.CoreV1().Services("default").List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, services.Items, 1)
+	service := services.Items[0]
+	assert.Equal(t, "spark-pi-0f6b1a4e5d2c4b1e-driver-svc", service.Name)
+	assert.Equal(t, apiv1.ClusterIPNone, service.Spec.ClusterIP)
+	assert.Equal(t, appID, service.Spec.Selector[config.SparkApplicationSelectorLabel])
+	assert.Equal(t, "Pod", service.OwnerReferences[0].Kind)
+
+	configMap, err := kubeClient.CoreV1().ConfigMaps("default").Get(context.TODO(), "spark-pi-0f6b1a4e5d2c4b1e-driver-conf-map", metav1.GetOptions{})
+	assert.NoError(t, err)
+	properties := configMap.Data[sparkPropertiesFileName]
+	for _, property := range []string{
+		"spark.app.id=" + appID,
+		"spark.app.name=spark-pi",
+		"spark.driver.host=spark-pi-0f6b1a4e5d2c4b1e-driver-svc.default.svc",
+		"spark.driver.port=7078",
+		"spark.eventLog.enabled=true",
+		"spark.kubernetes.driver.pod.name=spark-pi-driver",
+		"spark.kubernetes.executor.podNamePrefix=spark-pi-0f6b1a4e5d2c4b1e",
+		"spark.master=k8s://https://localhost:443",
+		"spark.submit.deployMode=cluster",
+	} {
+		assert.Contains(t, properties, property+"\n")
+	}
+
+	// Submitting again finds the existing driver pod.
+	submitted, err = runNativeSubmission(kubeClient, submission, app, "spark-pi-driver", nativeTestSubmissionID)
+	assert.NoError(t, err)
+	assert.False(t, submitted)
+}
+
+func TestNativeSubmissionUnsupported(t *testing.T) {
+	localFile := "/opt/app/app.jar"
+	app := newNativeTestApp()
+	app.Spec.MainApplicationFile = &localFile
+	assert.Contains(t, buildNativeTestSubmission(t, app).unsupportedReason(), "local dependency")
+
+	app = newNativeTestApp()
+	app.Spec.SparkConf["spark.kubernetes.driver.podTemplateFile"] = "/tmp/template.yaml"
+	assert.Contains(t, buildNativeTestSubmission(t, app).unsupportedReason(), "podTemplateFile")
+
+	app = newNativeTestApp()
+	app.Spec.Mode = v1beta2.ClientMode
+	assert.Contains(t, buildNativeTestSubmission(t, app).unsupportedReason(), "deploy mode")
+}
+
+func TestNativeSubmissionNonJVMMemoryOverhead(t *testing.T) {
+	app := newNativeTestApp()
+	app.Spec.Type = v1beta2.PythonApplicationType
+	memory := "2g"
+	app.Spec.Driver.Memory = &memory
+	resources, err := renderNativeSubmission(buildNativeTestSubmission(t, app), app, "spark-pi-driver", nativeTestSubmissionID)
+	assert.NoError(t, err)
+
+	container := resources.pod.Spec.Containers[0]
+	// 2048Mi of memory and 40% overhead for Python.
+	assert.True(t, resource.MustParse("2867Mi").Equal(container.Resources.Requests[apiv1.ResourceMemory]))
+	assert.Equal(t, pythonRunnerClass, container.Args[4])
+	assert.True(t, strings.Contains(resources.configMap.Data[sparkPropertiesFileName], "spark.kubernetes.resource.type=python\n"))
+}
+
+func TestParseSparkMemoryMiB(t *testing.T) {
+	for value, expected := range map[string]int64{"512m": 512, "512": 512, "2g": 2048, "2gb": 2048, "1t": 1 << 20, "2048k": 2,
+		"512k": 1, "1025k": 2, "1048576b": 1, "1b": 1, "0": 0} {
+		parsed, err := parseSparkMemoryMiB(value)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, parsed, value)
+	}
+	_, err := parseSparkMemoryMiB("lots")
+	assert.Error(t, err)
+}
+
+func TestGetSubmitter(t *testing.T) {
+	controller := &Controller{submitter: SparkSubmitSubmitter}
+	app := newNativeTestApp()
+	assert.Equal(t, SparkSubmitSubmitter, controller.getSubmitter(app))
+
+	app.Annotations = map[string]string{config.SubmitterAnnotation: "native"}
+	assert.Equal(t, NativeSubmitter, controller.getSubmitter(app))
+
+	app.Annotations[config.SubmitterAnnotation] = "unknown"
+	assert.Equal(t, SparkSubmitSubmitter, controller.getSubmitter(app))
+}
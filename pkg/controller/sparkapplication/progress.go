@@ -0,0 +1,343 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+const (
+	driverAPITimeout = 5 * time.Second
+	// defaultProgressPollInterval is the interval at which the progress of applications with a progress deadline is
+	// read if the operator does not poll the progress of all applications.
+	defaultProgressPollInterval = 30 * time.Second
+	// maxProgressPolls is the number of drivers whose progress is read at the same time.
+	maxProgressPolls = 10
+)
+
+var driverAPIClient = &http.Client{Timeout: driverAPITimeout}
+
+// sparkJob is the part of a job returned by the REST API of the Spark driver the operator uses.
+type sparkJob struct {
+	Status string `json:"status"`
+}
+
+// sparkStage is the part of a stage attempt returned by the REST API of the Spark driver the operator uses.
+type sparkStage struct {
+	Status            string `json:"status"`
+	StageID           int64  `json:"stageId"`
+	AttemptID         int64  `json:"attemptId"`
+	NumTasks          int32  `json:"numTasks"`
+	NumActiveTasks    int32  `json:"numActiveTasks"`
+	NumCompleteTasks  int32  `json:"numCompleteTasks"`
+	NumFailedTasks    int32  `json:"numFailedTasks"`
+	ShuffleReadBytes  int64  `json:"shuffleReadBytes"`
+	ShuffleWriteBytes int64  `json:"shuffleWriteBytes"`
+}
+
+// sparkThread is the part of a thread of a stack trace returned by the REST API of the Spark driver the operator uses.
+type sparkThread struct {
+	ThreadID      int64  `json:"threadId"`
+	ThreadName    string `json:"threadName"`
+	ThreadState   string `json:"threadState"`
+	BlockedByLock string `json:"blockedByLock"`
+	// StackTrace is a list of elements in Spark 3, and a single string in Spark 2.
+	StackTrace json.RawMessage `json:"stackTrace"`
+}
+
+func (t sparkThread) stackTraceElements() []string {
+	var stackTrace struct {
+		Elems []string `json:"elems"`
+	}
+	if err := json.Unmarshal(t.StackTrace, &stackTrace); err == nil {
+		return stackTrace.Elems
+	}
+	var elements string
+	if err := json.Unmarshal(t.StackTrace, &elements); err == nil {
+		return strings.Split(strings.TrimSpace(elements), "\n")
+	}
+	return nil
+}
+
+// sparkExecutor is the part of an executor returned by the REST API of the Spark driver the operator uses.
+type sparkExecutor struct {
+	ID       string `json:"id"`
+	IsActive bool   `json:"isActive"`
+}
+
+// updateProgress copies the progress of a running application last read from the REST API of its driver into its
+// status, and has the driver polled again once per progress poll interval. The application is requeued for the next
+// poll. The last progress read is kept if the driver cannot be reached.
+func (c *Controller) updateProgress(app *v1beta2.SparkApplication) {
+	key, err := keyFunc(app)
+	if err != nil {
+		glog.Errorf("failed to get key for %v: %v", app, err)
+		return
+	}
+	interval := c.getProgressPollInterval(app)
+	if interval <= 0 || app.Status.AppState.State != v1beta2.RunningState ||
+		app.Status.SparkApplicationID == "" || app.Status.DriverInfo.WebUIAddress == "" {
+		c.progressPoller.forget(key)
+		return
+	}
+
+	if progress := c.progressPoller.take(key, app.Status.SparkApplicationID); progress != nil {
+		lastProgress := app.Status.Progress
+		progress.LastProgressTime = progress.LastUpdateTime
+		if lastProgress != nil && !hasProgressed(lastProgress, progress) {
+			progress.LastProgressTime = lastProgress.LastProgressTime
+		}
+		app.Status.Progress = progress
+	}
+	c.enqueueAfter(app, c.progressPoller.poll(key, app, interval))
+}
+
+// progressPoller reads the progress of running applications from the REST API of their drivers in the background, a
+// bounded number at a time, so that drivers that are slow to answer do not block the controller workers. The progress
+// read is kept until the next sync of the application takes it, and the application is enqueued for that sync.
+type progressPoller struct {
+	slots   chan struct{}
+	enqueue func(key interface{})
+	// done is used by tests to wait for the polls started.
+	done sync.WaitGroup
+
+	mutex sync.Mutex
+	polls map[string]*progressPoll
+}
+
+type progressPoll struct {
+	sparkApplicationID string
+	inFlight           bool
+	lastStart          time.Time
+	// progress is the progress read since the last sync of the application, if any.
+	progress *v1beta2.ApplicationProgress
+}
+
+func newProgressPoller(enqueue func(key interface{})) *progressPoller {
+	return &progressPoller{
+		slots:   make(chan struct{}, maxProgressPolls),
+		enqueue: enqueue,
+		polls:   make(map[string]*progressPoll),
+	}
+}
+
+// poll starts reading the progress of the application with the given key unless it was read less than the interval
+// ago, a poll is in flight, or all slots are taken. It returns the duration after which the application is to be
+// polled again.
+func (p *progressPoller) poll(key string, app *v1beta2.SparkApplication, interval time.Duration) time.Duration {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	sparkApplicationID := app.Status.SparkApplicationID
+	poll, exists := p.polls[key]
+	if !exists || poll.sparkApplicationID != sparkApplicationID {
+		poll = &progressPoll{sparkApplicationID: sparkApplicationID}
+		if app.Status.Progress != nil {
+			// The operator may have restarted since the progress in the status was read.
+			poll.lastStart = app.Status.Progress.LastUpdateTime.Time
+		}
+		p.polls[key] = poll
+	}
+	if poll.inFlight {
+		return interval
+	}
+	if sinceLastStart := time.Since(poll.lastStart); sinceLastStart < interval {
+		return interval - sinceLastStart
+	}
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		return interval
+	}
+
+	poll.inFlight = true
+	poll.lastStart = time.Now()
+	webUIAddress := app.Status.DriverInfo.WebUIAddress
+	p.done.Add(1)
+	go func() {
+		defer p.done.Done()
+		defer func() { <-p.slots }()
+		progress, err := getDriverProgress(webUIAddress, sparkApplicationID)
+		if err != nil {
+			glog.V(2).Infof("failed to read the progress of SparkApplication %s: %v", key, err)
+		}
+
+		p.mutex.Lock()
+		poll.inFlight = false
+		read := err == nil && p.polls[key] == poll
+		if read {
+			poll.progress = progress
+		}
+		p.mutex.Unlock()
+		if read {
+			p.enqueue(key)
+		}
+	}()
+	return interval
+}
+
+// take returns the progress read for the given run of the application with the given key since the last call, or nil
+// if none was read.
+func (p *progressPoller) take(key, sparkApplicationID string) *v1beta2.ApplicationProgress {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	poll, exists := p.polls[key]
+	if !exists || poll.sparkApplicationID != sparkApplicationID {
+		return nil
+	}
+	progress := poll.progress
+	poll.progress = nil
+	return progress
+}
+
+// forget drops the state kept for the application with the given key, once its progress is no longer polled.
+func (p *progressPoller) forget(key string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	delete(p.polls, key)
+}
+
+// getProgressPollInterval returns the interval at which the progress of the application is read, or zero if it is
+// not read.
+func (c *Controller) getProgressPollInterval(app *v1beta2.SparkApplication) time.Duration {
+	if c.progressPollInterval > 0 {
+		return c.progressPollInterval
+	}
+	if app.Spec.ProgressDeadlineSeconds != nil {
+		return defaultProgressPollInterval
+	}
+	return 0
+}
+
+// hasProgressed returns whether more jobs, stages or tasks were completed than before.
+func hasProgressed(last, current *v1beta2.ApplicationProgress) bool {
+	return current.CompletedJobs != last.CompletedJobs || current.CompletedStages != last.CompletedStages ||
+		current.CompletedTasks != last.CompletedTasks
+}
+
+// getDriverProgress summarizes the jobs, stages and executors reported by the REST API of a Spark driver.
+func getDriverProgress(webUIAddress, sparkApplicationID string) (*v1beta2.ApplicationProgress, error) {
+	baseURL := fmt.Sprintf("http://%s/api/v1/applications/%s", webUIAddress, sparkApplicationID)
+	var jobs []sparkJob
+	if err := getDriverResource(baseURL+"/jobs", &jobs); err != nil {
+		return nil, err
+	}
+	var stages []sparkStage
+	if err := getDriverResource(baseURL+"/stages", &stages); err != nil {
+		return nil, err
+	}
+	var executors []sparkExecutor
+	if err := getDriverResource(baseURL+"/executors", &executors); err != nil {
+		return nil, err
+	}
+
+	progress := &v1beta2.ApplicationProgress{LastUpdateTime: metav1.Now()}
+	for _, job := range jobs {
+		switch job.Status {
+		case "RUNNING":
+			progress.ActiveJobs++
+		case "SUCCEEDED":
+			progress.CompletedJobs++
+		case "FAILED":
+			progress.FailedJobs++
+		}
+	}
+
+	// A stage is listed once per attempt, only its latest attempt counts.
+	latestAttempts := make(map[int64]sparkStage)
+	for _, stage := range stages {
+		if latest, ok := latestAttempts[stage.StageID]; !ok || stage.AttemptID > latest.AttemptID {
+			latestAttempts[stage.StageID] = stage
+		}
+	}
+	for _, stage := range latestAttempts {
+		progress.TotalStages++
+		switch stage.Status {
+		case "ACTIVE":
+			progress.ActiveStages++
+		case "COMPLETE", "SKIPPED":
+			progress.CompletedStages++
+		case "FAILED":
+			progress.FailedStages++
+		}
+		if stage.Status == "SKIPPED" {
+			continue
+		}
+		progress.TotalTasks += stage.NumTasks
+		progress.ActiveTasks += stage.NumActiveTasks
+		progress.CompletedTasks += stage.NumCompleteTasks
+		progress.FailedTasks += stage.NumFailedTasks
+		progress.ShuffleReadBytes += stage.ShuffleReadBytes
+		progress.ShuffleWriteBytes += stage.ShuffleWriteBytes
+	}
+
+	for _, executor := range executors {
+		if executor.ID != "driver" && executor.IsActive {
+			progress.ActiveExecutors++
+		}
+	}
+	return progress, nil
+}
+
+func getDriverResource(url string, resource interface{}) error {
+	response, err := driverAPIClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, response.Status)
+	}
+	if err := json.NewDecoder(response.Body).Decode(resource); err != nil {
+		return fmt.Errorf("failed to decode the response of %s: %v", url, err)
+	}
+	return nil
+}
+
+// getDriverThreadDump returns a thread dump of a Spark driver read from its REST API, in a format similar to jstack.
+func getDriverThreadDump(webUIAddress, sparkApplicationID string) (string, error) {
+	var threads []sparkThread
+	url := fmt.Sprintf("http://%s/api/v1/applications/%s/executors/driver/threads", webUIAddress, sparkApplicationID)
+	if err := getDriverResource(url, &threads); err != nil {
+		return "", err
+	}
+
+	var dump strings.Builder
+	for _, thread := range threads {
+		fmt.Fprintf(&dump, "\"%s\" #%d %s\n", thread.ThreadName, thread.ThreadID, thread.ThreadState)
+		if thread.BlockedByLock != "" {
+			fmt.Fprintf(&dump, "   - blocked on %s\n", thread.BlockedByLock)
+		}
+		for _, element := range thread.stackTraceElements() {
+			fmt.Fprintf(&dump, "\tat %s\n", element)
+		}
+		dump.WriteString("\n")
+	}
+	return dump.String(), nil
+}
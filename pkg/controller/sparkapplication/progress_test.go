@@ -0,0 +1,189 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// newFakeDriverAPI returns a server answering like the REST API of the driver of the given Spark application.
+func newFakeDriverAPI(sparkApplicationID string, requests *int) *httptest.Server {
+	responses := map[string]string{
+		"jobs": `[
+			{"jobId": 1, "status": "RUNNING"},
+			{"jobId": 0, "status": "SUCCEEDED"}
+		]`,
+		"stages": `[
+			{"status": "PENDING", "stageId": 3, "attemptId": 0, "numTasks": 10},
+			{"status": "ACTIVE", "stageId": 2, "attemptId": 1, "numTasks": 10, "numActiveTasks": 4, "numCompleteTasks": 6,
+				"shuffleReadBytes": 100},
+			{"status": "FAILED", "stageId": 2, "attemptId": 0, "numTasks": 10, "numFailedTasks": 1},
+			{"status": "SKIPPED", "stageId": 1, "attemptId": 0, "numTasks": 10},
+			{"status": "COMPLETE", "stageId": 0, "attemptId": 0, "numTasks": 10, "numCompleteTasks": 10,
+				"shuffleWriteBytes": 200}
+		]`,
+		"executors": `[
+			{"id": "driver", "isActive": true},
+			{"id": "1", "isActive": true},
+			{"id": "2", "isActive": true}
+		]`,
+		"executors/driver/threads": `[
+			{"threadId": 1, "threadName": "main", "threadState": "WAITING", "blockedByLock": "Lock(java.lang.Object@1)",
+				"stackTrace": {"elems": ["java.lang.Object.wait(Native Method)", "org.example.Job.main(Job.scala:10)"]}}
+		]`,
+	}
+	prefix := "/api/v1/applications/" + sparkApplicationID + "/"
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		response, ok := responses[strings.TrimPrefix(r.URL.Path, prefix)]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(response))
+	}))
+}
+
+func TestGetDriverProgress(t *testing.T) {
+	var requests int
+	server := newFakeDriverAPI("spark-1", &requests)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	progress, err := getDriverProgress(serverURL.Host, "spark-1")
+	assert.Nil(t, err)
+	progress.LastUpdateTime = metav1.Time{}
+	assert.Equal(t, &v1beta2.ApplicationProgress{
+		ActiveJobs:        1,
+		CompletedJobs:     1,
+		ActiveStages:      1,
+		CompletedStages:   2,
+		TotalStages:       4,
+		ActiveTasks:       4,
+		CompletedTasks:    16,
+		TotalTasks:        30,
+		ShuffleReadBytes:  100,
+		ShuffleWriteBytes: 200,
+		ActiveExecutors:   2,
+	}, progress)
+
+	_, err = getDriverProgress(serverURL.Host, "spark-2")
+	assert.NotNil(t, err)
+}
+
+func TestGetDriverThreadDump(t *testing.T) {
+	var requests int
+	server := newFakeDriverAPI("spark-1", &requests)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	dump, err := getDriverThreadDump(serverURL.Host, "spark-1")
+	assert.Nil(t, err)
+	assert.Equal(t, `"main" #1 WAITING
+   - blocked on Lock(java.lang.Object@1)
+	at java.lang.Object.wait(Native Method)
+	at org.example.Job.main(Job.scala:10)
+
+`, dump)
+
+	thread := sparkThread{StackTrace: []byte(`"java.lang.Thread.sleep(Native Method)\norg.example.Job.main(Job.scala:10)\n"`)}
+	assert.Equal(t, []string{"java.lang.Thread.sleep(Native Method)", "org.example.Job.main(Job.scala:10)"},
+		thread.stackTraceElements())
+}
+
+func TestSyncSparkApplication_Progress(t *testing.T) {
+	var requests int
+	server := newFakeDriverAPI("spark-1", &requests)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status: v1beta2.SparkApplicationStatus{
+			SparkApplicationID: "spark-1",
+			AppState:           v1beta2.ApplicationState{State: v1beta2.RunningState},
+			DriverInfo:         v1beta2.DriverInfo{PodName: "foo-driver", WebUIAddress: serverURL.Host},
+		},
+	}
+	driverPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-driver",
+			Namespace: "default",
+			Labels: map[string]string{
+				config.SparkRoleLabel:                config.SparkDriverRole,
+				config.SparkAppNameLabel:             "foo",
+				config.SparkApplicationSelectorLabel: "spark-1",
+			},
+		},
+		Status: apiv1.PodStatus{Phase: apiv1.PodRunning},
+	}
+	ctrl, _ := newFakeController(app, driverPod)
+	_, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// No progress is read unless polling is enabled.
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, requests)
+
+	// The progress is read in the background and copied into the status by the next sync.
+	ctrl.progressPollInterval = time.Minute
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	ctrl.progressPoller.done.Wait()
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, 1, ctrl.queue.Len())
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	ctrl.progressPoller.done.Wait()
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.RunningState, updatedApp.Status.AppState.State)
+	if assert.NotNil(t, updatedApp.Status.Progress) {
+		assert.Equal(t, int32(2), updatedApp.Status.Progress.CompletedStages)
+		assert.Equal(t, int32(4), updatedApp.Status.Progress.TotalStages)
+		assert.False(t, updatedApp.Status.Progress.LastUpdateTime.IsZero())
+		assert.Equal(t, updatedApp.Status.Progress.LastUpdateTime, updatedApp.Status.Progress.LastProgressTime)
+	}
+	assert.Equal(t, 3, requests)
+
+	// The driver is not polled again before the poll interval elapses.
+	ctrl, _ = newFakeController(updatedApp, driverPod)
+	ctrl.progressPollInterval = time.Minute
+	_, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), updatedApp, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, requests)
+}
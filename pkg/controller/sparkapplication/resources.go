@@ -0,0 +1,305 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/glog"
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	volcanov1beta1 "volcano.sh/volcano/pkg/apis/scheduling/v1beta1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+// Kinds of the resources the controller creates for an application.
+const (
+	podKind       = "Pod"
+	serviceKind   = "Service"
+	configMapKind = "ConfigMap"
+	ingressKind   = "Ingress"
+	podGroupKind  = "PodGroup"
+)
+
+var (
+	coreAPIVersion       = apiv1.SchemeGroupVersion.String()
+	networkingAPIVersion = networkingv1.SchemeGroupVersion.String()
+	extensionsAPIVersion = extensions.SchemeGroupVersion.String()
+	volcanoAPIVersion    = volcanov1beta1.SchemeGroupVersion.String()
+)
+
+// recordResource records a resource created for the current run of the application in its status, replacing any
+// earlier record of the same resource.
+func recordResource(app *v1beta2.SparkApplication, apiVersion, kind, name string, uid types.UID) {
+	resource := v1beta2.ResourceReference{APIVersion: apiVersion, Kind: kind, Name: name, UID: uid}
+	for i := range app.Status.Resources {
+		if isSameResource(app.Status.Resources[i], resource) {
+			app.Status.Resources[i] = resource
+			return
+		}
+	}
+	app.Status.Resources = append(app.Status.Resources, resource)
+}
+
+// recordPodGroup records the PodGroup the batch scheduler created for the application, if any.
+func recordPodGroup(app *v1beta2.SparkApplication) {
+	name := app.Spec.Driver.Annotations[volcanov1beta1.KubeGroupNameAnnotationKey]
+	if name == "" {
+		name = app.Spec.Executor.Annotations[volcanov1beta1.KubeGroupNameAnnotationKey]
+	}
+	if name != "" {
+		recordResource(app, volcanoAPIVersion, podGroupKind, name, "")
+	}
+}
+
+func isSameResource(a, b v1beta2.ResourceReference) bool {
+	return a.APIVersion == b.APIVersion && a.Kind == b.Kind && a.Name == b.Name
+}
+
+// getSparkResources returns the resources created for the current run of the application: those recorded in its
+// status, and those that can be derived from the rest of its status in case they were not recorded, e.g. because
+// the status update right after submission failed or the application was run by an earlier version of the operator.
+func getSparkResources(app *v1beta2.SparkApplication) []v1beta2.ResourceReference {
+	resources := append([]v1beta2.ResourceReference{}, app.Status.Resources...)
+	addResource := func(apiVersion, kind, name string) {
+		resource := v1beta2.ResourceReference{APIVersion: apiVersion, Kind: kind, Name: name}
+		for _, r := range resources {
+			if isSameResource(r, resource) {
+				return
+			}
+		}
+		resources = append(resources, resource)
+	}
+
+	driverPodName := app.Status.DriverInfo.PodName
+	// Derive the driver pod name in case the driver pod name was not recorded in the status.
+	if driverPodName == "" {
+		driverPodName = getDriverPodName(app)
+	}
+	addResource(coreAPIVersion, podKind, driverPodName)
+	if name := app.Status.DriverInfo.WebUIServiceName; name != "" {
+		addResource(coreAPIVersion, serviceKind, name)
+	}
+	if name := app.Status.DriverInfo.WebUIIngressName; name != "" {
+		if util.IngressCapabilities.Has(networkingAPIVersion) {
+			addResource(networkingAPIVersion, ingressKind, name)
+		}
+		if util.IngressCapabilities.Has(extensionsAPIVersion) {
+			addResource(extensionsAPIVersion, ingressKind, name)
+		}
+	}
+	if app.PrometheusMonitoringEnabled() && (!app.HasMetricsPropertiesFile() || !app.HasPrometheusConfigFile()) {
+		addResource(coreAPIVersion, configMapKind, config.GetPrometheusConfigMapName(app))
+	}
+	return resources
+}
+
+// deleteSparkResources deletes the resources created for the current run of the application. The executor pods and
+// the resources created by native submission are owned by the driver pod and go away with it.
+func (c *Controller) deleteSparkResources(app *v1beta2.SparkApplication) error {
+	for _, resource := range getSparkResources(app) {
+		if resource.Kind == podGroupKind {
+			if needScheduling, scheduler := c.shouldDoBatchScheduling(app); needScheduling {
+				glog.V(2).Infof("Deleting PodGroup %s in namespace %s", resource.Name, app.Namespace)
+				if err := scheduler.CleanupOnCompletion(app); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := c.deleteResource(app.Namespace, resource); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSparkResourceDeletion returns whether the resources created for the current run of the application are
+// gone. PodGroups are assumed to be gone once deleted.
+func (c *Controller) validateSparkResourceDeletion(app *v1beta2.SparkApplication) bool {
+	for _, resource := range getSparkResources(app) {
+		if resource.Kind != podGroupKind && !c.isResourceDeleted(app.Namespace, resource) {
+			return false
+		}
+	}
+	return true
+}
+
+// deleteResource deletes a resource unless it is already gone. A resource with a known UID is only deleted if it
+// still has that UID, so that a resource of the same name created later is left alone.
+func (c *Controller) deleteResource(namespace string, resource v1beta2.ResourceReference) error {
+	options := metav1.DeleteOptions{}
+	if resource.UID != "" {
+		options.Preconditions = metav1.NewUIDPreconditions(string(resource.UID))
+	}
+	glog.V(2).Infof("Deleting %s %s %s in namespace %s", resource.APIVersion, resource.Kind, resource.Name, namespace)
+	var err error
+	switch {
+	case resource.APIVersion == coreAPIVersion && resource.Kind == podKind:
+		err = c.kubeClient.CoreV1().Pods(namespace).Delete(context.TODO(), resource.Name, options)
+	case resource.APIVersion == coreAPIVersion && resource.Kind == serviceKind:
+		options.GracePeriodSeconds = int64ptr(0)
+		err = c.kubeClient.CoreV1().Services(namespace).Delete(context.TODO(), resource.Name, options)
+	case resource.APIVersion == coreAPIVersion && resource.Kind == configMapKind:
+		err = c.kubeClient.CoreV1().ConfigMaps(namespace).Delete(context.TODO(), resource.Name, options)
+	case resource.APIVersion == networkingAPIVersion && resource.Kind == ingressKind:
+		options.GracePeriodSeconds = int64ptr(0)
+		err = c.kubeClient.NetworkingV1().Ingresses(namespace).Delete(context.TODO(), resource.Name, options)
+	case resource.APIVersion == extensionsAPIVersion && resource.Kind == ingressKind:
+		options.GracePeriodSeconds = int64ptr(0)
+		err = c.kubeClient.ExtensionsV1beta1().Ingresses(namespace).Delete(context.TODO(), resource.Name, options)
+	default:
+		return fmt.Errorf("cannot delete %s %s %s: unsupported kind", resource.APIVersion, resource.Kind, resource.Name)
+	}
+	// A conflict means the UID precondition failed, i.e. the resource was replaced by another of the same name.
+	if err != nil && !errors.IsNotFound(err) && !errors.IsConflict(err) {
+		return err
+	}
+	return nil
+}
+
+// isResourceDeleted returns whether a resource is gone, or was replaced by another of the same name.
+func (c *Controller) isResourceDeleted(namespace string, resource v1beta2.ResourceReference) bool {
+	var object metav1.Object
+	var err error
+	switch {
+	case resource.APIVersion == coreAPIVersion && resource.Kind == podKind:
+		object, err = c.kubeClient.CoreV1().Pods(namespace).Get(context.TODO(), resource.Name, metav1.GetOptions{})
+	case resource.APIVersion == coreAPIVersion && resource.Kind == serviceKind:
+		object, err = c.kubeClient.CoreV1().Services(namespace).Get(context.TODO(), resource.Name, metav1.GetOptions{})
+	case resource.APIVersion == coreAPIVersion && resource.Kind == configMapKind:
+		object, err = c.kubeClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), resource.Name, metav1.GetOptions{})
+	case resource.APIVersion == networkingAPIVersion && resource.Kind == ingressKind:
+		object, err = c.kubeClient.NetworkingV1().Ingresses(namespace).Get(context.TODO(), resource.Name, metav1.GetOptions{})
+	case resource.APIVersion == extensionsAPIVersion && resource.Kind == ingressKind:
+		object, err = c.kubeClient.ExtensionsV1beta1().Ingresses(namespace).Get(context.TODO(), resource.Name, metav1.GetOptions{})
+	default:
+		return true
+	}
+	if err != nil {
+		return errors.IsNotFound(err)
+	}
+	return resource.UID != "" && object.GetUID() != resource.UID
+}
+
+// getSparkApplicationController returns the owner reference of the SparkApplication that controls the object, or nil
+// if the object is not controlled by a SparkApplication.
+func getSparkApplicationController(object metav1.Object) *metav1.OwnerReference {
+	owner := metav1.GetControllerOf(object)
+	if owner == nil || owner.Kind != "SparkApplication" {
+		return nil
+	}
+	if gv, err := schema.ParseGroupVersion(owner.APIVersion); err != nil || gv.Group != v1beta2.SchemeGroupVersion.Group {
+		return nil
+	}
+	return owner
+}
+
+// sweepOrphanedResources deletes the resources labelled with the name of a SparkApplication that no longer exists,
+// or that belong to an earlier SparkApplication of the same name. These are left behind, e.g., if the application
+// was deleted while the operator was down and its resources had no owner reference. Only resources that are labelled
+// as launched by the operator, or that are controlled by a SparkApplication, are deleted, so that resources a user
+// labelled with the name of an application are left alone.
+func (c *Controller) sweepOrphanedResources() {
+	glog.V(2).Infof("Sweeping orphaned Spark resources")
+	// The applications are looked up on the API server rather than in the informer cache, whose view may be stale.
+	apps := make(map[string]*v1beta2.SparkApplication)
+	sweep := func(apiVersion, kind string, object metav1.Object) {
+		if object.GetDeletionTimestamp() != nil {
+			return
+		}
+		owner := getSparkApplicationController(object)
+		if owner == nil && object.GetLabels()[config.LaunchedBySparkOperatorLabel] != "true" {
+			return
+		}
+		appName := object.GetLabels()[config.SparkAppNameLabel]
+		key := object.GetNamespace() + "/" + appName
+		app, found := apps[key]
+		if !found {
+			var err error
+			app, err = c.crdClient.SparkoperatorV1beta2().SparkApplications(object.GetNamespace()).Get(context.TODO(), appName, metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				app = nil
+			} else if err != nil {
+				glog.Errorf("failed to get SparkApplication %s: %v", key, err)
+				return
+			}
+			apps[key] = app
+		}
+		if app != nil && (owner == nil || owner.UID == app.UID) {
+			return
+		}
+
+		glog.Infof("Deleting %s %s/%s orphaned by SparkApplication %s", kind, object.GetNamespace(), object.GetName(), appName)
+		resource := v1beta2.ResourceReference{APIVersion: apiVersion, Kind: kind, Name: object.GetName(), UID: object.GetUID()}
+		if err := c.deleteResource(object.GetNamespace(), resource); err != nil {
+			glog.Errorf("failed to delete orphaned %s %s/%s: %v", kind, object.GetNamespace(), object.GetName(), err)
+		}
+	}
+
+	options := metav1.ListOptions{LabelSelector: config.SparkAppNameLabel}
+	driverOptions := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s,%s=%s,%s=true", config.SparkAppNameLabel,
+		config.SparkRoleLabel, config.SparkDriverRole, config.LaunchedBySparkOperatorLabel)}
+	if pods, err := c.kubeClient.CoreV1().Pods(c.namespace).List(context.TODO(), driverOptions); err != nil {
+		glog.Errorf("failed to list driver pods: %v", err)
+	} else {
+		for i := range pods.Items {
+			sweep(coreAPIVersion, podKind, &pods.Items[i])
+		}
+	}
+	if services, err := c.kubeClient.CoreV1().Services(c.namespace).List(context.TODO(), options); err != nil {
+		glog.Errorf("failed to list Spark UI services: %v", err)
+	} else {
+		for i := range services.Items {
+			sweep(coreAPIVersion, serviceKind, &services.Items[i])
+		}
+	}
+	if configMaps, err := c.kubeClient.CoreV1().ConfigMaps(c.namespace).List(context.TODO(), options); err != nil {
+		glog.Errorf("failed to list Prometheus ConfigMaps: %v", err)
+	} else {
+		for i := range configMaps.Items {
+			sweep(coreAPIVersion, configMapKind, &configMaps.Items[i])
+		}
+	}
+	if util.IngressCapabilities.Has(networkingAPIVersion) {
+		if ingresses, err := c.kubeClient.NetworkingV1().Ingresses(c.namespace).List(context.TODO(), options); err != nil {
+			glog.Errorf("failed to list Spark UI ingresses: %v", err)
+		} else {
+			for i := range ingresses.Items {
+				sweep(networkingAPIVersion, ingressKind, &ingresses.Items[i])
+			}
+		}
+	}
+	if util.IngressCapabilities.Has(extensionsAPIVersion) {
+		if ingresses, err := c.kubeClient.ExtensionsV1beta1().Ingresses(c.namespace).List(context.TODO(), options); err != nil {
+			glog.Errorf("failed to list extensions/v1beta1 Spark UI ingresses: %v", err)
+		} else {
+			for i := range ingresses.Items {
+				sweep(extensionsAPIVersion, ingressKind, &ingresses.Items[i])
+			}
+		}
+	}
+}
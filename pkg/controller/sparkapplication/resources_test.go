@@ -0,0 +1,207 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+func TestGetSparkResources(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status: v1beta2.SparkApplicationStatus{
+			DriverInfo: v1beta2.DriverInfo{WebUIServiceName: "foo-ui-svc", WebUIIngressName: "foo-ui-ingress"},
+		},
+	}
+	recordResource(app, coreAPIVersion, configMapKind, "foo-prom-conf", "uid-1")
+	recordResource(app, coreAPIVersion, serviceKind, "foo-ui-svc", "uid-2")
+	recordResource(app, coreAPIVersion, configMapKind, "foo-prom-conf", "uid-3")
+	assert.Equal(t, []v1beta2.ResourceReference{
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "foo-prom-conf", UID: "uid-3"},
+		{APIVersion: "v1", Kind: "Service", Name: "foo-ui-svc", UID: "uid-2"},
+	}, app.Status.Resources)
+
+	// Resources that were not recorded are derived from the status.
+	assert.Equal(t, []v1beta2.ResourceReference{
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "foo-prom-conf", UID: "uid-3"},
+		{APIVersion: "v1", Kind: "Service", Name: "foo-ui-svc", UID: "uid-2"},
+		{APIVersion: "v1", Kind: "Pod", Name: "foo-driver"},
+		{APIVersion: "networking.k8s.io/v1", Kind: "Ingress", Name: "foo-ui-ingress"},
+	}, getSparkResources(app))
+
+	app.Spec.Driver.Annotations = map[string]string{"scheduling.k8s.io/group-name": "spark-foo-pg"}
+	recordPodGroup(app)
+	assert.Equal(t, v1beta2.ResourceReference{APIVersion: "scheduling.volcano.sh/v1beta1", Kind: "PodGroup", Name: "spark-foo-pg"},
+		app.Status.Resources[2])
+}
+
+func TestDeleteSparkResources(t *testing.T) {
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState:   v1beta2.ApplicationState{State: v1beta2.FailingState},
+			DriverInfo: v1beta2.DriverInfo{PodName: "foo-driver"},
+			Resources: []v1beta2.ResourceReference{
+				{APIVersion: "v1", Kind: "Pod", Name: "foo-driver", UID: "uid-1"},
+				{APIVersion: "v1", Kind: "ConfigMap", Name: "foo-extra", UID: "uid-2"},
+			},
+		},
+	}
+	ctrl, _ := newFakeController(app)
+	ctrl.kubeClient.CoreV1().Pods(app.Namespace).Create(context.TODO(), &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo-driver", Namespace: "default", UID: "uid-1"}}, metav1.CreateOptions{})
+	ctrl.kubeClient.CoreV1().ConfigMaps(app.Namespace).Create(context.TODO(), &apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo-extra", Namespace: "default", UID: "uid-2"}}, metav1.CreateOptions{})
+	assert.False(t, ctrl.validateSparkResourceDeletion(app))
+
+	assert.Nil(t, ctrl.deleteSparkResources(app))
+	_, err := ctrl.kubeClient.CoreV1().Pods("default").Get(context.TODO(), "foo-driver", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	_, err = ctrl.kubeClient.CoreV1().ConfigMaps("default").Get(context.TODO(), "foo-extra", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	assert.True(t, ctrl.validateSparkResourceDeletion(app))
+
+	// A resource replaced by another of the same name counts as deleted.
+	ctrl.kubeClient.CoreV1().ConfigMaps(app.Namespace).Create(context.TODO(), &apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo-extra", Namespace: "default", UID: "uid-3"}}, metav1.CreateOptions{})
+	assert.True(t, ctrl.validateSparkResourceDeletion(app))
+}
+
+func TestSyncSparkApplication_RecordResources(t *testing.T) {
+	os.Setenv(sparkHomeEnvVar, "/spark")
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+	defer func() { execCommand = exec.Command }()
+	execCommand = helperCommand("TestHelperProcessSuccess")
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Monitoring: &v1beta2.MonitoringSpec{Prometheus: &v1beta2.PrometheusSpec{JmxExporterJar: "/prometheus/exporter.jar"}},
+		},
+	}
+	ctrl, _ := newFakeController(app)
+	_, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.SubmittedState, updatedApp.Status.AppState.State)
+	assert.Equal(t, []v1beta2.ResourceReference{
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "foo-prom-conf"},
+		{APIVersion: "v1", Kind: "Service", Name: "foo-ui-svc"},
+	}, updatedApp.Status.Resources)
+	configMap, err := ctrl.kubeClient.CoreV1().ConfigMaps("default").Get(context.TODO(), "foo-prom-conf", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", configMap.Labels[config.SparkAppNameLabel])
+
+	// The driver pod is recorded once it is found.
+	driverPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-driver",
+			Namespace: "default",
+			UID:       "uid-1",
+			Labels:    map[string]string{config.SparkRoleLabel: config.SparkDriverRole, config.SparkAppNameLabel: "foo"},
+		},
+		Status: apiv1.PodStatus{Phase: apiv1.PodRunning},
+	}
+	ctrl, _ = newFakeController(updatedApp, driverPod)
+	_, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), updatedApp, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.RunningState, updatedApp.Status.AppState.State)
+	assert.Equal(t, v1beta2.ResourceReference{APIVersion: "v1", Kind: "Pod", Name: "foo-driver", UID: "uid-1"},
+		updatedApp.Status.Resources[2])
+}
+
+func TestSweepOrphanedResources(t *testing.T) {
+	app := &v1beta2.SparkApplication{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "uid-2"}}
+	ctrl, _ := newFakeController(app)
+	_, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	objectMeta := func(name, appName string, ownerUID types.UID) metav1.ObjectMeta {
+		meta := metav1.ObjectMeta{Name: name, Namespace: "default"}
+		if appName != "" {
+			meta.Labels = map[string]string{config.SparkAppNameLabel: appName}
+		}
+		if ownerUID != "" {
+			isController := true
+			meta.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: v1beta2.SchemeGroupVersion.String(),
+				Kind:       "SparkApplication",
+				Name:       appName,
+				UID:        ownerUID,
+				Controller: &isController,
+			}}
+		}
+		return meta
+	}
+	driverMeta := objectMeta("bar-driver", "bar", "")
+	driverMeta.Labels[config.SparkRoleLabel] = config.SparkDriverRole
+	driverMeta.Labels[config.LaunchedBySparkOperatorLabel] = "true"
+	kubeClient := ctrl.kubeClient
+	kubeClient.CoreV1().Pods("default").Create(context.TODO(), &apiv1.Pod{ObjectMeta: driverMeta}, metav1.CreateOptions{})
+	kubeClient.CoreV1().Services("default").Create(context.TODO(), &apiv1.Service{ObjectMeta: objectMeta("foo-ui-svc", "foo", "uid-2")}, metav1.CreateOptions{})
+	kubeClient.CoreV1().Services("default").Create(context.TODO(), &apiv1.Service{ObjectMeta: objectMeta("other-svc", "", "")}, metav1.CreateOptions{})
+	kubeClient.CoreV1().ConfigMaps("default").Create(context.TODO(), &apiv1.ConfigMap{ObjectMeta: objectMeta("foo-prom-conf", "foo", "uid-1")}, metav1.CreateOptions{})
+	kubeClient.CoreV1().ConfigMaps("default").Create(context.TODO(), &apiv1.ConfigMap{ObjectMeta: objectMeta("bar-prom-conf", "bar", "uid-3")}, metav1.CreateOptions{})
+	kubeClient.CoreV1().ConfigMaps("default").Create(context.TODO(), &apiv1.ConfigMap{ObjectMeta: objectMeta("bar-user-conf", "bar", "")}, metav1.CreateOptions{})
+	otherOwnerMeta := objectMeta("bar-other-conf", "bar", "uid-4")
+	otherOwnerMeta.OwnerReferences[0].APIVersion = "example.com/v1"
+	kubeClient.CoreV1().ConfigMaps("default").Create(context.TODO(), &apiv1.ConfigMap{ObjectMeta: otherOwnerMeta}, metav1.CreateOptions{})
+
+	ctrl.sweepOrphanedResources()
+
+	// Resources of deleted applications, or of an earlier application of the same name, are deleted.
+	_, err = kubeClient.CoreV1().Pods("default").Get(context.TODO(), "bar-driver", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	_, err = kubeClient.CoreV1().ConfigMaps("default").Get(context.TODO(), "bar-prom-conf", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	_, err = kubeClient.CoreV1().ConfigMaps("default").Get(context.TODO(), "foo-prom-conf", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	// Resources of existing applications, and resources not labelled by the operator, are kept.
+	_, err = kubeClient.CoreV1().Services("default").Get(context.TODO(), "foo-ui-svc", metav1.GetOptions{})
+	assert.Nil(t, err)
+	_, err = kubeClient.CoreV1().Services("default").Get(context.TODO(), "other-svc", metav1.GetOptions{})
+	assert.Nil(t, err)
+	// Resources labelled with the name of a deleted application are kept unless they were launched by the operator
+	// or are controlled by a SparkApplication.
+	_, err = kubeClient.CoreV1().ConfigMaps("default").Get(context.TODO(), "bar-user-conf", metav1.GetOptions{})
+	assert.Nil(t, err)
+	_, err = kubeClient.CoreV1().ConfigMaps("default").Get(context.TODO(), "bar-other-conf", metav1.GetOptions{})
+	assert.Nil(t, err)
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+)
+
+const (
+	defaultSidecarQuitPath = "/quitquitquit"
+	sidecarQuitTimeout     = 5 * time.Second
+	// maxSidecarQuitRequests is the number of requests asking sidecars to exit that are sent at the same time.
+	maxSidecarQuitRequests = 10
+	// maxSidecarQuitAttempts is the number of times the sidecars of a pod are asked to exit.
+	maxSidecarQuitAttempts = 3
+	// sidecarQuitForgetAfter is how long the attempts for a pod are remembered after the last one.
+	sidecarQuitForgetAfter = time.Hour
+)
+
+var sidecarQuitClient = &http.Client{Timeout: sidecarQuitTimeout}
+
+// sidecarTerminator asks the sidecars of pods whose main Spark container has terminated to exit, so that the pods
+// terminate too. Requests are sent in the background, a bounded number at a time, so that unreachable pods do not
+// block the controller workers, and the sidecars of a pod are asked at most maxSidecarQuitAttempts times.
+type sidecarTerminator struct {
+	slots chan struct{}
+	// done is used by tests to wait for the requests sent.
+	done sync.WaitGroup
+
+	mutex     sync.Mutex
+	pods      map[types.UID]*sidecarQuitAttempts
+	lastPrune time.Time
+}
+
+type sidecarQuitAttempts struct {
+	count    int
+	inFlight bool
+	last     time.Time
+}
+
+func newSidecarTerminator() *sidecarTerminator {
+	return &sidecarTerminator{
+		slots: make(chan struct{}, maxSidecarQuitRequests),
+		pods:  make(map[types.UID]*sidecarQuitAttempts),
+	}
+}
+
+// terminate asks the sidecars of the pod to exit. It does nothing unless the pod is still running and sidecar
+// termination is configured, or if a request for the pod is already in flight, the attempts for the pod are used
+// up, or all request slots are taken, in which case the next sync tries again.
+func (t *sidecarTerminator) terminate(pod *apiv1.Pod, termination *v1beta2.SidecarTermination) {
+	if termination == nil || pod.Status.Phase != apiv1.PodRunning || pod.Status.PodIP == "" {
+		return
+	}
+	if !t.startAttempt(pod.UID) {
+		return
+	}
+	select {
+	case t.slots <- struct{}{}:
+	default:
+		t.cancelAttempt(pod.UID)
+		return
+	}
+
+	path := defaultSidecarQuitPath
+	if termination.QuitPath != nil {
+		path = *termination.QuitPath
+	}
+	url := fmt.Sprintf("http://%s%s", net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(termination.QuitPort))), path)
+	t.done.Add(1)
+	go func() {
+		defer t.done.Done()
+		defer func() { <-t.slots }()
+		t.endAttempt(pod.UID, sendSidecarQuitRequest(pod, url))
+	}()
+}
+
+// startAttempt returns whether the sidecars of the pod with the given UID are to be asked to exit and marks the
+// request as in flight if so.
+func (t *sidecarTerminator) startAttempt(uid types.UID) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(t.lastPrune) > sidecarQuitForgetAfter {
+		for podUID, attempts := range t.pods {
+			if !attempts.inFlight && now.Sub(attempts.last) > sidecarQuitForgetAfter {
+				delete(t.pods, podUID)
+			}
+		}
+		t.lastPrune = now
+	}
+
+	attempts, exists := t.pods[uid]
+	if !exists {
+		attempts = &sidecarQuitAttempts{}
+		t.pods[uid] = attempts
+	}
+	if attempts.inFlight || attempts.count >= maxSidecarQuitAttempts {
+		return false
+	}
+	attempts.inFlight = true
+	return true
+}
+
+// endAttempt records the end of a request for the pod with the given UID. The sidecars are not asked again once a
+// request succeeded.
+func (t *sidecarTerminator) endAttempt(uid types.UID, succeeded bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	attempts := t.pods[uid]
+	attempts.inFlight = false
+	attempts.count++
+	if succeeded {
+		attempts.count = maxSidecarQuitAttempts
+	}
+	attempts.last = time.Now()
+}
+
+// cancelAttempt clears the request for the pod with the given UID that could not be sent. It does not count as an
+// attempt.
+func (t *sidecarTerminator) cancelAttempt(uid types.UID) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.pods[uid].inFlight = false
+}
+
+func sendSidecarQuitRequest(pod *apiv1.Pod, url string) bool {
+	glog.V(2).Infof("Asking the sidecars of pod %s/%s to exit at %s", pod.Namespace, pod.Name, url)
+	response, err := sidecarQuitClient.Post(url, "", nil)
+	if err != nil {
+		glog.Warningf("failed to ask the sidecars of pod %s/%s to exit: %v", pod.Namespace, pod.Name, err)
+		return false
+	}
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		glog.Warningf("failed to ask the sidecars of pod %s/%s to exit: %s returned %s", pod.Namespace, pod.Name, url, response.Status)
+		return false
+	}
+	return true
+}
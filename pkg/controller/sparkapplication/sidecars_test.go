@@ -0,0 +1,162 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
+)
+
+// newSidecarServer returns a server recording the requests it receives and answering them with the given status.
+func newSidecarServer(status int) (*httptest.Server, func() []string) {
+	var mutex sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(status)
+	}))
+	return server, func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		sorted := append([]string(nil), requests...)
+		sort.Strings(sorted)
+		return sorted
+	}
+}
+
+func sidecarServerAddress(server *httptest.Server) (string, int) {
+	serverURL, _ := url.Parse(server.URL)
+	host, portString, _ := net.SplitHostPort(serverURL.Host)
+	port, _ := strconv.Atoi(portString)
+	return host, port
+}
+
+func TestSyncSparkApplication_SidecarTermination(t *testing.T) {
+	server, requests := newSidecarServer(http.StatusOK)
+	defer server.Close()
+	host, port := sidecarServerAddress(server)
+
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: v1beta2.SparkApplicationSpec{
+			Driver: v1beta2.DriverSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{SidecarTermination: &v1beta2.SidecarTermination{QuitPort: int32(port)}},
+			},
+			Executor: v1beta2.ExecutorSpec{
+				SparkPodSpec: v1beta2.SparkPodSpec{
+					SidecarTermination: &v1beta2.SidecarTermination{QuitPort: int32(port), QuitPath: stringptr("/shutdown")},
+				},
+			},
+		},
+		Status: v1beta2.SparkApplicationStatus{
+			AppState:   v1beta2.ApplicationState{State: v1beta2.RunningState},
+			DriverInfo: v1beta2.DriverInfo{PodName: "foo-driver"},
+		},
+	}
+	newPod := func(name, role, containerName string) *apiv1.Pod {
+		return &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				UID:       types.UID(name),
+				Labels:    map[string]string{config.SparkRoleLabel: role, config.SparkAppNameLabel: "foo"},
+			},
+			Status: apiv1.PodStatus{
+				Phase: apiv1.PodRunning,
+				PodIP: host,
+				ContainerStatuses: []apiv1.ContainerStatus{
+					{Name: containerName, State: apiv1.ContainerState{Terminated: &apiv1.ContainerStateTerminated{ExitCode: 0}}},
+					{Name: "istio-proxy", State: apiv1.ContainerState{Running: &apiv1.ContainerStateRunning{}}},
+				},
+			},
+		}
+	}
+	driverPod := newPod("foo-driver", config.SparkDriverRole, config.SparkDriverContainerName)
+	executorPod := newPod("foo-exec-1", config.SparkExecutorRole, config.Spark3DefaultExecutorContainerName)
+	ctrl, _ := newFakeController(app, driverPod, executorPod)
+	_, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctrl.syncSparkApplication("default/foo")
+	assert.Nil(t, err)
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	// The application completes although its pods keep running because of their sidecars.
+	assert.Equal(t, v1beta2.SucceedingState, updatedApp.Status.AppState.State)
+	if assert.Len(t, updatedApp.Status.Executors, 1) {
+		assert.Equal(t, v1beta2.ExecutorCompletedState, updatedApp.Status.Executors[0].State)
+	}
+	ctrl.sidecarTerminator.done.Wait()
+	assert.Equal(t, []string{"POST /quitquitquit", "POST /shutdown"}, requests())
+
+	// The sidecars are not asked again once they accepted.
+	ctrl.sidecarTerminator.terminate(driverPod, app.Spec.Driver.SidecarTermination)
+	ctrl.sidecarTerminator.done.Wait()
+	assert.Len(t, requests(), 2)
+}
+
+func TestSidecarTerminatorAttempts(t *testing.T) {
+	server, requests := newSidecarServer(http.StatusServiceUnavailable)
+	defer server.Close()
+	host, port := sidecarServerAddress(server)
+
+	pod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-exec-1", Namespace: "default", UID: "foo-exec-1"},
+		Status:     apiv1.PodStatus{Phase: apiv1.PodRunning, PodIP: host},
+	}
+	termination := &v1beta2.SidecarTermination{QuitPort: int32(port)}
+	terminator := newSidecarTerminator()
+	for i := 0; i < maxSidecarQuitAttempts+2; i++ {
+		terminator.terminate(pod, termination)
+		terminator.done.Wait()
+	}
+	assert.Len(t, requests(), maxSidecarQuitAttempts)
+
+	// No request is sent while all slots are taken.
+	pod.UID = "foo-exec-2"
+	for i := 0; i < maxSidecarQuitRequests; i++ {
+		terminator.slots <- struct{}{}
+	}
+	terminator.terminate(pod, termination)
+	terminator.done.Wait()
+	assert.Len(t, requests(), maxSidecarQuitAttempts)
+	for i := 0; i < maxSidecarQuitRequests; i++ {
+		<-terminator.slots
+	}
+	terminator.terminate(pod, termination)
+	terminator.done.Wait()
+	assert.Len(t, requests(), maxSidecarQuitAttempts+1)
+}
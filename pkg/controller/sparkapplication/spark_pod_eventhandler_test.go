@@ -79,7 +79,7 @@ func TestOnPodAdded(t *testing.T) {
 				config.SparkRoleLabel:                config.SparkExecutorRole,
 				config.SparkApplicationSelectorLabel: "foo-123",
 				config.SparkAppNameLabel:             appName,
-				sparkExecutorIDLabel:                 "1",
+				config.SparkExecutorIDLabel:          "1",
 			},
 		},
 		Status: apiv1.PodStatus{
@@ -165,7 +165,7 @@ func TestOnPodUpdated(t *testing.T) {
 				config.SparkRoleLabel:                config.SparkExecutorRole,
 				config.SparkApplicationSelectorLabel: "foo-123",
 				config.SparkAppNameLabel:             appName,
-				sparkExecutorIDLabel:                 "1",
+				config.SparkExecutorIDLabel:          "1",
 			},
 			ResourceVersion: "1",
 		},
@@ -250,7 +250,7 @@ func TestOnPodDeleted(t *testing.T) {
 				config.SparkRoleLabel:                config.SparkExecutorRole,
 				config.SparkApplicationSelectorLabel: "foo-123",
 				config.SparkAppNameLabel:             appName,
-				sparkExecutorIDLabel:                 "1",
+				config.SparkExecutorIDLabel:          "1",
 			},
 		},
 		Status: apiv1.PodStatus{
@@ -26,6 +26,12 @@ import (
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
 )
 
+// reasonLabel is the metric label telling why a driver container failed to start.
+const reasonLabel = "reason"
+
+// progressLabels are the metric labels of the progress gauges, which are exported per application.
+var progressLabels = []string{"namespace", "name"}
+
 type sparkAppMetrics struct {
 	labels []string
 	prefix string
@@ -35,6 +41,7 @@ type sparkAppMetrics struct {
 	sparkAppSuccessCount          *prometheus.CounterVec
 	sparkAppFailureCount          *prometheus.CounterVec
 	sparkAppFailedSubmissionCount *prometheus.CounterVec
+	sparkAppDriverErrorCount      *prometheus.CounterVec
 	sparkAppRunningCount          *util.PositiveGauge
 
 	sparkAppSuccessExecutionTime  *prometheus.SummaryVec
@@ -45,6 +52,15 @@ type sparkAppMetrics struct {
 	sparkAppExecutorRunningCount *util.PositiveGauge
 	sparkAppExecutorFailureCount *prometheus.CounterVec
 	sparkAppExecutorSuccessCount *prometheus.CounterVec
+
+	sparkAppSubmissionQueueDepth prometheus.Gauge
+	sparkAppSubmissionDuration   *prometheus.HistogramVec
+
+	sparkAppProgressJobs         *prometheus.GaugeVec
+	sparkAppProgressStages       *prometheus.GaugeVec
+	sparkAppProgressTasks        *prometheus.GaugeVec
+	sparkAppProgressShuffleBytes *prometheus.GaugeVec
+	sparkAppProgressExecutors    *prometheus.GaugeVec
 }
 
 func newSparkAppMetrics(metricsConfig *util.MetricConfig) *sparkAppMetrics {
@@ -90,6 +106,13 @@ func newSparkAppMetrics(metricsConfig *util.MetricConfig) *sparkAppMetrics {
 		},
 		validLabels,
 	)
+	sparkAppDriverErrorCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_driver_container_error_count"),
+			Help: "Spark App Driver Containers Failing to Start via the Operator, by reason",
+		},
+		append(append([]string{}, validLabels...), reasonLabel),
+	)
 	sparkAppSuccessExecutionTime := prometheus.NewSummaryVec(
 		prometheus.SummaryOpts{
 			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_success_execution_time_microseconds"),
@@ -138,6 +161,57 @@ func newSparkAppMetrics(metricsConfig *util.MetricConfig) *sparkAppMetrics {
 	sparkAppExecutorRunningCount := util.NewPositiveGauge(util.CreateValidMetricNameLabel(prefix,
 		"spark_app_executor_running_count"), "Spark App Running Executor Count via the Operator", validLabels)
 
+	sparkAppSubmissionQueueDepth := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_submission_queue_depth"),
+			Help: "Number of Spark Apps waiting for a free submission slot",
+		},
+	)
+	sparkAppSubmissionDuration := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    util.CreateValidMetricNameLabel(prefix, "spark_app_submission_duration_seconds"),
+			Help:    "Duration of spark-submit runs by result (success, failure or timeout)",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+		},
+		[]string{"result"},
+	)
+
+	sparkAppProgressJobs := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_progress_jobs"),
+			Help: "Jobs of running Spark Apps by status (active, completed or failed), as reported by their driver",
+		},
+		append(append([]string{}, progressLabels...), "status"),
+	)
+	sparkAppProgressStages := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_progress_stages"),
+			Help: "Stages of running Spark Apps by status (active, completed, failed or pending), as reported by their driver",
+		},
+		append(append([]string{}, progressLabels...), "status"),
+	)
+	sparkAppProgressTasks := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_progress_tasks"),
+			Help: "Tasks of running Spark Apps by status (active, completed, failed or pending), as reported by their driver",
+		},
+		append(append([]string{}, progressLabels...), "status"),
+	)
+	sparkAppProgressShuffleBytes := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_progress_shuffle_bytes"),
+			Help: "Bytes shuffled by running Spark Apps by direction (read or write), as reported by their driver",
+		},
+		append(append([]string{}, progressLabels...), "direction"),
+	)
+	sparkAppProgressExecutors := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: util.CreateValidMetricNameLabel(prefix, "spark_app_progress_executors"),
+			Help: "Active executors of running Spark Apps, as reported by their driver",
+		},
+		progressLabels,
+	)
+
 	return &sparkAppMetrics{
 		labels:                        validLabels,
 		prefix:                        prefix,
@@ -147,6 +221,7 @@ func newSparkAppMetrics(metricsConfig *util.MetricConfig) *sparkAppMetrics {
 		sparkAppSuccessCount:          sparkAppSuccessCount,
 		sparkAppFailureCount:          sparkAppFailureCount,
 		sparkAppFailedSubmissionCount: sparkAppFailedSubmissionCount,
+		sparkAppDriverErrorCount:      sparkAppDriverErrorCount,
 		sparkAppSuccessExecutionTime:  sparkAppSuccessExecutionTime,
 		sparkAppFailureExecutionTime:  sparkAppFailureExecutionTime,
 		sparkAppStartLatency:          sparkAppStartLatency,
@@ -154,6 +229,13 @@ func newSparkAppMetrics(metricsConfig *util.MetricConfig) *sparkAppMetrics {
 		sparkAppExecutorRunningCount:  sparkAppExecutorRunningCount,
 		sparkAppExecutorSuccessCount:  sparkAppExecutorSuccessCount,
 		sparkAppExecutorFailureCount:  sparkAppExecutorFailureCount,
+		sparkAppSubmissionQueueDepth:  sparkAppSubmissionQueueDepth,
+		sparkAppSubmissionDuration:    sparkAppSubmissionDuration,
+		sparkAppProgressJobs:          sparkAppProgressJobs,
+		sparkAppProgressStages:        sparkAppProgressStages,
+		sparkAppProgressTasks:         sparkAppProgressTasks,
+		sparkAppProgressShuffleBytes:  sparkAppProgressShuffleBytes,
+		sparkAppProgressExecutors:     sparkAppProgressExecutors,
 	}
 }
 
@@ -162,12 +244,20 @@ func (sm *sparkAppMetrics) registerMetrics() {
 	util.RegisterMetric(sm.sparkAppSubmitCount)
 	util.RegisterMetric(sm.sparkAppSuccessCount)
 	util.RegisterMetric(sm.sparkAppFailureCount)
+	util.RegisterMetric(sm.sparkAppDriverErrorCount)
 	util.RegisterMetric(sm.sparkAppSuccessExecutionTime)
 	util.RegisterMetric(sm.sparkAppFailureExecutionTime)
 	util.RegisterMetric(sm.sparkAppStartLatency)
 	util.RegisterMetric(sm.sparkAppStartLatencyHistogram)
 	util.RegisterMetric(sm.sparkAppExecutorSuccessCount)
 	util.RegisterMetric(sm.sparkAppExecutorFailureCount)
+	util.RegisterMetric(sm.sparkAppSubmissionQueueDepth)
+	util.RegisterMetric(sm.sparkAppSubmissionDuration)
+	util.RegisterMetric(sm.sparkAppProgressJobs)
+	util.RegisterMetric(sm.sparkAppProgressStages)
+	util.RegisterMetric(sm.sparkAppProgressTasks)
+	util.RegisterMetric(sm.sparkAppProgressShuffleBytes)
+	util.RegisterMetric(sm.sparkAppProgressExecutors)
 	sm.sparkAppRunningCount.Register()
 	sm.sparkAppExecutorRunningCount.Register()
 }
@@ -178,12 +268,13 @@ func (sm *sparkAppMetrics) exportMetricsOnDelete(oldApp *v1beta2.SparkApplicatio
 	if oldState == v1beta2.RunningState {
 		sm.sparkAppRunningCount.Dec(metricLabels)
 	}
-	for executor, oldExecState := range oldApp.Status.ExecutorState {
-		if oldExecState == v1beta2.ExecutorRunningState {
-			glog.V(2).Infof("Application is deleted. Decreasing Running Count for Executor %s.", executor)
+	for _, executor := range oldApp.Status.Executors {
+		if executor.State == v1beta2.ExecutorRunningState {
+			glog.V(2).Infof("Application is deleted. Decreasing Running Count for Executor %s.", executor.PodName)
 			sm.sparkAppExecutorRunningCount.Dec(metricLabels)
 		}
 	}
+	sm.deleteProgressMetrics(oldApp)
 }
 
 func (sm *sparkAppMetrics) exportMetrics(oldApp, newApp *v1beta2.SparkApplication) {
@@ -240,12 +331,29 @@ func (sm *sparkAppMetrics) exportMetrics(oldApp, newApp *v1beta2.SparkApplicatio
 			} else {
 				m.Inc()
 			}
+			switch reason := newApp.Status.TerminationReason; reason {
+			case v1beta2.ImagePullFailureReason, v1beta2.ContainerConfigErrorReason:
+				reasonLabels := map[string]string{reasonLabel: string(reason)}
+				for label, value := range metricLabels {
+					reasonLabels[label] = value
+				}
+				if m, err := sm.sparkAppDriverErrorCount.GetMetricWith(reasonLabels); err != nil {
+					glog.Errorf("Error while exporting metrics: %v", err)
+				} else {
+					m.Inc()
+				}
+			}
 		case v1beta2.FailedSubmissionState:
 			if m, err := sm.sparkAppFailedSubmissionCount.GetMetricWith(metricLabels); err != nil {
 				glog.Errorf("Error while exporting metrics: %v", err)
 			} else {
 				m.Inc()
 			}
+		case v1beta2.SuspendingState, v1beta2.SuspendedState:
+			// A running application whose resources are deleted right away is suspended within a single sync.
+			if oldState == v1beta2.RunningState {
+				sm.sparkAppRunningCount.Dec(metricLabels)
+			}
 		}
 	}
 
@@ -261,9 +369,15 @@ func (sm *sparkAppMetrics) exportMetrics(oldApp, newApp *v1beta2.SparkApplicatio
 		}
 	}
 
-	oldExecutorStates := oldApp.Status.ExecutorState
+	// Executors are only rolled up once they were listed as terminated, so every transition shows in the listed
+	// executors.
+	oldExecutorStates := make(map[string]v1beta2.ExecutorState, len(oldApp.Status.Executors))
+	for _, executor := range oldApp.Status.Executors {
+		oldExecutorStates[executor.PodName] = executor.State
+	}
 	// Potential Executor status updates
-	for executor, newExecState := range newApp.Status.ExecutorState {
+	for _, newExecutor := range newApp.Status.Executors {
+		executor, newExecState := newExecutor.PodName, newExecutor.State
 		switch newExecState {
 		case v1beta2.ExecutorRunningState:
 			if oldExecutorStates[executor] != newExecState {
@@ -295,6 +409,64 @@ func (sm *sparkAppMetrics) exportMetrics(oldApp, newApp *v1beta2.SparkApplicatio
 			}
 		}
 	}
+
+	sm.exportProgressMetrics(newApp)
+}
+
+// exportProgressMetrics exports the progress of a running application, and deletes it once the application stops
+// running.
+func (sm *sparkAppMetrics) exportProgressMetrics(app *v1beta2.SparkApplication) {
+	progress := app.Status.Progress
+	if app.Status.AppState.State != v1beta2.RunningState || progress == nil {
+		sm.deleteProgressMetrics(app)
+		return
+	}
+	labels := prometheus.Labels{"namespace": app.Namespace, "name": app.Name}
+	setGauge := func(gauge *prometheus.GaugeVec, label, value string, count int64) {
+		gaugeLabels := prometheus.Labels{label: value}
+		for k, v := range labels {
+			gaugeLabels[k] = v
+		}
+		if m, err := gauge.GetMetricWith(gaugeLabels); err != nil {
+			glog.Errorf("Error while exporting metrics: %v", err)
+		} else {
+			m.Set(float64(count))
+		}
+	}
+	setGauge(sm.sparkAppProgressJobs, "status", "active", int64(progress.ActiveJobs))
+	setGauge(sm.sparkAppProgressJobs, "status", "completed", int64(progress.CompletedJobs))
+	setGauge(sm.sparkAppProgressJobs, "status", "failed", int64(progress.FailedJobs))
+	setGauge(sm.sparkAppProgressStages, "status", "active", int64(progress.ActiveStages))
+	setGauge(sm.sparkAppProgressStages, "status", "completed", int64(progress.CompletedStages))
+	setGauge(sm.sparkAppProgressStages, "status", "failed", int64(progress.FailedStages))
+	setGauge(sm.sparkAppProgressStages, "status", "pending",
+		int64(progress.TotalStages-progress.ActiveStages-progress.CompletedStages-progress.FailedStages))
+	setGauge(sm.sparkAppProgressTasks, "status", "active", int64(progress.ActiveTasks))
+	setGauge(sm.sparkAppProgressTasks, "status", "completed", int64(progress.CompletedTasks))
+	setGauge(sm.sparkAppProgressTasks, "status", "failed", int64(progress.FailedTasks))
+	setGauge(sm.sparkAppProgressTasks, "status", "pending",
+		int64(progress.TotalTasks-progress.ActiveTasks-progress.CompletedTasks))
+	setGauge(sm.sparkAppProgressShuffleBytes, "direction", "read", progress.ShuffleReadBytes)
+	setGauge(sm.sparkAppProgressShuffleBytes, "direction", "write", progress.ShuffleWriteBytes)
+	if m, err := sm.sparkAppProgressExecutors.GetMetricWith(labels); err != nil {
+		glog.Errorf("Error while exporting metrics: %v", err)
+	} else {
+		m.Set(float64(progress.ActiveExecutors))
+	}
+}
+
+func (sm *sparkAppMetrics) deleteProgressMetrics(app *v1beta2.SparkApplication) {
+	labels := prometheus.Labels{"namespace": app.Namespace, "name": app.Name}
+	deleteGauge := func(gauge *prometheus.GaugeVec, label string, values ...string) {
+		for _, value := range values {
+			gauge.Delete(prometheus.Labels{"namespace": labels["namespace"], "name": labels["name"], label: value})
+		}
+	}
+	deleteGauge(sm.sparkAppProgressJobs, "status", "active", "completed", "failed")
+	deleteGauge(sm.sparkAppProgressStages, "status", "active", "completed", "failed", "pending")
+	deleteGauge(sm.sparkAppProgressTasks, "status", "active", "completed", "failed", "pending")
+	deleteGauge(sm.sparkAppProgressShuffleBytes, "direction", "read", "write")
+	sm.sparkAppProgressExecutors.Delete(labels)
 }
 
 func (sm *sparkAppMetrics) exportJobStartLatencyMetrics(app *v1beta2.SparkApplication, labels map[string]string) {
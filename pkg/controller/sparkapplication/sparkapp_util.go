@@ -19,6 +19,7 @@ package sparkapplication
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/kubernetes/pkg/apis/policy"
@@ -110,11 +111,19 @@ func getIngressTlsHosts(app *v1beta2.SparkApplication) []networkingv1.IngressTLS
 	return ingressTls
 }
 
-func podPhaseToExecutorState(podPhase apiv1.PodPhase) v1beta2.ExecutorState {
-	switch podPhase {
+func podStatusToExecutorState(podStatus apiv1.PodStatus) v1beta2.ExecutorState {
+	switch podStatus.Phase {
 	case apiv1.PodPending:
 		return v1beta2.ExecutorPendingState
 	case apiv1.PodRunning:
+		// The pod keeps running after the executor container terminates if it has sidecars.
+		state := getExecutorContainerTerminatedState(podStatus)
+		if state != nil {
+			if state.ExitCode == 0 {
+				return v1beta2.ExecutorCompletedState
+			}
+			return v1beta2.ExecutorFailedState
+		}
 		return v1beta2.ExecutorRunningState
 	case apiv1.PodSucceeded:
 		return v1beta2.ExecutorCompletedState
@@ -146,22 +155,112 @@ func getExecutorContainerTerminatedState(podStatus apiv1.PodStatus) *apiv1.Conta
 }
 
 func getContainerTerminatedState(name string, podStatus apiv1.PodStatus) *apiv1.ContainerStateTerminated {
+	if c := getContainerStatus(name, podStatus); c != nil {
+		return c.State.Terminated
+	}
+	return nil
+}
+
+func getExecutorContainerStatus(podStatus apiv1.PodStatus) *apiv1.ContainerStatus {
+	status := getContainerStatus(config.Spark3DefaultExecutorContainerName, podStatus)
+	if status == nil {
+		status = getContainerStatus(config.SparkExecutorContainerName, podStatus)
+	}
+	return status
+}
+
+func getContainerStatus(name string, podStatus apiv1.PodStatus) *apiv1.ContainerStatus {
+	for i := range podStatus.ContainerStatuses {
+		if podStatus.ContainerStatuses[i].Name == name {
+			return &podStatus.ContainerStatuses[i]
+		}
+	}
+	return nil
+}
+
+// getDriverTerminationReason classifies why a failed driver pod terminated. It returns an empty reason if the
+// driver failed for another reason, e.g. an error in the application.
+func getDriverTerminationReason(pod *apiv1.Pod) v1beta2.TerminationReason {
+	if state := getDriverContainerTerminatedState(pod.Status); state != nil && state.Reason == oomKilledReason {
+		return v1beta2.OOMKilledReason
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type != podDisruptionTargetCondition || condition.Status != apiv1.ConditionTrue {
+			continue
+		}
+		switch condition.Reason {
+		case "PreemptionByKubeScheduler", "PreemptionByScheduler":
+			return v1beta2.PreemptedReason
+		case "DeletionByTaintManager", "DeletionByPodGC":
+			return v1beta2.NodeLostReason
+		case "EvictionByEvictionAPI", "TerminationByKubelet":
+			return v1beta2.EvictedReason
+		}
+	}
+	switch pod.Status.Reason {
+	case "Evicted":
+		return v1beta2.EvictedReason
+	case "NodeLost", "Shutdown", "NodeShutdown", "Terminated":
+		return v1beta2.NodeLostReason
+	case "Preempting":
+		return v1beta2.PreemptedReason
+	}
+	if waiting := getDriverContainerWaitingError(pod.Status); waiting != nil {
+		if waiting.Reason == createContainerConfigErrorReason {
+			return v1beta2.ContainerConfigErrorReason
+		}
+		return v1beta2.ImagePullFailureReason
+	}
+	return ""
+}
+
+// getDriverContainerWaitingError returns the waiting state of the driver container if it cannot start because of an
+// error that does not go away by waiting: an invalid image name or an image pull the kubelet backs off from right
+// away, and a failed image pull or a missing Secret or ConfigMap once the driver pod has been started for longer than
+// driverContainerErrorGracePeriod.
+func getDriverContainerWaitingError(podStatus apiv1.PodStatus) *apiv1.ContainerStateWaiting {
+	waiting := getDriverContainerWaitingState(podStatus)
+	if waiting == nil {
+		return nil
+	}
+	switch waiting.Reason {
+	case "ImagePullBackOff", "InvalidImageName":
+		return waiting
+	case errImagePullReason, createContainerConfigErrorReason:
+		if driverContainerErrorGraceRemaining(podStatus) <= 0 {
+			return waiting
+		}
+	}
+	return nil
+}
+
+func getDriverContainerWaitingState(podStatus apiv1.PodStatus) *apiv1.ContainerStateWaiting {
 	for _, c := range podStatus.ContainerStatuses {
-		if c.Name == name {
-			if c.State.Terminated != nil {
-				return c.State.Terminated
-			}
-			return nil
+		if c.Name == config.SparkDriverContainerName {
+			return c.State.Waiting
 		}
 	}
 	return nil
 }
 
+// driverContainerErrorGraceRemaining returns how much longer the driver container may fail to be pulled or created,
+// counted from the start of the driver pod.
+func driverContainerErrorGraceRemaining(podStatus apiv1.PodStatus) time.Duration {
+	if podStatus.StartTime == nil {
+		return driverContainerErrorGracePeriod
+	}
+	return driverContainerErrorGracePeriod - time.Since(podStatus.StartTime.Time)
+}
+
 func podStatusToDriverState(podStatus apiv1.PodStatus) v1beta2.DriverState {
 	switch podStatus.Phase {
 	case apiv1.PodPending:
+		if getDriverContainerWaitingError(podStatus) != nil {
+			return v1beta2.DriverFailedState
+		}
 		return v1beta2.DriverPendingState
 	case apiv1.PodRunning:
+		// The pod keeps running after the driver container terminates if it has sidecars.
 		state := getDriverContainerTerminatedState(podStatus)
 		if state != nil {
 			if state.ExitCode == 0 {
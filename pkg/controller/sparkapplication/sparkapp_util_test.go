@@ -18,8 +18,14 @@ package sparkapplication
 
 import (
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/config"
 )
 
 var expectedStatusString = `{
@@ -31,9 +37,15 @@ var expectedStatusString = `{
   "applicationState": {
     "state": "COMPLETED"
   },
-  "executorState": {
-    "executor-1": "COMPLETED"
-  }
+  "executors": [
+    {
+      "podName": "executor-1",
+      "state": "COMPLETED",
+      "startTime": null,
+      "endTime": null
+    }
+  ],
+  "nextRetryTime": null
 }`
 
 func TestPrintStatus(t *testing.T) {
@@ -43,8 +55,8 @@ func TestPrintStatus(t *testing.T) {
 		AppState: v1beta2.ApplicationState{
 			State: v1beta2.CompletedState,
 		},
-		ExecutorState: map[string]v1beta2.ExecutorState{
-			"executor-1": v1beta2.ExecutorCompletedState,
+		Executors: []v1beta2.ExecutorStatus{
+			{PodName: "executor-1", State: v1beta2.ExecutorCompletedState},
 		},
 	}
 
@@ -57,3 +69,79 @@ func TestPrintStatus(t *testing.T) {
 		t.Errorf("status string\n %s is different from expected status string\n %s", statusString, expectedStatusString)
 	}
 }
+
+func TestGetDriverTerminationReason(t *testing.T) {
+	terminated := func(reason string) apiv1.ContainerStatus {
+		return apiv1.ContainerStatus{
+			Name:  config.SparkDriverContainerName,
+			State: apiv1.ContainerState{Terminated: &apiv1.ContainerStateTerminated{ExitCode: 137, Reason: reason}},
+		}
+	}
+	testcases := []struct {
+		status   apiv1.PodStatus
+		expected v1beta2.TerminationReason
+	}{
+		{apiv1.PodStatus{ContainerStatuses: []apiv1.ContainerStatus{terminated("OOMKilled")}}, v1beta2.OOMKilledReason},
+		{apiv1.PodStatus{ContainerStatuses: []apiv1.ContainerStatus{terminated("Error")}}, ""},
+		{apiv1.PodStatus{Reason: "Evicted"}, v1beta2.EvictedReason},
+		{apiv1.PodStatus{Reason: "NodeLost"}, v1beta2.NodeLostReason},
+		{apiv1.PodStatus{Reason: "Shutdown"}, v1beta2.NodeLostReason},
+		{apiv1.PodStatus{Conditions: []apiv1.PodCondition{{Type: podDisruptionTargetCondition, Status: apiv1.ConditionTrue, Reason: "PreemptionByKubeScheduler"}}}, v1beta2.PreemptedReason},
+		{apiv1.PodStatus{Conditions: []apiv1.PodCondition{{Type: podDisruptionTargetCondition, Status: apiv1.ConditionTrue, Reason: "DeletionByTaintManager"}}}, v1beta2.NodeLostReason},
+		{apiv1.PodStatus{ContainerStatuses: []apiv1.ContainerStatus{{
+			Name:  config.SparkDriverContainerName,
+			State: apiv1.ContainerState{Waiting: &apiv1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+		}}}, v1beta2.ImagePullFailureReason},
+		{apiv1.PodStatus{StartTime: &metav1.Time{Time: time.Now().Add(-time.Hour)}, ContainerStatuses: []apiv1.ContainerStatus{{
+			Name:  config.SparkDriverContainerName,
+			State: apiv1.ContainerState{Waiting: &apiv1.ContainerStateWaiting{Reason: "CreateContainerConfigError"}},
+		}}}, v1beta2.ContainerConfigErrorReason},
+	}
+	for _, test := range testcases {
+		assert.Equal(t, test.expected, getDriverTerminationReason(&apiv1.Pod{Status: test.status}))
+	}
+}
+
+func TestPodStatusToDriverState(t *testing.T) {
+	waiting := func(reason string) apiv1.PodStatus {
+		return apiv1.PodStatus{
+			Phase: apiv1.PodPending,
+			ContainerStatuses: []apiv1.ContainerStatus{{
+				Name:  config.SparkDriverContainerName,
+				State: apiv1.ContainerState{Waiting: &apiv1.ContainerStateWaiting{Reason: reason}},
+			}},
+		}
+	}
+	assert.Equal(t, v1beta2.DriverPendingState, podStatusToDriverState(apiv1.PodStatus{Phase: apiv1.PodPending}))
+	assert.Equal(t, v1beta2.DriverPendingState, podStatusToDriverState(waiting("ContainerCreating")))
+	assert.Equal(t, v1beta2.DriverFailedState, podStatusToDriverState(waiting("ImagePullBackOff")))
+	assert.Equal(t, v1beta2.DriverFailedState, podStatusToDriverState(waiting("InvalidImageName")))
+
+	// A failed pull may succeed when retried, and a missing Secret or ConfigMap may still be created, within the
+	// grace period.
+	for _, reason := range []string{"ErrImagePull", "CreateContainerConfigError"} {
+		status := waiting(reason)
+		status.StartTime = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+		assert.Equal(t, v1beta2.DriverPendingState, podStatusToDriverState(status))
+		status.StartTime = &metav1.Time{Time: time.Now().Add(-driverContainerErrorGracePeriod)}
+		assert.Equal(t, v1beta2.DriverFailedState, podStatusToDriverState(status))
+	}
+}
+
+func TestPodStatusToExecutorState(t *testing.T) {
+	withSidecar := func(executorState apiv1.ContainerState) apiv1.PodStatus {
+		return apiv1.PodStatus{
+			Phase: apiv1.PodRunning,
+			ContainerStatuses: []apiv1.ContainerStatus{
+				{Name: config.Spark3DefaultExecutorContainerName, State: executorState},
+				{Name: "istio-proxy", State: apiv1.ContainerState{Running: &apiv1.ContainerStateRunning{}}},
+			},
+		}
+	}
+	assert.Equal(t, v1beta2.ExecutorPendingState, podStatusToExecutorState(apiv1.PodStatus{Phase: apiv1.PodPending}))
+	assert.Equal(t, v1beta2.ExecutorRunningState, podStatusToExecutorState(withSidecar(apiv1.ContainerState{Running: &apiv1.ContainerStateRunning{}})))
+	assert.Equal(t, v1beta2.ExecutorCompletedState, podStatusToExecutorState(withSidecar(apiv1.ContainerState{Terminated: &apiv1.ContainerStateTerminated{ExitCode: 0}})))
+	assert.Equal(t, v1beta2.ExecutorFailedState, podStatusToExecutorState(withSidecar(apiv1.ContainerState{Terminated: &apiv1.ContainerStateTerminated{ExitCode: 137}})))
+	assert.Equal(t, v1beta2.ExecutorCompletedState, podStatusToExecutorState(apiv1.PodStatus{Phase: apiv1.PodSucceeded}))
+	assert.Equal(t, v1beta2.ExecutorFailedState, podStatusToExecutorState(apiv1.PodStatus{Phase: apiv1.PodFailed}))
+}
@@ -29,6 +29,7 @@ import (
 	extensions "k8s.io/api/extensions/v1beta1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	clientset "k8s.io/client-go/kubernetes"
 
@@ -71,6 +72,7 @@ type SparkService struct {
 	targetPort         intstr.IntOrString
 	serviceIP          string
 	serviceAnnotations map[string]string
+	serviceUID         types.UID
 }
 
 // SparkIngress encapsulates information about the driver UI ingress.
@@ -79,6 +81,8 @@ type SparkIngress struct {
 	ingressURL  *url.URL
 	annotations map[string]string
 	ingressTLS  []networkingv1.IngressTLS
+	ingressUID  types.UID
+	apiVersion  string
 }
 
 func createSparkUIIngress(app *v1beta2.SparkApplication, service SparkService, ingressURL *url.URL, kubeClient clientset.Interface) (*SparkIngress, error) {
@@ -146,7 +150,7 @@ func createSparkUIIngress_v1(app *v1beta2.SparkApplication, service SparkService
 		ingress.Spec.TLS = ingressTlsHosts
 	}
 	glog.Infof("Creating an Ingress %s for the Spark UI for application %s", ingress.Name, app.Name)
-	_, err := kubeClient.NetworkingV1().Ingresses(ingress.Namespace).Create(context.TODO(), &ingress, metav1.CreateOptions{})
+	created, err := kubeClient.NetworkingV1().Ingresses(ingress.Namespace).Create(context.TODO(), &ingress, metav1.CreateOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -155,6 +159,8 @@ func createSparkUIIngress_v1(app *v1beta2.SparkApplication, service SparkService
 		ingressURL:  ingressURL,
 		annotations: ingress.Annotations,
 		ingressTLS:  ingressTlsHosts,
+		ingressUID:  created.UID,
+		apiVersion:  networkingAPIVersion,
 	}, nil
 }
 
@@ -213,7 +219,7 @@ func createSparkUIIngress_legacy(app *v1beta2.SparkApplication, service SparkSer
 		ingress.Spec.TLS = convertIngressTlsHostsToLegacy(ingressTlsHosts)
 	}
 	glog.Infof("Creating an extensions/v1beta1 Ingress %s for the Spark UI for application %s", ingress.Name, app.Name)
-	_, err := kubeClient.ExtensionsV1beta1().Ingresses(ingress.Namespace).Create(context.TODO(), &ingress, metav1.CreateOptions{})
+	created, err := kubeClient.ExtensionsV1beta1().Ingresses(ingress.Namespace).Create(context.TODO(), &ingress, metav1.CreateOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -222,6 +228,8 @@ func createSparkUIIngress_legacy(app *v1beta2.SparkApplication, service SparkSer
 		ingressURL:  ingressURL,
 		annotations: ingress.Annotations,
 		ingressTLS:  ingressTlsHosts,
+		ingressUID:  created.UID,
+		apiVersion:  extensionsAPIVersion,
 	}, nil
 }
 
@@ -293,6 +301,7 @@ func createSparkUIService(
 		targetPort:         service.Spec.Ports[0].TargetPort,
 		serviceIP:          service.Spec.ClusterIP,
 		serviceAnnotations: serviceAnnotations,
+		serviceUID:         service.UID,
 	}, nil
 }
 
@@ -17,12 +17,14 @@ limitations under the License.
 package sparkapplication
 
 import (
+	"bytes"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"reflect"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/golang/glog"
 	v1 "k8s.io/api/core/v1"
@@ -54,7 +56,20 @@ func newSubmission(args []string, app *v1beta2.SparkApplication) *submission {
 	}
 }
 
-func runSparkSubmit(submission *submission) (bool, error) {
+// submissionTimeoutError is returned if spark-submit does not finish within the submission timeout.
+type submissionTimeoutError struct {
+	namespace string
+	name      string
+	timeout   time.Duration
+}
+
+func (e *submissionTimeoutError) Error() string {
+	return fmt.Sprintf("spark-submit for SparkApplication %s/%s did not finish within %v and was killed", e.namespace, e.name, e.timeout)
+}
+
+// runSparkSubmit runs spark-submit for the submission. If it does not finish within the timeout, spark-submit and
+// any process it started are killed. A timeout of zero means no timeout.
+func runSparkSubmit(submission *submission, timeout time.Duration) (bool, error) {
 	sparkHome, present := os.LookupEnv(sparkHomeEnvVar)
 	if !present {
 		glog.Error("SPARK_HOME is not specified")
@@ -63,13 +78,39 @@ func runSparkSubmit(submission *submission) (bool, error) {
 
 	cmd := execCommand(command, submission.args...)
 	glog.V(2).Infof("spark-submit arguments: %v", cmd.Args)
-	output, err := cmd.Output()
-	glog.V(3).Infof("spark-submit output: %s", string(output))
-	if err != nil {
-		var errorMsg string
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			errorMsg = string(exitErr.Stderr)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Run spark-submit in its own process group so that the JVM it launches is killed with it on timeout.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return false, fmt.Errorf("failed to run spark-submit for SparkApplication %s/%s: %v", submission.namespace, submission.name, err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	var timeoutCh <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutCh = timer.C
+	}
+	var err error
+	select {
+	case err = <-done:
+	case <-timeoutCh:
+		if killErr := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); killErr != nil {
+			glog.Errorf("failed to kill spark-submit for SparkApplication %s/%s: %v", submission.namespace, submission.name, killErr)
 		}
+		<-done
+		glog.V(3).Infof("spark-submit output: %s", stdout.String())
+		return false, &submissionTimeoutError{namespace: submission.namespace, name: submission.name, timeout: timeout}
+	}
+	glog.V(3).Infof("spark-submit output: %s", stdout.String())
+	if err != nil {
+		errorMsg := stderr.String()
 		// The driver pod of the application already exists.
 		if strings.Contains(errorMsg, podAlreadyExistsErrorCode) {
 			glog.Warningf("trying to resubmit an already submitted SparkApplication %s/%s", submission.namespace, submission.name)
@@ -0,0 +1,126 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+const (
+	submissionResultSuccess = "success"
+	submissionResultFailure = "failure"
+	submissionResultTimeout = "timeout"
+
+	// submissionSlotRetryInterval is the interval at which an application waiting for a free submission slot is
+	// requeued.
+	submissionSlotRetryInterval = 2 * time.Second
+)
+
+// submissionExecutor runs spark-submit for the controller workers. It limits the number of submissions running at
+// the same time, so that spark-submit processes cannot starve the operator of CPU and memory, and bounds how long a
+// single submission can block a worker. Workers never wait for a free slot, applications that find none are
+// requeued instead, so that the workers keep processing the other applications.
+type submissionExecutor struct {
+	slots   chan struct{}
+	timeout time.Duration
+	metrics *sparkAppMetrics
+
+	mutex sync.Mutex
+	// waiting records the keys of the applications that found no free slot and were not submitted since.
+	waiting map[string]bool
+}
+
+func newSubmissionExecutor(workers int, timeout time.Duration, metrics *sparkAppMetrics) *submissionExecutor {
+	if workers < 1 {
+		workers = 1
+	}
+	return &submissionExecutor{
+		slots:   make(chan struct{}, workers),
+		timeout: timeout,
+		metrics: metrics,
+		waiting: make(map[string]bool),
+	}
+}
+
+// tryAcquire takes a free slot for submitting the application with the given key without waiting. It returns false
+// and counts the application as waiting if all slots are taken. A slot taken must be given back with release.
+func (e *submissionExecutor) tryAcquire(key string) bool {
+	select {
+	case e.slots <- struct{}{}:
+		e.setWaiting(key, false)
+		return true
+	default:
+		e.setWaiting(key, true)
+		return false
+	}
+}
+
+func (e *submissionExecutor) release() {
+	<-e.slots
+}
+
+// isFull returns whether all slots are taken.
+func (e *submissionExecutor) isFull() bool {
+	return len(e.slots) == cap(e.slots)
+}
+
+// forget stops counting the application with the given key as waiting, e.g. because it was deleted.
+func (e *submissionExecutor) forget(key string) {
+	e.setWaiting(key, false)
+}
+
+func (e *submissionExecutor) setWaiting(key string, waiting bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if waiting {
+		e.waiting[key] = true
+	} else {
+		delete(e.waiting, key)
+	}
+	if e.metrics != nil {
+		e.metrics.sparkAppSubmissionQueueDepth.Set(float64(len(e.waiting)))
+	}
+}
+
+// run runs spark-submit for the submission with the configured timeout. The caller must hold a slot.
+func (e *submissionExecutor) run(submission *submission) (bool, error) {
+	start := time.Now()
+	submitted, err := runSparkSubmit(submission, e.timeout)
+	result := submissionResultSuccess
+	if err != nil {
+		result = submissionResultFailure
+		if _, ok := err.(*submissionTimeoutError); ok {
+			result = submissionResultTimeout
+		}
+	}
+	e.observeDuration(result, time.Since(start))
+	return submitted, err
+}
+
+func (e *submissionExecutor) observeDuration(result string, duration time.Duration) {
+	if e.metrics == nil {
+		return
+	}
+	if m, err := e.metrics.sparkAppSubmissionDuration.GetMetricWithLabelValues(result); err != nil {
+		glog.Errorf("Error while exporting metrics: %v", err)
+	} else {
+		m.Observe(duration.Seconds())
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2017 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparkapplication
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	prometheus_model "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apis/sparkoperator.k8s.io/v1beta2"
+	"github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/util"
+)
+
+// TestHelperProcessHang starts a child process sharing its output and then hangs, like spark-submit waiting on a
+// JVM that does not return.
+func TestHelperProcessHang(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	child := exec.Command("sleep", "60")
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+	child.Start()
+	time.Sleep(time.Minute)
+	os.Exit(0)
+}
+
+func helperCommand(test string) func(command string, args ...string) *exec.Cmd {
+	return func(command string, args ...string) *exec.Cmd {
+		cs := []string{"-test.run=" + test, "--", command}
+		cs = append(cs, args...)
+		cmd := exec.Command(os.Args[0], cs...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1", "PATH=" + os.Getenv("PATH")}
+		return cmd
+	}
+}
+
+func fetchGaugeValue(m prometheus.Gauge) float64 {
+	pb := &prometheus_model.Metric{}
+	m.Write(pb)
+	return pb.GetGauge().GetValue()
+}
+
+func fetchHistogramCount(m *prometheus.HistogramVec, result string) uint64 {
+	pb := &prometheus_model.Metric{}
+	m.WithLabelValues(result).(prometheus.Histogram).Write(pb)
+	return pb.GetHistogram().GetSampleCount()
+}
+
+func TestSubmissionExecutorTimeout(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = helperCommand("TestHelperProcessHang")
+	metrics := newSparkAppMetrics(&util.MetricConfig{})
+	executor := newSubmissionExecutor(1, 500*time.Millisecond, metrics)
+
+	start := time.Now()
+	submitted, err := executor.run(&submission{namespace: "default", name: "foo"})
+	assert.False(t, submitted)
+	assert.Error(t, err)
+	assert.Equal(t, "spark-submit for SparkApplication default/foo did not finish within 500ms and was killed", err.Error())
+	// The grandchild holding the output pipe is killed with spark-submit, otherwise waiting would take a minute.
+	assert.True(t, time.Since(start) < 30*time.Second)
+	assert.Equal(t, uint64(1), fetchHistogramCount(metrics.sparkAppSubmissionDuration, submissionResultTimeout))
+
+	// Starting the helper process can take longer than the timeout above, e.g. under the race detector.
+	execCommand = helperCommand("TestHelperProcessSuccess")
+	executor = newSubmissionExecutor(1, time.Minute, metrics)
+	submitted, err = executor.run(&submission{namespace: "default", name: "foo"})
+	assert.True(t, submitted)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), fetchHistogramCount(metrics.sparkAppSubmissionDuration, submissionResultSuccess))
+}
+
+func TestSubmissionExecutorLimitsConcurrency(t *testing.T) {
+	metrics := newSparkAppMetrics(&util.MetricConfig{})
+	executor := newSubmissionExecutor(1, time.Minute, metrics)
+
+	assert.True(t, executor.tryAcquire("default/foo"))
+	assert.True(t, executor.isFull())
+	// Applications that find no free slot are counted as waiting until they get one or are forgotten.
+	assert.False(t, executor.tryAcquire("default/bar"))
+	assert.False(t, executor.tryAcquire("default/baz"))
+	assert.Equal(t, float64(2), fetchGaugeValue(metrics.sparkAppSubmissionQueueDepth))
+	executor.forget("default/baz")
+	assert.Equal(t, float64(1), fetchGaugeValue(metrics.sparkAppSubmissionQueueDepth))
+
+	executor.release()
+	assert.True(t, executor.tryAcquire("default/bar"))
+	assert.Equal(t, float64(0), fetchGaugeValue(metrics.sparkAppSubmissionQueueDepth))
+}
+
+func TestSyncSparkApplication_NoFreeSubmissionSlot(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = helperCommand("TestHelperProcessSuccess")
+	os.Setenv(sparkHomeEnvVar, "/spark")
+	os.Setenv(kubernetesServiceHostEnvVar, "localhost")
+	os.Setenv(kubernetesServicePortEnvVar, "443")
+	app := &v1beta2.SparkApplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status:     v1beta2.SparkApplicationStatus{AppState: v1beta2.ApplicationState{State: v1beta2.NewState}},
+	}
+	ctrl, _ := newFakeController(app)
+	_, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Create(context.TODO(), app, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The worker does not wait for the slot taken by another application, the application is requeued instead.
+	assert.True(t, ctrl.executor.tryAcquire("default/bar"))
+	done := make(chan error)
+	go func() {
+		done <- ctrl.syncSparkApplication("default/foo")
+	}()
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the worker waited for a free submission slot")
+	}
+	updatedApp, err := ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.NewState, updatedApp.Status.AppState.State)
+
+	ctrl.executor.release()
+	assert.Nil(t, ctrl.syncSparkApplication("default/foo"))
+	updatedApp, err = ctrl.crdClient.SparkoperatorV1beta2().SparkApplications(app.Namespace).Get(context.TODO(), app.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1beta2.SubmittedState, updatedApp.Status.AppState.State)
+	assert.False(t, ctrl.executor.isFull())
+}
@@ -0,0 +1,334 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	deviceCodeGrantType   = "urn:ietf:params:oauth:grant-type:device_code"
+	refreshTokenGrantType = "refresh_token"
+	// tokenExpiryLeeway is how long before its expiry a cached token is refreshed.
+	tokenExpiryLeeway = 30 * time.Second
+)
+
+// Authenticator adds credentials to requests sent to the API gateway.
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// BasicAuthenticator authenticates with a user name and password.
+type BasicAuthenticator struct {
+	Credential UserCredential
+}
+
+func (a *BasicAuthenticator) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.Credential.Name, a.Credential.Password)
+	return nil
+}
+
+// TokenAuthenticator authenticates with a static bearer token.
+type TokenAuthenticator struct {
+	Token string
+}
+
+func (a *TokenAuthenticator) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+// cachedToken is the on-disk form of the tokens obtained through the OIDC device flow.
+type cachedToken struct {
+	IDToken      string    `json:"idToken"`
+	RefreshToken string    `json:"refreshToken,omitempty"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+func (t *cachedToken) valid(now time.Time) bool {
+	return t != nil && t.IDToken != "" && now.Add(tokenExpiryLeeway).Before(t.Expiry)
+}
+
+// DeviceFlowAuthenticator authenticates with an OIDC ID token obtained through the OAuth 2.0 device
+// authorization grant (RFC 8628). Tokens are cached in a file and refreshed when they expire, so users only
+// need to log in through the browser when the refresh token is no longer valid.
+type DeviceFlowAuthenticator struct {
+	IssuerURL string
+	ClientID  string
+	CacheFile string
+	// Prompt tells the user where to log in. It prints to stderr if nil.
+	Prompt func(verificationURI string, userCode string)
+
+	httpClient *http.Client
+	now        func() time.Time
+	sleep      func(time.Duration)
+	mutex      sync.Mutex
+	token      *cachedToken
+}
+
+// NewDeviceFlowAuthenticator creates a DeviceFlowAuthenticator caching tokens in the given directory.
+func NewDeviceFlowAuthenticator(issuerURL string, clientID string, cacheDir string) *DeviceFlowAuthenticator {
+	hash := sha256.Sum256([]byte(issuerURL + "\n" + clientID))
+	return &DeviceFlowAuthenticator{
+		IssuerURL:  strings.TrimSuffix(issuerURL, "/"),
+		ClientID:   clientID,
+		CacheFile:  filepath.Join(cacheDir, fmt.Sprintf("oidc-%x.json", hash[:8])),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		now:        time.Now,
+		sleep:      time.Sleep,
+	}
+}
+
+// DefaultTokenCacheDir returns the directory OIDC tokens are cached in by default.
+func DefaultTokenCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "sparkcli")
+}
+
+func (a *DeviceFlowAuthenticator) Authenticate(req *http.Request) error {
+	token, err := a.getToken()
+	if err != nil {
+		return fmt.Errorf("failed to get OIDC token: %s", err.Error())
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (a *DeviceFlowAuthenticator) getToken() (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.token == nil {
+		a.token = a.readCache()
+	}
+	if a.token.valid(a.now()) {
+		return a.token.IDToken, nil
+	}
+
+	endpoints, err := a.discover()
+	if err != nil {
+		return "", err
+	}
+
+	var token *cachedToken
+	if a.token != nil && a.token.RefreshToken != "" {
+		token, err = a.requestToken(endpoints.TokenEndpoint, url.Values{
+			"grant_type":    {refreshTokenGrantType},
+			"refresh_token": {a.token.RefreshToken},
+			"client_id":     {a.ClientID},
+		})
+		if err != nil {
+			log.Printf("Failed to refresh OIDC token, logging in again: %s", err.Error())
+		} else if token.RefreshToken == "" {
+			token.RefreshToken = a.token.RefreshToken
+		}
+	}
+	if token == nil {
+		if token, err = a.runDeviceFlow(endpoints); err != nil {
+			return "", err
+		}
+	}
+
+	a.token = token
+	a.writeCache()
+	return token.IDToken, nil
+}
+
+type oidcEndpoints struct {
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	TokenEndpoint               string `json:"token_endpoint"`
+}
+
+func (a *DeviceFlowAuthenticator) discover() (oidcEndpoints, error) {
+	endpoints := oidcEndpoints{}
+	discoveryURL := a.IssuerURL + "/.well-known/openid-configuration"
+	response, err := a.httpClient.Get(discoveryURL)
+	if err != nil {
+		return endpoints, fmt.Errorf("failed to get %s: %s", discoveryURL, err.Error())
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return endpoints, ErrorBadHttpStatus(discoveryURL, response)
+	}
+	if err := json.NewDecoder(response.Body).Decode(&endpoints); err != nil {
+		return endpoints, fmt.Errorf("failed to parse response from %s: %s", discoveryURL, err.Error())
+	}
+	if endpoints.DeviceAuthorizationEndpoint == "" || endpoints.TokenEndpoint == "" {
+		return endpoints, fmt.Errorf("OIDC provider %s does not support the device authorization grant", a.IssuerURL)
+	}
+	return endpoints, nil
+}
+
+func (a *DeviceFlowAuthenticator) runDeviceFlow(endpoints oidcEndpoints) (*cachedToken, error) {
+	response, err := a.httpClient.PostForm(endpoints.DeviceAuthorizationEndpoint, url.Values{
+		"client_id": {a.ClientID},
+		"scope":     {"openid offline_access email profile"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to post %s: %s", endpoints.DeviceAuthorizationEndpoint, err.Error())
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, ErrorBadHttpStatus(endpoints.DeviceAuthorizationEndpoint, response)
+	}
+
+	authorization := struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int    `json:"expires_in"`
+		Interval                int    `json:"interval"`
+	}{}
+	if err := json.NewDecoder(response.Body).Decode(&authorization); err != nil {
+		return nil, fmt.Errorf("failed to parse response from %s: %s", endpoints.DeviceAuthorizationEndpoint, err.Error())
+	}
+
+	verificationURI := authorization.VerificationURIComplete
+	if verificationURI == "" {
+		verificationURI = authorization.VerificationURI
+	}
+	if a.Prompt != nil {
+		a.Prompt(verificationURI, authorization.UserCode)
+	} else {
+		fmt.Fprintf(os.Stderr, "To log in, open %s and enter the code %s\n", verificationURI, authorization.UserCode)
+	}
+
+	interval := time.Duration(authorization.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := a.now().Add(time.Duration(authorization.ExpiresIn) * time.Second)
+	for authorization.ExpiresIn <= 0 || a.now().Before(deadline) {
+		a.sleep(interval)
+		token, err := a.requestToken(endpoints.TokenEndpoint, url.Values{
+			"grant_type":  {deviceCodeGrantType},
+			"device_code": {authorization.DeviceCode},
+			"client_id":   {a.ClientID},
+		})
+		if err == nil {
+			return token, nil
+		}
+		switch err.Error() {
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		default:
+			return nil, fmt.Errorf("device authorization failed: %s", err.Error())
+		}
+	}
+	return nil, fmt.Errorf("device authorization expired before login completed")
+}
+
+// requestToken posts a token request. OAuth error codes, e.g. authorization_pending, are returned as the
+// error message.
+func (a *DeviceFlowAuthenticator) requestToken(tokenEndpoint string, form url.Values) (*cachedToken, error) {
+	response, err := a.httpClient.PostForm(tokenEndpoint, form)
+	if err != nil {
+		return nil, fmt.Errorf("failed to post %s: %s", tokenEndpoint, err.Error())
+	}
+	defer response.Body.Close()
+
+	result := struct {
+		IDToken      string `json:"id_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		Error        string `json:"error"`
+	}{}
+	responseBytes, err := ReadHttpResponse(response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response data from %s: %s", tokenEndpoint, err.Error())
+	}
+	if err := json.Unmarshal(responseBytes, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response from %s: %s, response: %s", tokenEndpoint, err.Error(), string(responseBytes))
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("%s", result.Error)
+	}
+	if response.StatusCode != http.StatusOK || result.IDToken == "" {
+		return nil, fmt.Errorf("got no ID token from %s, status %d", tokenEndpoint, response.StatusCode)
+	}
+
+	expiry := idTokenExpiry(result.IDToken)
+	if expiry.IsZero() {
+		expiry = a.now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	}
+	return &cachedToken{IDToken: result.IDToken, RefreshToken: result.RefreshToken, Expiry: expiry}, nil
+}
+
+// idTokenExpiry returns the exp claim of an ID token, or the zero time if it cannot be read. The token is
+// not verified, that is up to the API gateway.
+func idTokenExpiry(idToken string) time.Time {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	claims := struct {
+		Exp int64 `json:"exp"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+func (a *DeviceFlowAuthenticator) readCache() *cachedToken {
+	content, err := ioutil.ReadFile(a.CacheFile)
+	if err != nil {
+		return nil
+	}
+	token := &cachedToken{}
+	if err := json.Unmarshal(content, token); err != nil {
+		log.Printf("Ignoring invalid OIDC token cache %s: %s", a.CacheFile, err.Error())
+		return nil
+	}
+	return token
+}
+
+func (a *DeviceFlowAuthenticator) writeCache() {
+	content, err := json.Marshal(a.token)
+	if err != nil {
+		log.Printf("Failed to serialize OIDC token: %s", err.Error())
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(a.CacheFile), 0700); err != nil {
+		log.Printf("Failed to create OIDC token cache directory: %s", err.Error())
+		return
+	}
+	if err := ioutil.WriteFile(a.CacheFile, content, 0600); err != nil {
+		log.Printf("Failed to write OIDC token cache %s: %s", a.CacheFile, err.Error())
+	}
+}
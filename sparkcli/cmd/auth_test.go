@@ -0,0 +1,143 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	apigatewayv1 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apigateway/apis/v1"
+)
+
+func fakeIDToken(name string, expiry time.Time) string {
+	payload, _ := json.Marshal(map[string]interface{}{"sub": name, "exp": expiry.Unix()})
+	return "e30." + base64.RawURLEncoding.EncodeToString(payload) + ".c2ln"
+}
+
+func TestClientSendsTokenAndNamespace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "team-a", r.URL.Query().Get("namespace"))
+		json.NewEncoder(w).Encode(apigatewayv1.SubmissionStatusResponse{SubmissionId: "s-1", State: "RUNNING"})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "team-a", &TokenAuthenticator{Token: "secret"}, nil)
+	_, status, err := client.GetApplicationStatus("s-1")
+	assert.Nil(t, err)
+	assert.Equal(t, "RUNNING", status.State)
+}
+
+func TestDeviceFlowAuthenticator(t *testing.T) {
+	now := time.Now()
+	deviceCodeRequests := 0
+	tokenRequests := 0
+	var provider *httptest.Server
+	provider = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{
+				"device_authorization_endpoint": provider.URL + "/device",
+				"token_endpoint":                provider.URL + "/token",
+			})
+		case "/device":
+			deviceCodeRequests++
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"device_code":      "device-1",
+				"user_code":        "ABCD-EFGH",
+				"verification_uri": provider.URL + "/activate",
+				"expires_in":       60,
+				"interval":         0,
+			})
+		case "/token":
+			tokenRequests++
+			r.ParseForm()
+			switch {
+			case r.Form.Get("grant_type") == deviceCodeGrantType && tokenRequests == 1:
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "authorization_pending"})
+			case r.Form.Get("grant_type") == deviceCodeGrantType:
+				json.NewEncoder(w).Encode(map[string]string{
+					"id_token":      fakeIDToken("alice", now.Add(time.Hour)),
+					"refresh_token": "refresh-1",
+				})
+			case r.Form.Get("grant_type") == refreshTokenGrantType && r.Form.Get("refresh_token") == "refresh-1":
+				json.NewEncoder(w).Encode(map[string]string{
+					"id_token": fakeIDToken("alice-refreshed", now.Add(3*time.Hour)),
+				})
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer provider.Close()
+
+	cacheDir, err := ioutil.TempDir("", "sparkcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	newAuthenticator := func() *DeviceFlowAuthenticator {
+		authenticator := NewDeviceFlowAuthenticator(provider.URL, "sparkcli", cacheDir)
+		authenticator.sleep = func(time.Duration) {}
+		authenticator.Prompt = func(verificationURI string, userCode string) {
+			assert.Equal(t, "ABCD-EFGH", userCode)
+		}
+		return authenticator
+	}
+	authorization := func(authenticator *DeviceFlowAuthenticator) string {
+		req := httptest.NewRequest(http.MethodGet, "/submissions", nil)
+		assert.Nil(t, authenticator.Authenticate(req))
+		return req.Header.Get("Authorization")
+	}
+
+	// The first login goes through the device flow.
+	assert.Equal(t, "Bearer "+fakeIDToken("alice", now.Add(time.Hour)), authorization(newAuthenticator()))
+	assert.Equal(t, 1, deviceCodeRequests)
+	assert.Equal(t, 2, tokenRequests)
+
+	// Later invocations use the cached token.
+	authenticator := newAuthenticator()
+	assert.Equal(t, "Bearer "+fakeIDToken("alice", now.Add(time.Hour)), authorization(authenticator))
+	assert.Equal(t, 2, tokenRequests)
+	info, err := os.Stat(authenticator.CacheFile)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Expired tokens are refreshed with the refresh token.
+	authenticator = newAuthenticator()
+	authenticator.now = func() time.Time { return now.Add(2 * time.Hour) }
+	assert.Equal(t, "Bearer "+fakeIDToken("alice-refreshed", now.Add(3*time.Hour)), authorization(authenticator))
+	assert.Equal(t, 1, deviceCodeRequests)
+	assert.Equal(t, 3, tokenRequests)
+
+	cached := newAuthenticator().readCache()
+	assert.Equal(t, "refresh-1", cached.RefreshToken, fmt.Sprintf("cache: %+v", cached))
+}
@@ -17,7 +17,6 @@ limitations under the License.
 package cmd
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -29,22 +28,60 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
+
+	"github.com/google/uuid"
 )
 
 type Client struct {
-	serverUrl  string
-	credential UserCredential
+	serverUrl     string
+	namespace     string
+	authenticator Authenticator
+	httpClient    *http.Client
+	retryPolicy   RetryPolicy
 }
 
-func NewBasicAuthClient(serverUrl string, user string, password string) *Client {
+// NewClient creates a Client that authenticates requests with the given Authenticator, which may be nil.
+// The namespace, if not empty, is sent with every request. http.DefaultClient is used if httpClient is nil.
+func NewClient(serverUrl string, namespace string, authenticator Authenticator, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
 	return &Client{
-		serverUrl: serverUrl,
-		credential: UserCredential{
+		serverUrl:     serverUrl,
+		namespace:     namespace,
+		authenticator: authenticator,
+		httpClient:    httpClient,
+		retryPolicy:   DefaultRetryPolicy,
+	}
+}
+
+// SetRetryPolicy sets how failed requests are retried.
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.retryPolicy = policy
+}
+
+func NewBasicAuthClient(serverUrl string, user string, password string) *Client {
+	return NewClient(serverUrl, "", &BasicAuthenticator{
+		Credential: UserCredential{
 			Name:     user,
 			Password: password,
 		},
+	}, nil)
+}
+
+// do authenticates and sends the request, retrying it according to the retry policy.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	response, _, err := c.sendWithNamespace(req)
+	return response, err
+}
+
+func (c *Client) sendWithNamespace(req *http.Request) (*http.Response, int, error) {
+	if c.namespace != "" {
+		query := req.URL.Query()
+		query.Set("namespace", c.namespace)
+		req.URL.RawQuery = query.Encode()
 	}
+	return c.send(req)
 }
 
 func (c *Client) UploadFileToS3(filePath string) (string, error) {
@@ -68,13 +105,15 @@ func (c *Client) UploadFileToS3(filePath string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to create post request for %s: %s", url, err.Error())
 	}
-	req.Header.Set("Content-Length", fmt.Sprintf("%d", fileSize))
+	req.ContentLength = fileSize
+	req.GetBody = func() (io.ReadCloser, error) {
+		return os.Open(filePath)
+	}
 	req.Header.Set("Content-Type", "application/octet-stream")
-	req.SetBasicAuth(c.credential.Name, c.credential.Password)
 
 	log.Printf("Sending file %s to %s", filePath, url)
 
-	response, err := http.DefaultClient.Do(req)
+	response, err := c.do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to post to %s: %s", url, err)
 	}
@@ -103,9 +142,12 @@ func (c *Client) UploadFileToS3(filePath string) (string, error) {
 	return responseStruct.Url, nil
 }
 
+// SubmitApplication submits the application with a new submission ID. The ID is generated here rather than by
+// the gateway, so that retrying the request after a lost response cannot submit the application twice.
 func (c *Client) SubmitApplication(request apigatewayv1.SparkApplicationSubmissionRequest) (string, error) {
-	url := fmt.Sprintf("%s/submissions", c.serverUrl)
-	return c.submitApplicationImpl(request, url)
+	submissionId := "s-" + strings.ReplaceAll(uuid.New().String(), "-", "")
+	url := fmt.Sprintf("%s/submissions/%s", c.serverUrl, submissionId)
+	return c.submitApplicationImpl(request, url, submissionId)
 }
 
 func (c *Client) SubmitApplicationWithId(request apigatewayv1.SparkApplicationSubmissionRequest, submissionId string, overwrite bool) (string, error) {
@@ -113,10 +155,12 @@ func (c *Client) SubmitApplicationWithId(request apigatewayv1.SparkApplicationSu
 	if overwrite {
 		url += fmt.Sprintf("?overwrite=%t", overwrite)
 	}
-	return c.submitApplicationImpl(request, url)
+	return c.submitApplicationImpl(request, url, "")
 }
 
-func (c *Client) submitApplicationImpl(request apigatewayv1.SparkApplicationSubmissionRequest, url string) (string, error) {
+// submitApplicationImpl posts the submission request. If generatedId is set, a conflict on a retried request
+// means that an earlier attempt created the submission, and generatedId is returned.
+func (c *Client) submitApplicationImpl(request apigatewayv1.SparkApplicationSubmissionRequest, url string, generatedId string) (string, error) {
 	requestBytes, err := json.Marshal(request)
 	if err != nil {
 		return "",
@@ -129,9 +173,8 @@ func (c *Client) submitApplicationImpl(request apigatewayv1.SparkApplicationSubm
 			fmt.Errorf("failed to create post request for %s: %s", url, err.Error())
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.SetBasicAuth(c.credential.Name, c.credential.Password)
 
-	response, err := http.DefaultClient.Do(req)
+	response, attempts, err := c.sendWithNamespace(req)
 	if err != nil {
 		return "",
 			fmt.Errorf("failed to post %s: %s", url, err.Error())
@@ -139,6 +182,10 @@ func (c *Client) submitApplicationImpl(request apigatewayv1.SparkApplicationSubm
 
 	defer response.Body.Close()
 
+	if response.StatusCode == http.StatusConflict && generatedId != "" && attempts > 1 {
+		log.Printf("Submission %s was created by an earlier attempt", generatedId)
+		return generatedId, nil
+	}
 	if response.StatusCode != http.StatusOK {
 		return "", ErrorBadHttpStatus(url, response)
 	}
@@ -172,41 +219,20 @@ func (c *Client) GetApplicationStatus(submissionId string) (string, apigatewayv1
 		return "", result,
 			fmt.Errorf("failed to create get request for %s: %s", url, err.Error())
 	}
-	req.SetBasicAuth(c.credential.Name, c.credential.Password)
-
-	var lastError error
-	var responseBody io.ReadCloser
-	RetryUntilTrue(func() (bool, error) {
-		response, err := http.DefaultClient.Do(req)
-		if err != nil {
-			lastError = err
-			log.Printf("Failed to get %s: %s", url, err.Error())
-			return false, nil
-		}
-		if response.StatusCode != http.StatusOK {
-			lastError = ErrorBadHttpStatus(url, response)
-			log.Printf("Failed to get %s: %s", url, lastError.Error())
-			response.Body.Close()
-			return false, nil
-		}
-		lastError = nil
-		responseBody = response.Body
-		return true, nil
-	},
-		60*time.Second,
-		1*time.Second)
 
-	if lastError != nil {
-		return "", result, lastError
+	response, err := c.do(req)
+	if err != nil {
+		return "", result,
+			fmt.Errorf("failed to get %s: %s", url, err.Error())
 	}
 
-	if responseBody == nil {
-		return "", result, fmt.Errorf("failed to send http request and get response from %s: response body is nil", url)
-	}
+	defer response.Body.Close()
 
-	defer responseBody.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", result, ErrorBadHttpStatus(url, response)
+	}
 
-	responseBytes, err := io.ReadAll(responseBody)
+	responseBytes, err := io.ReadAll(response.Body)
 	if err != nil {
 		return "", result,
 			fmt.Errorf("failed to read response data for %s: %s", url, err.Error())
@@ -239,7 +265,7 @@ func (c *Client) ListSubmissions(limit int64, state string, ignoreKilled bool) (
 	if limit > 0 {
 		params.Add("limit", fmt.Sprintf("%d", limit))
 	}
-	if State != "" {
+	if state != "" {
 		params.Add("state", state)
 	}
 	if ApplicationName != "" {
@@ -256,9 +282,8 @@ func (c *Client) ListSubmissions(limit int64, state string, ignoreKilled bool) (
 		return "", result,
 			fmt.Errorf("failed to create get request for %s: %s", requestUrl, err.Error())
 	}
-	req.SetBasicAuth(c.credential.Name, c.credential.Password)
 
-	response, err := http.DefaultClient.Do(req)
+	response, err := c.do(req)
 	if err != nil {
 		return "", result,
 			fmt.Errorf("failed to get %s: %s", requestUrl, err.Error())
@@ -287,62 +312,6 @@ func (c *Client) ListSubmissions(limit int64, state string, ignoreKilled bool) (
 	return responseStr, result, nil
 }
 
-func (c *Client) PrintApplicationLog(submissionId string, executorId int, followLogs bool) {
-	writer := os.Stdout
-	c.WriteApplicationLog(submissionId, executorId, followLogs, writer)
-}
-
-func (c *Client) WriteApplicationLog(submissionId string, executorId int, followLogs bool, writer io.Writer) {
-	url := fmt.Sprintf("%s/submissions/%s/log", c.serverUrl, submissionId)
-
-	queryParameters := make([]string, 0, 5)
-	if executorId != -1 {
-		queryParameters = append(queryParameters, fmt.Sprintf("executor=%d", executorId))
-	}
-	if followLogs {
-		queryParameters = append(queryParameters, fmt.Sprintf("follow=true"))
-	}
-	if len(queryParameters) > 0 {
-		url += "?" + strings.Join(queryParameters, "&")
-	}
-
-	req, err := http.NewRequest(http.MethodGet, url, bytes.NewReader([]byte{}))
-	if err != nil {
-		log.Printf("Failed to create get request for %s: %s", url, err.Error())
-		return
-	}
-	req.SetBasicAuth(c.credential.Name, c.credential.Password)
-
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		log.Printf("Failed to get %s: %s", url, err.Error())
-		return
-	}
-
-	defer response.Body.Close()
-
-	if response.StatusCode != http.StatusOK {
-		log.Printf("Failed to get %s: %s", url, ErrorBadHttpStatus(url, response).Error())
-		return
-	}
-
-	reader := bufio.NewReader(response.Body)
-	for {
-		// TODO optimize reading here by reading in batches
-		if c, _, err := reader.ReadRune(); err != nil {
-			if err == io.EOF {
-				break
-			} else {
-				log.Printf("Failed to get log: %s", err.Error())
-				return
-			}
-		} else {
-			str := fmt.Sprintf("%s", string(c))
-			writer.Write([]byte(str))
-		}
-	}
-}
-
 func (c *Client) DeleteApplication(submissionId string) (string, apigatewayv1.DeleteSubmissionResponse, error) {
 	result := apigatewayv1.DeleteSubmissionResponse{}
 
@@ -353,9 +322,8 @@ func (c *Client) DeleteApplication(submissionId string) (string, apigatewayv1.De
 		return "", result,
 			fmt.Errorf("failed to create delete request for %s: %s", url, err.Error())
 	}
-	req.SetBasicAuth(c.credential.Name, c.credential.Password)
 
-	response, err := http.DefaultClient.Do(req)
+	response, err := c.do(req)
 	if err != nil {
 		return "", result,
 			fmt.Errorf("failed to get %s: %s", url, err.Error())
@@ -404,9 +372,8 @@ func (c *Client) KillApplication(submissionId string) (string, apigatewayv1.Kill
 			fmt.Errorf("failed to create post request for %s: %s", url, err.Error())
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.SetBasicAuth(c.credential.Name, c.credential.Password)
 
-	response, err := http.DefaultClient.Do(req)
+	response, err := c.do(req)
 	if err != nil {
 		return "", result,
 			fmt.Errorf("failed to post %s: %s", url, err.Error())
@@ -459,9 +426,8 @@ func (c *Client) KillApplicationByName(applicationName string, maxCount int) (st
 			fmt.Errorf("failed to create post request for %s: %s", url, err.Error())
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.SetBasicAuth(c.credential.Name, c.credential.Password)
 
-	response, err := http.DefaultClient.Do(req)
+	response, err := c.do(req)
 	if err != nil {
 		return "", result,
 			fmt.Errorf("failed to post %s: %s", url, err.Error())
@@ -0,0 +1,301 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+// envPrefix is the prefix of the environment variables that override profile settings, e.g. SPARKCLI_URL.
+const envPrefix = "SPARKCLI_"
+
+// profileSettings are the global flags a profile or an environment variable can set.
+var profileSettings = []string{
+	"url", "namespace", "output",
+	"user", "password", "token", "token-file", "oidc-issuer-url", "oidc-client-id", "oidc-cache-dir",
+	"client-cert", "client-key", "ca-file", "insecure-skip-tls-verify", "proxy",
+	"connect-timeout", "request-timeout", "max-retries",
+}
+
+// secretSettings are not shown by config view unless --raw is set.
+var secretSettings = map[string]bool{"password": true, "token": true}
+
+var ConfigFile string
+var ProfileName string
+var RawConfig bool
+
+// Config is the content of the sparkcli config file.
+type Config struct {
+	// CurrentProfile is the profile used if no profile is selected with --profile.
+	CurrentProfile string `json:"currentProfile,omitempty"`
+	// Profiles maps profile names to profiles.
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+}
+
+// Profile maps global flags, e.g. url or token-file, to the values they default to.
+type Profile map[string]string
+
+// DefaultConfigFile returns the path of the config file used if --config is not set.
+func DefaultConfigFile() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "sparkcli", "config.yaml")
+}
+
+// LoadConfig reads the config file. A missing file is an empty config.
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return nil, fmt.Errorf("failed to read config file %s: %s", path, err.Error())
+	}
+	if err := yaml.UnmarshalStrict(content, config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %s", path, err.Error())
+	}
+	return config, nil
+}
+
+// Save writes the config file. It is only readable by the user because profiles may contain credentials.
+func (c *Config) Save(path string) error {
+	content, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to serialize config: %s", err.Error())
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory for config file %s: %s", path, err.Error())
+	}
+	if err := ioutil.WriteFile(path, content, 0600); err != nil {
+		return fmt.Errorf("failed to write config file %s: %s", path, err.Error())
+	}
+	return nil
+}
+
+func envName(setting string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(setting, "-", "_"))
+}
+
+// applyConfigEnv sets the config file and profile from SPARKCLI_CONFIG and SPARKCLI_PROFILE unless they were given
+// on the command line.
+func applyConfigEnv(flags *pflag.FlagSet) {
+	if value, ok := os.LookupEnv(envPrefix + "CONFIG"); ok && !flags.Changed("config") {
+		ConfigFile = value
+	}
+	if value, ok := os.LookupEnv(envPrefix + "PROFILE"); ok && !flags.Changed("profile") {
+		ProfileName = value
+	}
+}
+
+// applyConfig sets the global flags that were not given on the command line from environment variables, or
+// otherwise from the selected profile. The profile is selected with --profile or SPARKCLI_PROFILE, and defaults
+// to the current profile of the config file.
+func applyConfig(flags *pflag.FlagSet) error {
+	applyConfigEnv(flags)
+	config, err := LoadConfig(ConfigFile)
+	if err != nil {
+		return err
+	}
+	profileName := ProfileName
+	if profileName == "" {
+		profileName = config.CurrentProfile
+	}
+	profile, ok := config.Profiles[profileName]
+	if profileName != "" && !ok {
+		return fmt.Errorf("profile %q not found in config file %s", profileName, ConfigFile)
+	}
+
+	for _, setting := range profileSettings {
+		if flags.Lookup(setting) == nil || flags.Changed(setting) {
+			continue
+		}
+		source := "environment variable " + envName(setting)
+		value, ok := os.LookupEnv(envName(setting))
+		if !ok {
+			source = fmt.Sprintf("profile %s", profileName)
+			value, ok = profile[setting]
+		}
+		if !ok {
+			continue
+		}
+		if err := flags.Set(setting, value); err != nil {
+			return fmt.Errorf("invalid value %q for %s in %s: %s", value, setting, source, err.Error())
+		}
+	}
+	return nil
+}
+
+func isProfileSetting(setting string) bool {
+	for _, s := range profileSettings {
+		if s == setting {
+			return true
+		}
+	}
+	return false
+}
+
+// setProfileValue sets a setting of the profile, creating the profile if needed, and makes it the current profile
+// if there is none. An empty value removes the setting.
+func setProfileValue(config *Config, profileName string, setting string, value string) error {
+	if !isProfileSetting(setting) {
+		return fmt.Errorf("unsupported setting %q, must be one of %s", setting, strings.Join(profileSettings, ", "))
+	}
+	if profileName == "" {
+		profileName = config.CurrentProfile
+	}
+	if profileName == "" {
+		profileName = "default"
+	}
+	if config.Profiles == nil {
+		config.Profiles = map[string]Profile{}
+	}
+	profile := config.Profiles[profileName]
+	if profile == nil {
+		profile = Profile{}
+	}
+	if value == "" {
+		delete(profile, setting)
+	} else {
+		profile[setting] = value
+	}
+	config.Profiles[profileName] = profile
+	if config.CurrentProfile == "" {
+		config.CurrentProfile = profileName
+	}
+	return nil
+}
+
+// redactedConfig returns a copy of the config with secrets replaced.
+func redactedConfig(config *Config) *Config {
+	redacted := &Config{CurrentProfile: config.CurrentProfile, Profiles: map[string]Profile{}}
+	for name, profile := range config.Profiles {
+		copied := Profile{}
+		for setting, value := range profile {
+			if secretSettings[setting] {
+				value = "REDACTED"
+			}
+			copied[setting] = value
+		}
+		redacted.Profiles[name] = copied
+	}
+	return redacted
+}
+
+func loadConfigExitOnError() *Config {
+	config, err := LoadConfig(ConfigFile)
+	if err != nil {
+		ExitWithError(err.Error())
+	}
+	return config
+}
+
+func saveConfigExitOnError(config *Config) {
+	if err := config.Save(ConfigFile); err != nil {
+		ExitWithError(err.Error())
+	}
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "View and edit sparkcli profiles",
+	Long: `View and edit the profiles in the sparkcli config file. A profile sets defaults for the global flags, e.g.
+           the gateway URL, credentials, namespace and output format. Flags take precedence over environment
+           variables named SPARKCLI_<FLAG>, e.g. SPARKCLI_URL, which take precedence over the selected profile.`,
+	// The config commands edit profiles rather than use them, so a profile that does not exist yet is fine.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		applyConfigEnv(cmd.Flags())
+		return validateOutputFormat(OutputFormat)
+	},
+}
+
+var configViewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Print the config file",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config := loadConfigExitOnError()
+		if !RawConfig {
+			config = redactedConfig(config)
+		}
+		printed, err := printStructured(os.Stdout, OutputFormat, config)
+		if !printed {
+			_, err = printStructured(os.Stdout, yamlOutput, config)
+		}
+		if err != nil {
+			ExitWithError(err.Error())
+		}
+	},
+}
+
+var configUseCmd = &cobra.Command{
+	Use:   "use <profile>",
+	Short: "Set the current profile",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		config := loadConfigExitOnError()
+		if _, ok := config.Profiles[args[0]]; !ok {
+			names := make([]string, 0, len(config.Profiles))
+			for name := range config.Profiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			ExitWithError(fmt.Sprintf("profile %q not found, existing profiles: %s", args[0], strings.Join(names, ", ")))
+		}
+		config.CurrentProfile = args[0]
+		saveConfigExitOnError(config)
+		fmt.Printf("Switched to profile %s\n", args[0])
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <setting> <value>",
+	Short: "Set a setting of a profile",
+	Long: `Set a setting of the profile given with --profile, or of the current profile. The profile is created if it
+           does not exist. An empty value removes the setting.`,
+	Example: `  sparkcli config set url https://spark-gateway.staging.example.com --profile staging
+  sparkcli config set namespace team-a`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		config := loadConfigExitOnError()
+		if err := setProfileValue(config, ProfileName, args[0], args[1]); err != nil {
+			ExitWithError(err.Error())
+		}
+		saveConfigExitOnError(config)
+	},
+}
+
+func init() {
+	configViewCmd.Flags().BoolVarP(&RawConfig, "raw", "", false,
+		"whether to print passwords and tokens")
+	configCmd.AddCommand(configViewCmd, configUseCmd, configSetCmd)
+}
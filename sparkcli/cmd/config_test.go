@@ -0,0 +1,95 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sparkcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sparkcli", "config.yaml")
+
+	config, err := LoadConfig(path)
+	assert.NoError(t, err)
+	assert.NoError(t, setProfileValue(config, "dev", "url", "http://dev:8080"))
+	assert.NoError(t, setProfileValue(config, "dev", "max-retries", "2"))
+	assert.NoError(t, setProfileValue(config, "prod", "url", "https://prod"))
+	assert.NoError(t, setProfileValue(config, "prod", "namespace", "team-a"))
+	assert.NoError(t, setProfileValue(config, "prod", "token", "secret"))
+	assert.Error(t, setProfileValue(config, "prod", "unknown", "value"))
+	assert.Equal(t, "dev", config.CurrentProfile)
+	assert.NoError(t, config.Save(path))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	assert.Equal(t, "REDACTED", redactedConfig(config).Profiles["prod"]["token"])
+	assert.Equal(t, "secret", config.Profiles["prod"]["token"])
+
+	apply := func(args []string, env map[string]string) (string, string, int, error) {
+		for name, value := range env {
+			os.Setenv(name, value)
+			defer os.Unsetenv(name)
+		}
+		var url, namespace string
+		var maxRetries int
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flags.StringVar(&ConfigFile, "config", path, "")
+		flags.StringVar(&ProfileName, "profile", "", "")
+		flags.StringVar(&url, "url", "http://localhost:8080", "")
+		flags.StringVar(&namespace, "namespace", "", "")
+		flags.IntVar(&maxRetries, "max-retries", 4, "")
+		if err := flags.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		err := applyConfig(flags)
+		return url, namespace, maxRetries, err
+	}
+
+	url, namespace, maxRetries, err := apply(nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://dev:8080", url)
+	assert.Equal(t, "", namespace)
+	assert.Equal(t, 2, maxRetries)
+
+	url, namespace, maxRetries, err = apply([]string{"--profile", "prod", "--namespace", "team-b"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://prod", url)
+	assert.Equal(t, "team-b", namespace)
+	assert.Equal(t, 4, maxRetries)
+
+	url, namespace, _, err = apply(nil, map[string]string{"SPARKCLI_PROFILE": "prod", "SPARKCLI_URL": "http://env"})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://env", url)
+	assert.Equal(t, "team-a", namespace)
+
+	_, _, _, err = apply(nil, map[string]string{"SPARKCLI_MAX_RETRIES": "many"})
+	assert.Error(t, err)
+	_, _, _, err = apply([]string{"--profile", "staging"}, nil)
+	assert.Error(t, err)
+}
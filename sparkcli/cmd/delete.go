@@ -0,0 +1,48 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete <submission id>",
+	Short: "Delete an application submission",
+	Long:  ``,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client := newClientExitOnError()
+
+		responseStr, response, err := client.DeleteApplication(args[0])
+		if err != nil {
+			ExitWithError(fmt.Sprintf("Failed to delete submission %s: %s", args[0], err.Error()))
+		}
+
+		if OutputFile != "" {
+			WriteOutputFileExitOnError(OutputFile, responseStr)
+		}
+
+		printObjectExitOnError(os.Stdout, response, fmt.Sprintf("Deleted submission %s", response.SubmissionId))
+	},
+}
+
+func init() {
+	addOutputFlag(deleteCmd)
+}
@@ -0,0 +1,79 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var MaxKillCount int
+
+var killCmd = &cobra.Command{
+	Use:   "kill [submission id]",
+	Short: "Kill an application submission, or all running submissions of an application name",
+	Long: `Kill an application submission with a given submission id. With --application-name, kill the running
+submissions of that application instead.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client := newClientExitOnError()
+
+		if len(args) == 1 && ApplicationName != "" {
+			ExitWithError("Cannot specify both a submission id and an application name")
+		}
+
+		var responseStr string
+		var response interface{}
+		var message string
+		if len(args) == 1 {
+			str, killResponse, err := client.KillApplication(args[0])
+			if err != nil {
+				ExitWithError(fmt.Sprintf("Failed to kill submission %s: %s", args[0], err.Error()))
+			}
+			responseStr, response = str, killResponse
+			message = fmt.Sprintf("Killed submission %s, state: %s", killResponse.SubmissionId, killResponse.State)
+		} else if ApplicationName != "" {
+			str, killResponse, err := client.KillApplicationByName(ApplicationName, MaxKillCount)
+			if err != nil {
+				ExitWithError(fmt.Sprintf("Failed to kill application %s: %s", ApplicationName, err.Error()))
+			}
+			responseStr, response = str, killResponse
+			message = fmt.Sprintf("Killed %d submissions of application %s: %s", len(killResponse.SubmissionIds),
+				ApplicationName, strings.Join(killResponse.SubmissionIds, ", "))
+		} else {
+			ExitWithError("Must specify a submission id or an application name")
+		}
+
+		if OutputFile != "" {
+			WriteOutputFileExitOnError(OutputFile, responseStr)
+		}
+
+		printObjectExitOnError(os.Stdout, response, message)
+	},
+}
+
+func init() {
+	addOutputFlag(killCmd)
+
+	killCmd.Flags().StringVarP(&ApplicationName, "application-name", "", "",
+		"kill the running submissions of the Spark application with this name")
+
+	killCmd.Flags().IntVarP(&MaxKillCount, "max-count", "", 0,
+		"maximum number of submissions to kill by application name, all if not set")
+}
@@ -18,22 +18,34 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"time"
+
 	"github.com/spf13/cobra"
-	"log"
 )
 
 var Limit int64
 var State string
 var IgnoreKilled bool
+var Filters []string
+var MaxAge time.Duration
+var SortBy string
+var Reverse bool
 
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List application submissions",
-	Long:  ``,
+	Long: `List application submissions, most recent first. --limit, --state, --application-name and --ignore-killed
+are applied by the server, --filter, --max-age and --sort-by by sparkcli.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		client := NewBasicAuthClient(ServerUrl, User, Password)
+		filters, err := parseSubmissionFilters(Filters)
+		if err != nil {
+			ExitWithError(err.Error())
+		}
 
-		responseStr, _, err := client.ListSubmissions(Limit, State, IgnoreKilled)
+		client := newClientExitOnError()
+
+		responseStr, response, err := client.ListSubmissions(Limit, State, IgnoreKilled)
 		if err != nil {
 			ExitWithError(fmt.Sprintf("Failed to get application submissions: %s", err.Error()))
 		}
@@ -42,7 +54,14 @@ var listCmd = &cobra.Command{
 			WriteOutputFileExitOnError(OutputFile, responseStr)
 		}
 
-		log.Printf("Response: %s", responseStr)
+		now := time.Now()
+		submissions := filterSubmissions(response.Submissions, filters, MaxAge, now)
+		if err := sortSubmissions(submissions, SortBy, Reverse, now); err != nil {
+			ExitWithError(err.Error())
+		}
+		if err := printSubmissions(os.Stdout, OutputFormat, submissions, now); err != nil {
+			ExitWithError(err.Error())
+		}
 	},
 }
 
@@ -60,4 +79,16 @@ func init() {
 
 	listCmd.Flags().BoolVarP(&IgnoreKilled, "ignore-killed", "", false,
 		"ignore killed spark application")
+
+	listCmd.Flags().StringArrayVarP(&Filters, "filter", "", nil,
+		"only list submissions whose id, name, state or namespace matches a shell pattern, e.g. name=etl-*, can be repeated")
+
+	listCmd.Flags().DurationVarP(&MaxAge, "max-age", "", 0,
+		"only list submissions created within this duration, e.g. 24h")
+
+	listCmd.Flags().StringVarP(&SortBy, "sort-by", "", "",
+		"sort submissions by id, name, state, age or duration")
+
+	listCmd.Flags().BoolVarP(&Reverse, "reverse", "", false,
+		"reverse the order of submissions")
 }
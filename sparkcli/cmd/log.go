@@ -0,0 +1,63 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var ExecutorIds []int
+var DriverLog bool
+var FollowLogs bool
+var LogTimestamps bool
+
+var logCmd = &cobra.Command{
+	Use:   "log <submission id>",
+	Short: "Fetch the driver or executor log of an application submission",
+	Long: `Fetch the driver or executor log of an application submission. Logs of several executors, or of the
+           driver and executors, are fetched concurrently and every line is prefixed with the pod it comes from.
+           Followed logs that break off are resumed after the last line received.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		executorIds := ExecutorIds
+		if len(executorIds) == 0 || DriverLog {
+			executorIds = append([]int{-1}, executorIds...)
+		}
+		options := LogOptions{
+			Follow:     FollowLogs,
+			Timestamps: LogTimestamps,
+			Prefix:     len(executorIds) > 1,
+		}
+
+		client := newClientExitOnError()
+		if err := client.WriteApplicationLogs(args[0], executorIds, options, os.Stdout); err != nil {
+			ExitWithError(err.Error())
+		}
+	},
+}
+
+func init() {
+	logCmd.Flags().IntSliceVarP(&ExecutorIds, "executor", "e", nil,
+		"ids of the executors to fetch logs for, the driver log is fetched if not set")
+	logCmd.Flags().BoolVarP(&DriverLog, "driver", "", false,
+		"whether to fetch the driver log in addition to the executor logs")
+	logCmd.Flags().BoolVarP(&LogTimestamps, "timestamps", "", false,
+		"whether to print the timestamp of every line")
+	logCmd.Flags().BoolVarP(&FollowLogs, "follow", "f", false,
+		"whether to stream the logs")
+}
@@ -0,0 +1,249 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLogReconnects is how often in a row a log stream is reconnected without receiving new lines before giving up.
+const maxLogReconnects = 10
+
+// LogOptions configures how application logs are fetched.
+type LogOptions struct {
+	// Follow streams the logs until the containers terminate.
+	Follow bool
+	// Timestamps keeps the timestamp the gateway prefixes every line with.
+	Timestamps bool
+	// Prefix prefixes every line with the name of its pod, e.g. [driver] or [executor-1].
+	Prefix bool
+}
+
+// WriteApplicationLogs writes the logs of the driver, given as executor ID -1, and of executors to the writer.
+// The logs are streamed concurrently and written line by line, so lines of different pods do not interleave.
+// Streams that break off are reconnected and continue after the last line received.
+func (c *Client) WriteApplicationLogs(submissionId string, executorIds []int, options LogOptions, writer io.Writer) error {
+	out := &lineWriter{writer: writer}
+	errs := make([]error, len(executorIds))
+	var wg sync.WaitGroup
+	for i, executorId := range executorIds {
+		stream := &logStream{
+			client:       c,
+			submissionId: submissionId,
+			executorId:   executorId,
+			options:      options,
+			out:          out,
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = stream.run()
+		}(i)
+	}
+	wg.Wait()
+
+	var messages []string
+	for i, err := range errs {
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", logSourceName(executorIds[i]), err.Error()))
+		}
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("failed to get log of %s", strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+func logSourceName(executorId int) string {
+	if executorId < 0 {
+		return "driver"
+	}
+	return fmt.Sprintf("executor-%d", executorId)
+}
+
+// lineWriter writes whole lines to the writer, one at a time.
+type lineWriter struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+func (w *lineWriter) writeLine(prefix string, line string) error {
+	if !strings.HasSuffix(line, "\n") {
+		line += "\n"
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	_, err := io.WriteString(w.writer, prefix+line)
+	return err
+}
+
+// logStream streams the log of a pod. It requests the lines with timestamps and remembers the timestamp of the
+// last line written, so that a stream that breaks off can be resumed from there without repeating lines.
+type logStream struct {
+	client       *Client
+	submissionId string
+	executorId   int
+	options      LogOptions
+	out          *lineWriter
+
+	// lastTimestamp is the timestamp of the last line written, and linesAtLastTimestamp counts the lines written
+	// with that timestamp.
+	lastTimestamp        time.Time
+	linesAtLastTimestamp int
+	// skip is the number of lines with lastTimestamp a resumed stream repeats.
+	skip    int
+	written int
+}
+
+func (s *logStream) run() error {
+	reconnects := 0
+	endedCleanly := false
+	for {
+		written := s.written
+		interrupted, err := s.stream()
+		progressed := s.written > written
+		if interrupted {
+			if progressed {
+				reconnects = 0
+			}
+			reconnects++
+			if reconnects > maxLogReconnects {
+				return fmt.Errorf("stream was interrupted %d times: %s", reconnects, err.Error())
+			}
+			wait := s.client.retryPolicy.backoff(reconnects)
+			log.Printf("Log stream of %s was interrupted, reconnecting in %s: %s", logSourceName(s.executorId), wait.Round(time.Millisecond), err.Error())
+			time.Sleep(wait)
+			endedCleanly = false
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// A followed log also ends when the connection is closed gracefully, e.g. when the gateway restarts.
+		// Reconnecting once tells that apart from a terminated container, whose log ends right away.
+		if !s.options.Follow || (endedCleanly && !progressed) {
+			return nil
+		}
+		endedCleanly = true
+	}
+}
+
+// stream streams the log once, continuing after the last line written. It returns an error if the log cannot be
+// fetched, and sets interrupted if the stream broke off after it was established.
+func (s *logStream) stream() (bool, error) {
+	query := url.Values{}
+	query.Set("timestamps", "true")
+	if s.executorId >= 0 {
+		query.Set("executor", strconv.Itoa(s.executorId))
+	}
+	if s.options.Follow {
+		query.Set("follow", "true")
+	}
+	if !s.lastTimestamp.IsZero() {
+		query.Set("sinceTime", s.lastTimestamp.Format(time.RFC3339Nano))
+	}
+	url := fmt.Sprintf("%s/submissions/%s/log?%s", s.client.serverUrl, s.submissionId, query.Encode())
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create get request for %s: %s", url, err.Error())
+	}
+	response, err := s.client.do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to get %s: %s", url, err.Error())
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound && s.written > 0 {
+		// The pod was deleted after its log was received, e.g. an executor after the application finished.
+		return false, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return false, ErrorBadHttpStatus(url, response)
+	}
+
+	s.skip = s.linesAtLastTimestamp
+	reader := bufio.NewReaderSize(response.Body, 64*1024)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			// A partial line is dropped here, it is received again when the stream is resumed.
+			return true, err
+		}
+		if line != "" {
+			if err := s.writeLine(line); err != nil {
+				return false, fmt.Errorf("failed to write log: %s", err.Error())
+			}
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+	}
+}
+
+func (s *logStream) writeLine(line string) error {
+	timestamp, text := splitLogTimestamp(line)
+	if !timestamp.IsZero() {
+		switch {
+		case timestamp.Before(s.lastTimestamp):
+			return nil
+		case timestamp.Equal(s.lastTimestamp):
+			if s.skip > 0 {
+				s.skip--
+				return nil
+			}
+			s.linesAtLastTimestamp++
+		default:
+			s.lastTimestamp = timestamp
+			s.linesAtLastTimestamp = 1
+			s.skip = 0
+		}
+	}
+	if s.options.Timestamps {
+		text = line
+	}
+
+	prefix := ""
+	if s.options.Prefix {
+		prefix = "[" + logSourceName(s.executorId) + "] "
+	}
+	s.written++
+	return s.out.writeLine(prefix, text)
+}
+
+// splitLogTimestamp splits the RFC3339 timestamp off a log line. It returns a zero time and the line as it is if
+// the line has no timestamp.
+func splitLogTimestamp(line string) (time.Time, string) {
+	index := strings.IndexByte(line, ' ')
+	if index <= 0 {
+		return time.Time{}, line
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, line[:index])
+	if err != nil {
+		return time.Time{}, line
+	}
+	return timestamp, line[index+1:]
+}
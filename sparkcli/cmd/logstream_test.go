@@ -0,0 +1,123 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeLogServer serves timestamped log lines like the Kubernetes API does, returning lines logged at or after the
+// second of sinceTime. The first request is aborted after the given number of lines and a partial line.
+type fakeLogServer struct {
+	lines      map[string][]string
+	abortAfter int
+	requests   []string
+}
+
+func (f *fakeLogServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests = append(f.requests, r.URL.RawQuery)
+	lines, ok := f.lines[r.URL.Query().Get("executor")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	var since time.Time
+	if value := r.URL.Query().Get("sinceTime"); value != "" {
+		since, _ = time.Parse(time.RFC3339Nano, value)
+		since = since.Truncate(time.Second)
+	}
+
+	written := 0
+	for _, line := range lines {
+		timestamp, _ := splitLogTimestamp(line)
+		if timestamp.Before(since) {
+			continue
+		}
+		if len(f.requests) == 1 && f.abortAfter > 0 && written == f.abortAfter {
+			fmt.Fprint(w, line[:len(line)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		fmt.Fprintln(w, line)
+		written++
+	}
+}
+
+func TestWriteApplicationLogsResumesInterruptedStream(t *testing.T) {
+	fake := &fakeLogServer{
+		lines: map[string][]string{"": {
+			"2021-01-01T00:00:01.100000000Z a",
+			"2021-01-01T00:00:01.500000000Z b",
+			"2021-01-01T00:00:02.200000000Z c",
+			"2021-01-01T00:00:02.200000000Z d",
+			"2021-01-01T00:00:03.000000000Z e",
+		}},
+		abortAfter: 3,
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := NewClient(server.URL, "", nil, nil)
+	client.SetRetryPolicy(testRetryPolicy)
+	out := &bytes.Buffer{}
+	err := client.WriteApplicationLogs("s-1", []int{-1}, LogOptions{Follow: true}, out)
+	assert.NoError(t, err)
+	assert.Equal(t, "a\nb\nc\nd\ne\n", out.String())
+
+	// The interrupted stream is resumed from the last line, and a stream ending without new lines ends following.
+	assert.Equal(t, 3, len(fake.requests))
+	assert.Contains(t, fake.requests[1], "sinceTime=2021-01-01T00%3A00%3A02.2Z")
+	assert.Contains(t, fake.requests[2], "sinceTime=2021-01-01T00%3A00%3A03Z")
+}
+
+func TestWriteApplicationLogsOfSeveralPods(t *testing.T) {
+	fake := &fakeLogServer{
+		lines: map[string][]string{
+			"":  {"2021-01-01T00:00:01Z driver started", "2021-01-01T00:00:05Z driver done"},
+			"1": {"2021-01-01T00:00:02Z task 1"},
+			"2": {"2021-01-01T00:00:03Z task 2"},
+		},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := NewClient(server.URL, "", nil, nil)
+	out := &bytes.Buffer{}
+	err := client.WriteApplicationLogs("s-1", []int{-1, 1, 2}, LogOptions{Prefix: true, Timestamps: true}, out)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	sort.Strings(lines)
+	assert.Equal(t, []string{
+		"[driver] 2021-01-01T00:00:01Z driver started",
+		"[driver] 2021-01-01T00:00:05Z driver done",
+		"[executor-1] 2021-01-01T00:00:02Z task 1",
+		"[executor-2] 2021-01-01T00:00:03Z task 2",
+	}, lines)
+
+	err = client.WriteApplicationLogs("s-1", []int{-1, 3}, LogOptions{}, out)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "executor-3")
+}
@@ -0,0 +1,268 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/yaml"
+
+	apigatewayv1 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apigateway/apis/v1"
+)
+
+const (
+	tableOutput = "table"
+	wideOutput  = "wide"
+	jsonOutput  = "json"
+	yamlOutput  = "yaml"
+)
+
+var OutputFormat string
+
+func validateOutputFormat(format string) error {
+	switch format {
+	case tableOutput, wideOutput, jsonOutput, yamlOutput:
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q, must be one of table, wide, json or yaml", format)
+}
+
+// printStructured prints the object as JSON or YAML. It returns false without printing anything if the format is
+// a table format, which the caller renders itself.
+func printStructured(w io.Writer, format string, obj interface{}) (bool, error) {
+	var data []byte
+	var err error
+	switch format {
+	case jsonOutput:
+		data, err = json.MarshalIndent(obj, "", "  ")
+		data = append(data, '\n')
+	case yamlOutput:
+		data, err = yaml.Marshal(obj)
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return true, fmt.Errorf("failed to serialize output: %s", err.Error())
+	}
+	_, err = w.Write(data)
+	return true, err
+}
+
+// printObjectExitOnError prints the object as JSON or YAML, or prints the message for table formats.
+func printObjectExitOnError(w io.Writer, obj interface{}, message string) {
+	printed, err := printStructured(w, OutputFormat, obj)
+	if err != nil {
+		ExitWithError(err.Error())
+	}
+	if !printed {
+		fmt.Fprintln(w, message)
+	}
+}
+
+// printSubmissions prints the submissions in the format. JSON and YAML output is a ListSubmissionsResponse.
+func printSubmissions(w io.Writer, format string, submissions []apigatewayv1.SubmissionStatusResponse, now time.Time) error {
+	if printed, err := printStructured(w, format, apigatewayv1.ListSubmissionsResponse{Submissions: submissions}); printed {
+		return err
+	}
+
+	header := []string{"Submission ID", "Name", "State", "Age", "Duration", "Executors"}
+	if format == wideOutput {
+		header = append(header, "Namespace", "Failed Executors", "Driver Pod", "Spark Application ID")
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	for _, submission := range submissions {
+		row := []string{
+			submission.SubmissionId,
+			formatNotAvailable(submission.ApplicationName),
+			submission.State,
+			formatAge(submission, now),
+			formatDuration(submission, now),
+			formatExecutors(submission.Executors),
+		}
+		if format == wideOutput {
+			row = append(row,
+				submission.Namespace,
+				fmt.Sprintf("%d", submission.Executors.Failed),
+				formatNotAvailable(submission.DriverPodName),
+				formatNotAvailable(submission.SparkApplicationId))
+		}
+		table.Append(row)
+	}
+	table.Render()
+	return nil
+}
+
+// printSubmissionExitOnError prints a single submission. JSON and YAML output is a SubmissionStatusResponse.
+func printSubmissionExitOnError(w io.Writer, submission apigatewayv1.SubmissionStatusResponse) {
+	printed, err := printStructured(w, OutputFormat, submission)
+	if !printed {
+		err = printSubmissions(w, OutputFormat, []apigatewayv1.SubmissionStatusResponse{submission}, time.Now())
+		if err == nil && submission.ErrorMessage != "" {
+			fmt.Fprintf(w, "\nerror message: %s\n", submission.ErrorMessage)
+		}
+	}
+	if err != nil {
+		ExitWithError(err.Error())
+	}
+}
+
+func formatNotAvailable(info string) string {
+	if info == "" {
+		return "N.A."
+	}
+	return info
+}
+
+func formatAge(submission apigatewayv1.SubmissionStatusResponse, now time.Time) string {
+	if submission.CreationTime.IsZero() {
+		return "N.A."
+	}
+	return duration.ShortHumanDuration(now.Sub(submission.CreationTime.Time))
+}
+
+// runDuration returns how long the application has been running, or ran if it terminated. It is zero if the
+// application was not submitted yet.
+func runDuration(submission apigatewayv1.SubmissionStatusResponse, now time.Time) time.Duration {
+	if submission.SubmissionTime.IsZero() {
+		return 0
+	}
+	end := now
+	if !submission.TerminationTime.IsZero() {
+		end = submission.TerminationTime.Time
+	}
+	return end.Sub(submission.SubmissionTime.Time)
+}
+
+func formatDuration(submission apigatewayv1.SubmissionStatusResponse, now time.Time) string {
+	if submission.SubmissionTime.IsZero() {
+		return "N.A."
+	}
+	return duration.ShortHumanDuration(runDuration(submission, now))
+}
+
+// formatExecutors formats executor counts as running/total.
+func formatExecutors(executors apigatewayv1.ExecutorCounts) string {
+	total := executors.Pending + executors.Running + executors.Completed + executors.Failed
+	return fmt.Sprintf("%d/%d", executors.Running, total)
+}
+
+// submissionFilter matches a column of a submission against a shell pattern, e.g. state=RUNNING or name=etl-*.
+type submissionFilter struct {
+	column  string
+	pattern string
+}
+
+func parseSubmissionFilters(filters []string) ([]submissionFilter, error) {
+	var result []submissionFilter
+	for _, filter := range filters {
+		kv := strings.SplitN(filter, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid filter %q, expected column=pattern", filter)
+		}
+		column := strings.ToLower(kv[0])
+		if _, err := submissionColumn(apigatewayv1.SubmissionStatusResponse{}, column); err != nil {
+			return nil, err
+		}
+		if _, err := path.Match(kv[1], ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern in filter %q: %s", filter, err.Error())
+		}
+		result = append(result, submissionFilter{column: column, pattern: kv[1]})
+	}
+	return result, nil
+}
+
+func submissionColumn(submission apigatewayv1.SubmissionStatusResponse, column string) (string, error) {
+	switch column {
+	case "id":
+		return submission.SubmissionId, nil
+	case "name":
+		return submission.ApplicationName, nil
+	case "state":
+		return submission.State, nil
+	case "namespace":
+		return submission.Namespace, nil
+	}
+	return "", fmt.Errorf("unsupported filter column %q, must be one of id, name, state or namespace", column)
+}
+
+// filterSubmissions returns the submissions matching all filters, and created within maxAge if it is positive.
+func filterSubmissions(submissions []apigatewayv1.SubmissionStatusResponse, filters []submissionFilter, maxAge time.Duration, now time.Time) []apigatewayv1.SubmissionStatusResponse {
+	result := []apigatewayv1.SubmissionStatusResponse{}
+	for _, submission := range submissions {
+		if maxAge > 0 && now.Sub(submission.CreationTime.Time) > maxAge {
+			continue
+		}
+		matches := true
+		for _, filter := range filters {
+			value, _ := submissionColumn(submission, filter.column)
+			if matched, _ := path.Match(filter.pattern, value); !matched {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			result = append(result, submission)
+		}
+	}
+	return result
+}
+
+// sortSubmissions sorts the submissions by a column: id, name, state, age or duration. Age sorts the most recent
+// submission first, duration the shortest-running one.
+func sortSubmissions(submissions []apigatewayv1.SubmissionStatusResponse, sortBy string, reverse bool, now time.Time) error {
+	var less func(a, b apigatewayv1.SubmissionStatusResponse) bool
+	switch strings.ToLower(sortBy) {
+	case "":
+		if reverse {
+			for i, j := 0, len(submissions)-1; i < j; i, j = i+1, j-1 {
+				submissions[i], submissions[j] = submissions[j], submissions[i]
+			}
+		}
+		return nil
+	case "id":
+		less = func(a, b apigatewayv1.SubmissionStatusResponse) bool { return a.SubmissionId < b.SubmissionId }
+	case "name":
+		less = func(a, b apigatewayv1.SubmissionStatusResponse) bool { return a.ApplicationName < b.ApplicationName }
+	case "state":
+		less = func(a, b apigatewayv1.SubmissionStatusResponse) bool { return a.State < b.State }
+	case "age":
+		less = func(a, b apigatewayv1.SubmissionStatusResponse) bool { return b.CreationTime.Before(&a.CreationTime) }
+	case "duration":
+		less = func(a, b apigatewayv1.SubmissionStatusResponse) bool {
+			return runDuration(a, now) < runDuration(b, now)
+		}
+	default:
+		return fmt.Errorf("unsupported sort column %q, must be one of id, name, state, age or duration", sortBy)
+	}
+
+	sort.SliceStable(submissions, func(i, j int) bool {
+		if reverse {
+			return less(submissions[j], submissions[i])
+		}
+		return less(submissions[i], submissions[j])
+	})
+	return nil
+}
@@ -0,0 +1,135 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	apigatewayv1 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apigateway/apis/v1"
+)
+
+func testSubmissions(now time.Time) []apigatewayv1.SubmissionStatusResponse {
+	return []apigatewayv1.SubmissionStatusResponse{
+		{
+			SubmissionId: "s-3",
+			State:        "NEW",
+			Namespace:    "team-a",
+			CreationTime: metav1.NewTime(now.Add(-time.Minute)),
+		},
+		{
+			SubmissionId:    "s-2",
+			ApplicationName: "etl-daily",
+			State:           "RUNNING",
+			Namespace:       "team-a",
+			CreationTime:    metav1.NewTime(now.Add(-2 * time.Hour)),
+			SubmissionTime:  metav1.NewTime(now.Add(-2 * time.Hour)),
+			Executors:       apigatewayv1.ExecutorCounts{Pending: 1, Running: 2},
+		},
+		{
+			SubmissionId:    "s-1",
+			ApplicationName: "etl-hourly",
+			State:           "COMPLETED",
+			Namespace:       "team-b",
+			CreationTime:    metav1.NewTime(now.Add(-48 * time.Hour)),
+			SubmissionTime:  metav1.NewTime(now.Add(-48 * time.Hour)),
+			TerminationTime: metav1.NewTime(now.Add(-47 * time.Hour)),
+			Executors:       apigatewayv1.ExecutorCounts{Completed: 3, Failed: 1},
+		},
+	}
+}
+
+func submissionIds(submissions []apigatewayv1.SubmissionStatusResponse) []string {
+	ids := []string{}
+	for _, submission := range submissions {
+		ids = append(ids, submission.SubmissionId)
+	}
+	return ids
+}
+
+func TestFilterSubmissions(t *testing.T) {
+	now := time.Now()
+
+	filters, err := parseSubmissionFilters([]string{"name=etl-*", "namespace=team-a"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"s-2"}, submissionIds(filterSubmissions(testSubmissions(now), filters, 0, now)))
+	assert.Equal(t, []string{"s-3", "s-2"}, submissionIds(filterSubmissions(testSubmissions(now), nil, 24*time.Hour, now)))
+
+	_, err = parseSubmissionFilters([]string{"driver=x"})
+	assert.NotNil(t, err)
+	_, err = parseSubmissionFilters([]string{"state"})
+	assert.NotNil(t, err)
+}
+
+func TestSortSubmissions(t *testing.T) {
+	now := time.Now()
+	testFn := func(sortBy string, reverse bool, expected []string) {
+		submissions := testSubmissions(now)
+		assert.Nil(t, sortSubmissions(submissions, sortBy, reverse, now))
+		assert.Equal(t, expected, submissionIds(submissions), "sort by %q, reverse %t", sortBy, reverse)
+	}
+	testFn("", false, []string{"s-3", "s-2", "s-1"})
+	testFn("", true, []string{"s-1", "s-2", "s-3"})
+	testFn("id", false, []string{"s-1", "s-2", "s-3"})
+	testFn("state", false, []string{"s-1", "s-3", "s-2"})
+	testFn("age", true, []string{"s-1", "s-2", "s-3"})
+	testFn("duration", false, []string{"s-3", "s-1", "s-2"})
+
+	assert.NotNil(t, sortSubmissions(testSubmissions(now), "driver", false, now))
+}
+
+func TestPrintSubmissions(t *testing.T) {
+	now := time.Now()
+
+	var out bytes.Buffer
+	assert.Nil(t, printSubmissions(&out, tableOutput, testSubmissions(now), now))
+	lines := strings.Split(out.String(), "\n")
+	assert.Contains(t, lines[1], "SUBMISSION ID")
+	assert.NotContains(t, lines[1], "NAMESPACE")
+	assert.Regexp(t, `s-2 +\| etl-daily +\| RUNNING +\| 2h +\| 2h +\| 2/3`, out.String())
+	assert.Regexp(t, `s-1 +\| etl-hourly +\| COMPLETED +\| 2d +\| 1h +\| 0/4`, out.String())
+	assert.Regexp(t, `s-3 +\| N.A. +\| NEW +\| 1m +\| N.A. +\| 0/0`, out.String())
+
+	out.Reset()
+	assert.Nil(t, printSubmissions(&out, wideOutput, testSubmissions(now), now))
+	assert.Contains(t, out.String(), "NAMESPACE")
+	assert.Contains(t, out.String(), "team-b")
+
+	for _, format := range []string{jsonOutput, yamlOutput} {
+		out.Reset()
+		assert.Nil(t, printSubmissions(&out, format, testSubmissions(now), now))
+		data := out.Bytes()
+		if format == yamlOutput {
+			var err error
+			data, err = yaml.YAMLToJSON(data)
+			assert.Nil(t, err)
+		}
+		parsed := apigatewayv1.ListSubmissionsResponse{}
+		assert.Nil(t, json.Unmarshal(data, &parsed))
+		assert.Equal(t, []string{"s-3", "s-2", "s-1"}, submissionIds(parsed.Submissions))
+		assert.Equal(t, 2, parsed.Submissions[1].Executors.Running)
+	}
+
+	assert.NotNil(t, validateOutputFormat("xml"))
+}
@@ -0,0 +1,171 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var ServerUrl string
+var User string
+var Password string
+var Token string
+var TokenFile string
+var OIDCIssuerUrl string
+var OIDCClientId string
+var OIDCCacheDir string
+var ClientCertFile string
+var ClientKeyFile string
+var Namespace string
+var CAFile string
+var InsecureSkipTLSVerify bool
+var ProxyUrl string
+var ConnectTimeout time.Duration
+var RequestTimeout time.Duration
+var MaxRetries int
+var ApplicationName string
+
+var rootCmd = &cobra.Command{
+	Use:   "sparkcli",
+	Short: "sparkcli is the command-line tool for working with the Spark API gateway",
+	Long: `sparkcli is the command-line tool for working with the Spark API gateway. It supports submitting, killing,
+           deleting and checking status of Spark applications without access to the Kubernetes API server. It also
+           supports fetching application logs and waiting for applications to finish.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyConfig(cmd.Flags()); err != nil {
+			return err
+		}
+		return validateOutputFormat(OutputFormat)
+	},
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&ConfigFile, "config", "", DefaultConfigFile(),
+		"The sparkcli config file, can also be set with SPARKCLI_CONFIG")
+	rootCmd.PersistentFlags().StringVarP(&ProfileName, "profile", "", "",
+		"The profile of the config file to use, defaults to the current profile")
+	rootCmd.PersistentFlags().StringVarP(&ServerUrl, "url", "", "http://localhost:8080",
+		"The URL of the Spark API gateway")
+	rootCmd.PersistentFlags().StringVarP(&User, "user", "", "",
+		"The user name to authenticate with")
+	rootCmd.PersistentFlags().StringVarP(&Password, "password", "", "",
+		"The password to authenticate with")
+	rootCmd.PersistentFlags().StringVarP(&Token, "token", "", "",
+		"The bearer token to authenticate with")
+	rootCmd.PersistentFlags().StringVarP(&TokenFile, "token-file", "", "",
+		"The file to read the bearer token to authenticate with from")
+	rootCmd.PersistentFlags().StringVarP(&OIDCIssuerUrl, "oidc-issuer-url", "", "",
+		"The URL of the OIDC provider to log in with through the device flow")
+	rootCmd.PersistentFlags().StringVarP(&OIDCClientId, "oidc-client-id", "", "",
+		"The OIDC client ID to log in with")
+	rootCmd.PersistentFlags().StringVarP(&OIDCCacheDir, "oidc-cache-dir", "", DefaultTokenCacheDir(),
+		"The directory OIDC tokens are cached in")
+	rootCmd.PersistentFlags().StringVarP(&ClientCertFile, "client-cert", "", "",
+		"The client certificate file for mTLS authentication")
+	rootCmd.PersistentFlags().StringVarP(&ClientKeyFile, "client-key", "", "",
+		"The client key file for mTLS authentication")
+	rootCmd.PersistentFlags().StringVarP(&CAFile, "ca-file", "", "",
+		"A PEM file of CAs to trust in addition to the system CAs")
+	rootCmd.PersistentFlags().BoolVarP(&InsecureSkipTLSVerify, "insecure-skip-tls-verify", "", false,
+		"Do not verify the server certificate. Only use this for development")
+	rootCmd.PersistentFlags().StringVarP(&ProxyUrl, "proxy", "", "",
+		"The proxy URL to send requests through, defaults to the proxy environment variables")
+	rootCmd.PersistentFlags().DurationVarP(&ConnectTimeout, "connect-timeout", "", 10*time.Second,
+		"The timeout for connecting to the Spark API gateway")
+	rootCmd.PersistentFlags().DurationVarP(&RequestTimeout, "request-timeout", "", 60*time.Second,
+		"The timeout for receiving a response after sending a request, 0 to wait indefinitely")
+	rootCmd.PersistentFlags().IntVarP(&MaxRetries, "max-retries", "", DefaultRetryPolicy.MaxAttempts-1,
+		"How often failed requests are retried")
+	rootCmd.PersistentFlags().StringVarP(&OutputFormat, "output", "o", tableOutput,
+		"The output format: table, wide, json or yaml")
+	rootCmd.PersistentFlags().StringVarP(&Namespace, "namespace", "n", "",
+		"The namespace to use, defaults to the first namespace the user may use")
+	rootCmd.AddCommand(submitCmd, statusCmd, logCmd, killCmd, deleteCmd, waitCmd, listCmd, configCmd)
+}
+
+// newClient creates a Client from the global flags. Bearer tokens take precedence over OIDC login, which
+// takes precedence over basic authentication. Client certificates can be combined with any of them.
+func newClient() (*Client, error) {
+	httpClient, err := NewHTTPClient(TransportConfig{
+		CAFile:             CAFile,
+		InsecureSkipVerify: InsecureSkipTLSVerify,
+		ClientCertFile:     ClientCertFile,
+		ClientKeyFile:      ClientKeyFile,
+		ProxyURL:           ProxyUrl,
+		ConnectTimeout:     ConnectTimeout,
+		ResponseTimeout:    RequestTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var authenticator Authenticator
+	switch {
+	case Token != "" || TokenFile != "":
+		token := Token
+		if TokenFile != "" {
+			content, err := ioutil.ReadFile(TokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read token file %s: %s", TokenFile, err.Error())
+			}
+			token = strings.TrimSpace(string(content))
+		}
+		authenticator = &TokenAuthenticator{Token: token}
+	case OIDCIssuerUrl != "":
+		if OIDCClientId == "" {
+			return nil, fmt.Errorf("--oidc-client-id is required with --oidc-issuer-url")
+		}
+		deviceFlowAuthenticator := NewDeviceFlowAuthenticator(OIDCIssuerUrl, OIDCClientId, OIDCCacheDir)
+		// The OIDC provider is usually reached through the same proxy and trusted CAs as the gateway.
+		deviceFlowAuthenticator.httpClient = &http.Client{Transport: httpClient.Transport, Timeout: 30 * time.Second}
+		authenticator = deviceFlowAuthenticator
+	case User != "":
+		authenticator = &BasicAuthenticator{Credential: UserCredential{Name: User, Password: Password}}
+	}
+
+	if MaxRetries < 0 {
+		return nil, fmt.Errorf("--max-retries must not be negative")
+	}
+	client := NewClient(ServerUrl, Namespace, authenticator, httpClient)
+	retryPolicy := DefaultRetryPolicy
+	retryPolicy.MaxAttempts = MaxRetries + 1
+	client.SetRetryPolicy(retryPolicy)
+	return client, nil
+}
+
+// newClientExitOnError creates a Client from the global flags and exits if that fails.
+func newClientExitOnError() *Client {
+	client, err := newClient()
+	if err != nil {
+		ExitWithError(err.Error())
+	}
+	return client
+}
+
+func Execute() {
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v", err)
+		os.Exit(1)
+	}
+}
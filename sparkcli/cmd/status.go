@@ -0,0 +1,48 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status <submission id>",
+	Short: "Check status of an application submission",
+	Long:  ``,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client := newClientExitOnError()
+
+		responseStr, status, err := client.GetApplicationStatus(args[0])
+		if err != nil {
+			ExitWithError(fmt.Sprintf("Failed to get status of submission %s: %s", args[0], err.Error()))
+		}
+
+		if OutputFile != "" {
+			WriteOutputFileExitOnError(OutputFile, responseStr)
+		}
+
+		printSubmissionExitOnError(os.Stdout, status)
+	},
+}
+
+func init() {
+	addOutputFlag(statusCmd)
+}
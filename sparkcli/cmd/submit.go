@@ -0,0 +1,200 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	apigatewayv1 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apigateway/apis/v1"
+)
+
+var SubmissionId string
+var Overwrite bool
+var ApplicationType string
+var SparkVersion string
+var Image string
+var MainClass string
+var SparkConf []string
+var HadoopConf []string
+var Jars []string
+var Files []string
+var PyFiles []string
+var DriverCores int32
+var DriverMemory string
+var ExecutorCores int32
+var ExecutorMemory string
+var NumExecutors int32
+var UploadChunkSizeMb int64
+
+var submitCmd = &cobra.Command{
+	Use:   "submit <main application file> [application arguments]",
+	Short: "Submit a Spark application",
+	Long: `Submit a Spark application through the API gateway and print its submission ID. Local files given as the
+main application file, jars, files or Python files are uploaded first.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client := newClientExitOnError()
+
+		request, err := buildSubmissionRequest(client, args[0], args[1:])
+		if err != nil {
+			ExitWithError(err.Error())
+		}
+
+		var submissionId string
+		if SubmissionId != "" {
+			submissionId, err = client.SubmitApplicationWithId(request, SubmissionId, Overwrite)
+		} else {
+			submissionId, err = client.SubmitApplication(request)
+		}
+		if err != nil {
+			ExitWithError(fmt.Sprintf("Failed to submit application: %s", err.Error()))
+		}
+
+		log.Printf("Submitted application, submission id: %s", submissionId)
+		// The table output is just the ID, so that scripts can capture it.
+		printObjectExitOnError(os.Stdout, apigatewayv1.SparkApplicationSubmissionResponse{SubmissionId: submissionId}, submissionId)
+	},
+}
+
+func init() {
+	submitCmd.Flags().StringVarP(&ApplicationName, "application-name", "", "",
+		"the name of the Spark application")
+	submitCmd.Flags().StringVarP(&SubmissionId, "submission-id", "", "",
+		"the submission id to use instead of a generated one")
+	submitCmd.Flags().BoolVarP(&Overwrite, "overwrite", "", false,
+		"overwrite an existing submission with the same submission id")
+	submitCmd.Flags().StringVarP(&ApplicationType, "type", "", "",
+		"the type of the Spark application: Java, Scala, Python or R")
+	submitCmd.Flags().StringVarP(&SparkVersion, "spark-version", "", "",
+		"the version of Spark the application uses")
+	submitCmd.Flags().StringVarP(&Image, "image", "", "",
+		"the container image for the driver and executors")
+	submitCmd.Flags().StringVarP(&MainClass, "class", "", "",
+		"the main class of the Spark application")
+	submitCmd.Flags().StringArrayVarP(&SparkConf, "conf", "", nil,
+		"a Spark configuration property in the form key=value, can be repeated")
+	submitCmd.Flags().StringArrayVarP(&HadoopConf, "hadoop-conf", "", nil,
+		"a Hadoop configuration property in the form key=value, can be repeated")
+	submitCmd.Flags().StringSliceVarP(&Jars, "jars", "", nil,
+		"comma-separated list of jars the application depends on")
+	submitCmd.Flags().StringSliceVarP(&Files, "files", "", nil,
+		"comma-separated list of files the application depends on")
+	submitCmd.Flags().StringSliceVarP(&PyFiles, "py-files", "", nil,
+		"comma-separated list of Python files the application depends on")
+	submitCmd.Flags().Int32VarP(&DriverCores, "driver-cores", "", 0,
+		"number of cores of the driver")
+	submitCmd.Flags().StringVarP(&DriverMemory, "driver-memory", "", "",
+		"amount of memory of the driver, e.g. 2g")
+	submitCmd.Flags().Int32VarP(&ExecutorCores, "executor-cores", "", 0,
+		"number of cores of each executor")
+	submitCmd.Flags().StringVarP(&ExecutorMemory, "executor-memory", "", "",
+		"amount of memory of each executor, e.g. 4g")
+	submitCmd.Flags().Int32VarP(&NumExecutors, "num-executors", "", 0,
+		"number of executors")
+	submitCmd.Flags().Int64VarP(&UploadChunkSizeMb, "upload-chunk-size", "", 16,
+		"size in MiB of the chunks local files are uploaded in")
+}
+
+func buildSubmissionRequest(client *Client, mainApplicationFile string, arguments []string) (apigatewayv1.SparkApplicationSubmissionRequest, error) {
+	request := apigatewayv1.SparkApplicationSubmissionRequest{
+		ApplicationName: ApplicationName,
+		Type:            ApplicationType,
+		SparkVersion:    SparkVersion,
+		Image:           Image,
+		MainClass:       MainClass,
+		Arguments:       arguments,
+		Driver: apigatewayv1.DriverSpec{
+			Cores:  DriverCores,
+			Memory: DriverMemory,
+		},
+		Executor: apigatewayv1.ExecutorSpec{
+			Instances: NumExecutors,
+			Cores:     ExecutorCores,
+			Memory:    ExecutorMemory,
+		},
+	}
+
+	var err error
+	if request.SparkConf, err = parseKeyValues(SparkConf); err != nil {
+		return request, err
+	}
+	if request.HadoopConf, err = parseKeyValues(HadoopConf); err != nil {
+		return request, err
+	}
+	if request.MainApplicationFile, err = uploadLocalFile(client, mainApplicationFile); err != nil {
+		return request, err
+	}
+	if request.Jars, err = uploadLocalFiles(client, Jars); err != nil {
+		return request, err
+	}
+	if request.Files, err = uploadLocalFiles(client, Files); err != nil {
+		return request, err
+	}
+	if request.PyFiles, err = uploadLocalFiles(client, PyFiles); err != nil {
+		return request, err
+	}
+	return request, nil
+}
+
+func parseKeyValues(keyValues []string) (map[string]string, error) {
+	if len(keyValues) == 0 {
+		return nil, nil
+	}
+	result := make(map[string]string)
+	for _, keyValue := range keyValues {
+		kv := strings.SplitN(keyValue, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid configuration property %q, expected key=value", keyValue)
+		}
+		result[kv[0]] = kv[1]
+	}
+	return result, nil
+}
+
+func uploadLocalFiles(client *Client, filePaths []string) ([]string, error) {
+	var result []string
+	for _, filePath := range filePaths {
+		url, err := uploadLocalFile(client, filePath)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, url)
+	}
+	return result, nil
+}
+
+// uploadLocalFile uploads the file if it is a local path and returns its remote URL. Paths with a scheme,
+// e.g. s3a:// or local://, are returned as they are.
+func uploadLocalFile(client *Client, filePath string) (string, error) {
+	if strings.Contains(filePath, "://") {
+		return filePath, nil
+	}
+	if _, err := os.Stat(filePath); err != nil {
+		return "", fmt.Errorf("cannot find local file %s: %s", filePath, err.Error())
+	}
+	url, err := client.UploadFile(filePath, UploadChunkSizeMb<<20)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file %s: %s", filePath, err.Error())
+	}
+	log.Printf("Uploaded file %s to %s", filePath, url)
+	return url, nil
+}
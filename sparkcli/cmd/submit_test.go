@@ -0,0 +1,47 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeyValues(t *testing.T) {
+	result, err := parseKeyValues([]string{"spark.executor.instances=2", "spark.driver.extraJavaOptions=-Da=b"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"spark.executor.instances":      "2",
+		"spark.driver.extraJavaOptions": "-Da=b",
+	}, result)
+
+	result, err = parseKeyValues(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, result)
+
+	_, err = parseKeyValues([]string{"spark.executor.instances"})
+	assert.NotNil(t, err)
+}
+
+func TestUploadLocalFileKeepsRemoteURLs(t *testing.T) {
+	url, err := uploadLocalFile(nil, "s3a://bucket/app.jar")
+	assert.Nil(t, err)
+	assert.Equal(t, "s3a://bucket/app.jar", url)
+
+	_, err = uploadLocalFile(nil, "/non-existent/app.jar")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,213 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// maxRetryAfter caps how long a Retry-After header can make the client wait before the next attempt.
+const maxRetryAfter = 5 * time.Minute
+
+// TransportConfig configures how the client connects to the API gateway.
+type TransportConfig struct {
+	// CAFile is a PEM bundle of CAs trusted in addition to the system CAs.
+	CAFile string
+	// InsecureSkipVerify disables verification of the server certificate. Only meant for development.
+	InsecureSkipVerify bool
+	// ClientCertFile and ClientKeyFile are a client certificate and key for mTLS.
+	ClientCertFile string
+	ClientKeyFile  string
+	// ProxyURL is the proxy requests are sent through. The proxy environment variables are used if empty.
+	ProxyURL string
+	// ConnectTimeout limits how long establishing a connection may take.
+	ConnectTimeout time.Duration
+	// ResponseTimeout limits how long to wait for the response headers once a request was sent. It does not
+	// limit reading the response body, so streamed logs are not cut off.
+	ResponseTimeout time.Duration
+}
+
+// NewHTTPClient creates an http.Client from the transport configuration.
+func NewHTTPClient(config TransportConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   config.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.ResponseHeaderTimeout = config.ResponseTimeout
+
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %s: %s", config.ProxyURL, err.Error())
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+	if config.CAFile != "" {
+		caBundle, err := ioutil.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file %s: %s", config.CAFile, err.Error())
+		}
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil || rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	if config.ClientCertFile != "" || config.ClientKeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(config.ClientCertFile, config.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %s", err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// RetryPolicy decides how failed requests are retried. Requests are retried on connection errors and on the
+// 429, 502, 503 and 504 status codes, and requests that do not create anything, i.e. all but POST, also on
+// other 5xx status codes. The wait between attempts grows exponentially, unless the server asks for a longer
+// wait with a Retry-After header.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a request, including the first one.
+	MaxAttempts int
+	// InitialInterval is the wait before the first retry.
+	InitialInterval time.Duration
+	// MaxInterval caps the wait between attempts.
+	MaxInterval time.Duration
+	// Multiplier is the factor the wait grows by with every attempt.
+	Multiplier float64
+}
+
+// DefaultRetryPolicy is the RetryPolicy of clients that do not specify one.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:     5,
+	InitialInterval: time.Second,
+	MaxInterval:     30 * time.Second,
+	Multiplier:      2,
+}
+
+// shouldRetry returns whether a request with the method that failed with the error or got the response
+// should be retried.
+func (p RetryPolicy) shouldRetry(method string, response *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch response.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return response.StatusCode >= 500 && method != http.MethodPost
+}
+
+// backoff returns the wait before the given retry, starting at 1, with up to 20% of jitter.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	interval := float64(p.InitialInterval)
+	for i := 1; i < retry; i++ {
+		interval *= p.Multiplier
+		if p.MaxInterval > 0 && interval > float64(p.MaxInterval) {
+			break
+		}
+	}
+	if p.MaxInterval > 0 && interval > float64(p.MaxInterval) {
+		interval = float64(p.MaxInterval)
+	}
+	return time.Duration(interval * (0.8 + 0.2*rand.Float64()))
+}
+
+// retryAfter parses the Retry-After header of the response, given in seconds or as an HTTP date.
+func retryAfter(response *http.Response, now time.Time) time.Duration {
+	if response == nil {
+		return 0
+	}
+	value := response.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		wait = date.Sub(now)
+	}
+	if wait > maxRetryAfter {
+		wait = maxRetryAfter
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// send sends the request, retrying it according to the retry policy, and returns the last response and the
+// number of attempts. Requests with a body that cannot be sent again, i.e. without GetBody, are not retried.
+func (c *Client) send(req *http.Request) (*http.Response, int, error) {
+	policy := c.retryPolicy
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, attempt - 1, fmt.Errorf("failed to rewind request body: %s", err.Error())
+			}
+			req.Body = body
+		}
+		if c.authenticator != nil {
+			if err := c.authenticator.Authenticate(req); err != nil {
+				return nil, attempt, err
+			}
+		}
+
+		response, err := c.httpClient.Do(req)
+		canRetry := attempt < policy.MaxAttempts && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+		if !canRetry || !policy.shouldRetry(req.Method, response, err) {
+			return response, attempt, err
+		}
+
+		wait := policy.backoff(attempt)
+		if after := retryAfter(response, time.Now()); after > wait {
+			wait = after
+		}
+		if err != nil {
+			log.Printf("Request to %s failed, retrying in %s: %s", req.URL.Redacted(), wait.Round(time.Millisecond), err.Error())
+		} else {
+			log.Printf("Request to %s got status %d, retrying in %s", req.URL.Redacted(), response.StatusCode, wait.Round(time.Millisecond))
+			response.Body.Close()
+		}
+		time.Sleep(wait)
+	}
+}
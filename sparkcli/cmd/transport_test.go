@@ -0,0 +1,170 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	apigatewayv1 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apigateway/apis/v1"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts:     4,
+	InitialInterval: time.Millisecond,
+	MaxInterval:     5 * time.Millisecond,
+	Multiplier:      2,
+}
+
+func TestClientRetriesTransientErrors(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		default:
+			json.NewEncoder(w).Encode(apigatewayv1.SubmissionStatusResponse{SubmissionId: "s-1", State: "RUNNING"})
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "", nil, nil)
+	client.SetRetryPolicy(testRetryPolicy)
+	_, status, err := client.GetApplicationStatus("s-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "RUNNING", status.State)
+	assert.Equal(t, 3, requests)
+}
+
+func TestClientGivesUpAfterMaxAttempts(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "failed", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "", nil, nil)
+	client.SetRetryPolicy(testRetryPolicy)
+	_, _, err := client.GetApplicationStatus("s-1")
+	assert.Error(t, err)
+	assert.Equal(t, testRetryPolicy.MaxAttempts, requests)
+
+	// A failed POST may have created something, so it is not retried on internal errors.
+	requests = 0
+	_, err = client.SubmitApplication(apigatewayv1.SparkApplicationSubmissionRequest{MainApplicationFile: "app.jar"})
+	assert.Error(t, err)
+	assert.Equal(t, 1, requests)
+}
+
+func TestSubmitApplicationRetriesWithSameId(t *testing.T) {
+	var paths []string
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		paths = append(paths, r.URL.Path)
+		bodies = append(bodies, string(body))
+		if len(paths) == 1 {
+			// The submission was created, but the response got lost.
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+			return
+		}
+		http.Error(w, "already exists", http.StatusConflict)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "", nil, nil)
+	client.SetRetryPolicy(testRetryPolicy)
+	submissionId, err := client.SubmitApplication(apigatewayv1.SparkApplicationSubmissionRequest{MainApplicationFile: "app.jar"})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(submissionId, "s-"))
+	assert.Equal(t, []string{"/submissions/" + submissionId, "/submissions/" + submissionId}, paths)
+	assert.Equal(t, bodies[0], bodies[1])
+	assert.Contains(t, bodies[1], "app.jar")
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	response := func(value string) *http.Response {
+		return &http.Response{Header: http.Header{"Retry-After": []string{value}}}
+	}
+	assert.Equal(t, 3*time.Second, retryAfter(response("3"), now))
+	assert.Equal(t, 10*time.Second, retryAfter(response(now.Add(10*time.Second).Format(http.TimeFormat)), now))
+	assert.Equal(t, maxRetryAfter, retryAfter(response("86400"), now))
+	assert.Equal(t, time.Duration(0), retryAfter(response("soon"), now))
+	assert.Equal(t, time.Duration(0), retryAfter(nil, now))
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialInterval: time.Second, MaxInterval: 5 * time.Second, Multiplier: 2}
+	for retry, expected := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 10: 5 * time.Second} {
+		wait := policy.backoff(retry)
+		assert.True(t, wait <= expected && wait >= expected*8/10, "retry %d waits %s", retry, wait)
+	}
+}
+
+func TestNewHTTPClientTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "sparkcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, caBundle, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(config TransportConfig) error {
+		client, err := NewHTTPClient(config)
+		if err != nil {
+			return err
+		}
+		response, err := client.Get(server.URL)
+		if err == nil {
+			response.Body.Close()
+		}
+		return err
+	}
+	assert.Error(t, get(TransportConfig{}))
+	assert.NoError(t, get(TransportConfig{CAFile: caFile}))
+	assert.NoError(t, get(TransportConfig{InsecureSkipVerify: true}))
+
+	_, err = NewHTTPClient(TransportConfig{CAFile: filepath.Join(dir, "missing.pem")})
+	assert.Error(t, err)
+	_, err = NewHTTPClient(TransportConfig{ProxyURL: "://proxy"})
+	assert.Error(t, err)
+}
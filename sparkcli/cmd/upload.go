@@ -0,0 +1,187 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	apigatewayv1 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apigateway/apis/v1"
+)
+
+// defaultChunkSize is the size of the chunks files are uploaded in if neither the caller nor the gateway set one.
+const defaultChunkSize = 16 << 20
+
+// maxChunkAttempts is how many times sending a chunk is attempted before an upload fails.
+const maxChunkAttempts = 5
+
+// chunkRetryInterval is the delay before the first retry of a chunk. It grows with every attempt.
+var chunkRetryInterval = time.Second
+
+// errUploadsNotSupported is returned by UploadFile if the gateway has no chunked upload endpoint.
+var errUploadsNotSupported = fmt.Errorf("the gateway does not support chunked uploads")
+
+// UploadFile uploads a file in chunks of at most chunkSize bytes and returns its URL. Failed chunks are retried
+// from the last byte the gateway received, and uploads interrupted earlier, e.g. by a previous sparkcli run,
+// are resumed. Files the gateway already has are not uploaded again. Gateways without chunked uploads get the
+// file in a single request.
+func (c *Client) UploadFile(filePath string, chunkSize int64) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("cannot open file %s: %s", filePath, err.Error())
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("cannot get info for file %s: %s", filePath, err.Error())
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("cannot read file %s: %s", filePath, err.Error())
+	}
+
+	status := apigatewayv1.UploadStatusResponse{}
+	err = c.sendUploadRequest(http.MethodPost, "/uploads", apigatewayv1.CreateUploadRequest{
+		Name:   filepath.Base(filePath),
+		Size:   fileInfo.Size(),
+		Sha256: hex.EncodeToString(hash.Sum(nil)),
+	}, &status)
+	if err == errUploadsNotSupported {
+		return c.UploadFileToS3(filePath)
+	}
+	if err != nil {
+		return "", err
+	}
+	if status.Url != "" {
+		log.Printf("File %s is unchanged since it was uploaded to %s", filePath, status.Url)
+		return status.Url, nil
+	}
+	if status.ReceivedBytes > 0 {
+		log.Printf("Resuming upload of %s at byte %d of %d", filePath, status.ReceivedBytes, fileInfo.Size())
+	}
+
+	if chunkSize <= 0 || (status.MaxChunkSize > 0 && chunkSize > status.MaxChunkSize) {
+		chunkSize = status.MaxChunkSize
+	}
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	uploadPath := "/uploads/" + status.UploadId
+	offset := status.ReceivedBytes
+	attempts := 0
+	for offset < fileInfo.Size() {
+		length := fileInfo.Size() - offset
+		if length > chunkSize {
+			length = chunkSize
+		}
+		chunk := io.NewSectionReader(file, offset, length)
+		err := c.sendUploadRequest(http.MethodPut, fmt.Sprintf("%s?offset=%d", uploadPath, offset), chunk, &status)
+		if err == nil {
+			if status.ReceivedBytes <= offset {
+				return "", fmt.Errorf("failed to upload %s, the gateway received no bytes of the chunk at byte %d",
+					filePath, offset)
+			}
+			offset = status.ReceivedBytes
+			attempts = 0
+			continue
+		}
+
+		attempts++
+		if attempts >= maxChunkAttempts {
+			return "", fmt.Errorf("failed to upload %s after %d attempts: %s", filePath, attempts, err.Error())
+		}
+		log.Printf("Failed to upload chunk of %s at byte %d, retrying: %s", filePath, offset, err.Error())
+		time.Sleep(time.Duration(attempts) * chunkRetryInterval)
+		// The gateway may have received part of the chunk, continue from there.
+		if err := c.sendUploadRequest(http.MethodGet, uploadPath, nil, &status); err == nil {
+			offset = status.ReceivedBytes
+		}
+	}
+
+	response := apigatewayv1.UploadFileResponse{}
+	if err := c.sendUploadRequest(http.MethodPost, uploadPath+"/complete", nil, &response); err != nil {
+		return "", err
+	}
+	if response.Url == "" {
+		return "", fmt.Errorf("failed to upload file %s, got no URL", filePath)
+	}
+	return response.Url, nil
+}
+
+// sendUploadRequest sends a request to an upload endpoint and parses the response into result. The body is
+// either an io.Reader sent as it is, or a value sent as JSON.
+func (c *Client) sendUploadRequest(method string, path string, body interface{}, result interface{}) error {
+	url := c.serverUrl + path
+
+	var reader io.Reader
+	contentType := "application/octet-stream"
+	switch value := body.(type) {
+	case nil:
+	case io.Reader:
+		reader = value
+	default:
+		requestBytes, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to serialize request to json: %s", err.Error())
+		}
+		reader = bytes.NewReader(requestBytes)
+		contentType = "application/json"
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create %s request for %s: %s", method, url, err.Error())
+	}
+	if section, ok := body.(*io.SectionReader); ok {
+		req.ContentLength = section.Size()
+	}
+	if reader != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	response, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send %s request to %s: %s", method, url, err.Error())
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound && path == "/uploads" {
+		return errUploadsNotSupported
+	}
+	if response.StatusCode != http.StatusOK {
+		return ErrorBadHttpStatus(url, response)
+	}
+
+	responseBytes, err := ReadHttpResponse(response)
+	if err != nil {
+		return fmt.Errorf("failed to read response data from %s: %s", url, err.Error())
+	}
+	if err := json.Unmarshal(responseBytes, result); err != nil {
+		return fmt.Errorf("failed to parse response from %s: %s, response: %s", url, err.Error(), string(responseBytes))
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	apigatewayv1 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apigateway/apis/v1"
+)
+
+// fakeUploadServer accepts chunked uploads and fails every other chunk after storing half of it.
+type fakeUploadServer struct {
+	received []byte
+	chunks   int
+}
+
+func (f *fakeUploadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/uploads":
+		json.NewEncoder(w).Encode(apigatewayv1.UploadStatusResponse{
+			UploadId: "u-1", ReceivedBytes: int64(len(f.received)), MaxChunkSize: 4})
+	case r.Method == http.MethodGet && r.URL.Path == "/uploads/u-1":
+		json.NewEncoder(w).Encode(apigatewayv1.UploadStatusResponse{UploadId: "u-1", ReceivedBytes: int64(len(f.received))})
+	case r.Method == http.MethodPut && r.URL.Path == "/uploads/u-1":
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		chunk, _ := ioutil.ReadAll(r.Body)
+		f.chunks++
+		if f.chunks%2 == 1 {
+			chunk = chunk[:len(chunk)/2]
+			f.received = append(f.received[:offset], chunk...)
+			http.Error(w, "connection lost", http.StatusServiceUnavailable)
+			return
+		}
+		f.received = append(f.received[:offset], chunk...)
+		json.NewEncoder(w).Encode(apigatewayv1.UploadStatusResponse{UploadId: "u-1", ReceivedBytes: int64(len(f.received))})
+	case r.Method == http.MethodPost && r.URL.Path == "/uploads/u-1/complete":
+		json.NewEncoder(w).Encode(apigatewayv1.UploadFileResponse{Url: "s3a://bucket/uploaded"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestUploadFileResumesChunks(t *testing.T) {
+	chunkRetryInterval = time.Millisecond
+	fake := &fakeUploadServer{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "sparkcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "app.jar")
+	content := []byte("0123456789abcdefghij")
+	if err := ioutil.WriteFile(filePath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewBasicAuthClient(server.URL, "", "")
+	url, err := client.UploadFile(filePath, 1<<20)
+	assert.Nil(t, err)
+	assert.Equal(t, "s3a://bucket/uploaded", url)
+	assert.Equal(t, content, fake.received)
+}
+
+func TestUploadFileFailsWithoutProgress(t *testing.T) {
+	var chunkSizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			json.NewEncoder(w).Encode(apigatewayv1.UploadStatusResponse{UploadId: "u-1"})
+		case http.MethodPut:
+			chunk, _ := ioutil.ReadAll(r.Body)
+			chunkSizes = append(chunkSizes, len(chunk))
+			json.NewEncoder(w).Encode(apigatewayv1.UploadStatusResponse{UploadId: "u-1"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "sparkcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "app.jar")
+	if err := ioutil.WriteFile(filePath, []byte("jar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither the caller nor the gateway set a chunk size, and the gateway does not store the chunk.
+	client := NewBasicAuthClient(server.URL, "", "")
+	_, err = client.UploadFile(filePath, 0)
+	assert.NotNil(t, err)
+	assert.Equal(t, []int{3}, chunkSizes)
+}
+
+func TestUploadFileFallsBackToSingleRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/s3/upload" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(apigatewayv1.UploadFileResponse{Url: "s3a://bucket/" + r.URL.Query().Get("name")})
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "sparkcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "app.jar")
+	if err := ioutil.WriteFile(filePath, []byte("jar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewBasicAuthClient(server.URL, "", "")
+	url, err := client.UploadFile(filePath, 1<<20)
+	assert.Nil(t, err)
+	assert.Equal(t, "s3a://bucket/app.jar", url)
+}
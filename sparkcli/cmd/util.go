@@ -0,0 +1,71 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var OutputFile string
+
+type UserCredential struct {
+	Name     string
+	Password string
+}
+
+func addOutputFlag(command *cobra.Command) {
+	command.Flags().StringVarP(&OutputFile, "output-file", "", "",
+		"file to write the raw response from the server to")
+}
+
+func ExitWithError(message string) {
+	log.Printf("ERROR: %s", message)
+	os.Exit(1)
+}
+
+func WriteOutputFileExitOnError(filePath string, content string) {
+	if err := ioutil.WriteFile(filePath, []byte(content), 0644); err != nil {
+		ExitWithError(fmt.Sprintf("Failed to write output file %s: %s", filePath, err.Error()))
+	}
+}
+
+func ReadHttpResponse(response *http.Response) ([]byte, error) {
+	return ioutil.ReadAll(response.Body)
+}
+
+func ErrorBadHttpStatus(url string, response *http.Response) error {
+	responseBytes, err := ReadHttpResponse(response)
+	if err != nil {
+		return fmt.Errorf("got bad response status %d from %s, and failed to read response: %s", response.StatusCode, url, err.Error())
+	}
+	return fmt.Errorf("got bad response status %d from %s: %s", response.StatusCode, url, strings.TrimSpace(string(responseBytes)))
+}
+
+func GetObjectTypeName(obj interface{}) string {
+	if obj == nil {
+		return "nil"
+	}
+	return reflect.TypeOf(obj).Name()
+}
@@ -0,0 +1,99 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	apigatewayv1 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apigateway/apis/v1"
+)
+
+const (
+	completedState = "COMPLETED"
+	failedState    = "FAILED"
+	killedState    = "KILLED"
+	// suspendedState is not terminal, but a suspended application does not finish until it is resumed.
+	suspendedState = "SUSPENDED"
+)
+
+var WaitInterval time.Duration
+var WaitTimeout time.Duration
+
+var waitCmd = &cobra.Command{
+	Use:   "wait <submission id>",
+	Short: "Wait for an application submission to finish",
+	Long: `Poll the status of an application submission until it reaches a terminal state. Exits with a non-zero
+code if the application failed, was killed or suspended, or if the timeout was reached.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client := newClientExitOnError()
+
+		status, err := waitForTerminalState(client, args[0], WaitInterval, WaitTimeout)
+		if err != nil {
+			ExitWithError(err.Error())
+		}
+
+		printSubmissionExitOnError(os.Stdout, status)
+		if status.State != completedState {
+			ExitWithError(fmt.Sprintf("Submission %s finished with state %s: %s", args[0], status.State, status.ErrorMessage))
+		}
+		log.Printf("Submission %s finished with state %s", args[0], status.State)
+	},
+}
+
+func init() {
+	waitCmd.Flags().DurationVarP(&WaitInterval, "interval", "", 10*time.Second,
+		"interval between status checks")
+	waitCmd.Flags().DurationVarP(&WaitTimeout, "timeout", "", 0,
+		"maximum time to wait, wait forever if not set")
+}
+
+// isTerminalState returns whether waiting for a submission in the given state stops.
+func isTerminalState(state string) bool {
+	return state == completedState || state == failedState || state == killedState || state == suspendedState
+}
+
+// waitForTerminalState polls the status of the submission until it reaches a terminal state.
+func waitForTerminalState(client *Client, submissionId string, interval time.Duration, timeout time.Duration) (apigatewayv1.SubmissionStatusResponse, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	lastState := ""
+	for {
+		_, status, err := client.GetApplicationStatus(submissionId)
+		if err != nil {
+			return status, fmt.Errorf("failed to get status of submission %s: %s", submissionId, err.Error())
+		}
+		if status.State != lastState {
+			log.Printf("Submission %s is in state %s", submissionId, status.State)
+			lastState = status.State
+		}
+		if isTerminalState(status.State) {
+			return status, nil
+		}
+		if !deadline.IsZero() && time.Now().Add(interval).After(deadline) {
+			return status, fmt.Errorf("timed out after %s waiting for submission %s, last state: %s", timeout, submissionId, status.State)
+		}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright spark-on-k8s-operator contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	apigatewayv1 "github.com/GoogleCloudPlatform/spark-on-k8s-operator/pkg/apigateway/apis/v1"
+)
+
+func newStatusServer(states ...string) *httptest.Server {
+	calls := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := states[len(states)-1]
+		if calls < len(states) {
+			state = states[calls]
+		}
+		calls++
+		json.NewEncoder(w).Encode(apigatewayv1.SubmissionStatusResponse{SubmissionId: "s-1", State: state})
+	}))
+}
+
+func TestWaitForTerminalState(t *testing.T) {
+	server := newStatusServer("NEW", "SUBMITTED", "RUNNING", "FAILED")
+	defer server.Close()
+
+	client := NewBasicAuthClient(server.URL, "", "")
+	status, err := waitForTerminalState(client, "s-1", time.Millisecond, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "FAILED", status.State)
+}
+
+func TestWaitForTerminalStateSuspended(t *testing.T) {
+	server := newStatusServer("RUNNING", "SUSPENDING", "SUSPENDED")
+	defer server.Close()
+
+	client := NewBasicAuthClient(server.URL, "", "")
+	status, err := waitForTerminalState(client, "s-1", time.Millisecond, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "SUSPENDED", status.State)
+}
+
+func TestWaitForTerminalStateTimeout(t *testing.T) {
+	server := newStatusServer("RUNNING")
+	defer server.Close()
+
+	client := NewBasicAuthClient(server.URL, "", "")
+	status, err := waitForTerminalState(client, "s-1", 10*time.Millisecond, 50*time.Millisecond)
+	assert.NotNil(t, err)
+	assert.Equal(t, "RUNNING", status.State)
+}
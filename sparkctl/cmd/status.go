@@ -75,7 +75,28 @@ func printStatus(app *v1beta2.SparkApplication) {
 	})
 	table.Render()
 
-	if len(app.Status.ExecutorState) > 0 {
+	if len(app.Status.Executors) > 0 {
+		fmt.Println("executor state:")
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Executor Pod", "Executor ID", "Node", "State", "Exit Code", "Reason", "Restarts"})
+		for _, executor := range app.Status.Executors {
+			exitCode := ""
+			if executor.ExitCode != nil {
+				exitCode = fmt.Sprintf("%d", *executor.ExitCode)
+			}
+			table.Append([]string{
+				executor.PodName,
+				formatNotAvailable(executor.ExecutorID),
+				formatNotAvailable(executor.NodeName),
+				string(executor.State),
+				formatNotAvailable(exitCode),
+				formatNotAvailable(executor.Reason),
+				fmt.Sprintf("%v", executor.Restarts),
+			})
+		}
+		table.Render()
+	} else if len(app.Status.ExecutorState) > 0 {
+		// The application is managed by an operator that does not list executors yet.
 		fmt.Println("executor state:")
 		table := tablewriter.NewWriter(os.Stdout)
 		table.SetHeader([]string{"Executor Pod", "State"})
@@ -84,6 +105,9 @@ func printStatus(app *v1beta2.SparkApplication) {
 		}
 		table.Render()
 	}
+	if rolledUp := app.Status.RolledUpExecutors; rolledUp != nil {
+		fmt.Printf("\n%d completed and %d failed executors are not listed\n", rolledUp.Completed, rolledUp.Failed)
+	}
 
 	if app.Status.AppState.ErrorMessage != "" {
 		fmt.Printf("\napplication error message: %s\n", app.Status.AppState.ErrorMessage)